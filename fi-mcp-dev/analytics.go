@@ -0,0 +1,286 @@
+package main
+
+import (
+    "math"
+    "net/http"
+    "sort"
+    "strings"
+    "unicode"
+)
+
+// ————— analytics —————
+// Views of the persona's data shaped for an assistant rather than a bank:
+// income against spend by month, spend by category, and where today's
+// holdings grow to. Everything is computed from the fixtures on each call.
+
+// ————— monthly cashflow —————
+
+type monthCashflow struct {
+    Month        string   `json:"month"` // YYYY-MM
+    Income       float64  `json:"income"`
+    Expense      float64  `json:"expense"`
+    Installments float64  `json:"installments"`
+    Net          float64  `json:"net"`
+    SavingsRate  *float64 `json:"savingsRate"` // nil in a month without income
+}
+
+// cashflowHandler serves /api/analytics/cashflow: money in and out per
+// calendar month of the bank fixture, oldest first, by the same rules as
+// monthlyCashflow (credits are income; debits, installments and TDS are
+// expense). A month the fixture covers with no flows is listed with zeros.
+func cashflowHandler(w http.ResponseWriter, r *http.Request) {
+    phone := r.Context().Value("phone").(string)
+    bt, err := loadBankTransactions(r.Context(), phone)
+    if err != nil {
+        http.Error(w, "data not found", http.StatusInternalServerError)
+        return
+    }
+    byMonth := map[string]*monthCashflow{}
+    for _, acc := range bt.BankTransactions {
+        for _, t := range acc.Txns {
+            if len(t.Date) < 7 {
+                continue
+            }
+            m, ok := byMonth[t.Date[:7]]
+            if !ok {
+                m = &monthCashflow{Month: t.Date[:7]}
+                byMonth[m.Month] = m
+            }
+            switch {
+            case t.Type == bankTxnCredit:
+                m.Income += t.Amount
+            case t.isSpend():
+                m.Expense += t.Amount
+                if t.Type == bankTxnInstallment {
+                    m.Installments += t.Amount
+                }
+            }
+        }
+    }
+    months := make([]monthCashflow, 0, len(byMonth))
+    for _, m := range byMonth {
+        m.Net = round2(m.Income - m.Expense)
+        if m.Income > 0 {
+            rate := math.Round(m.Net/m.Income*1000) / 1000
+            if rate == 0 {
+                rate = 0 // avoid "-0"
+            }
+            m.SavingsRate = &rate
+        }
+        m.Income, m.Expense, m.Installments = round2(m.Income), round2(m.Expense), round2(m.Installments)
+        months = append(months, *m)
+    }
+    sort.Slice(months, func(i, j int) bool { return months[i].Month < months[j].Month })
+
+    var average interface{} // null rather than zeros when nothing is dated
+    if cf := bt.monthlyCashflow(); cf.Months > 0 {
+        average = map[string]float64{
+            "income":       round2(cf.Income),
+            "expense":      round2(cf.Spend),
+            "installments": round2(cf.Installments),
+            "net":          round2(cf.Income - cf.Spend),
+        }
+    }
+    writeJSON(w, map[string]interface{}{
+        "months":  months,
+        "average": average,
+    })
+}
+
+// ————— spend categories —————
+// Each spend row is put in the first category with a keyword among the
+// words of its narration, e.g. "UPI-SWIGGY-SWIGGY@YBL-...-FOOD" is Food &
+// dining. Multi-word keywords must appear as consecutive words. Rows no rule
+// matches fall back on their type: installments are Loans & EMIs, TDS is
+// Taxes, and the rest is Other.
+
+var spendCategoryRules = []struct {
+    Category string
+    Keywords []string
+}{
+    {"Investments", []string{"SIP", "NPS", "LUMPSUM INV", "TD BOOKING", "RD", "SAFE GOLD", "ZERODHA", "GROWW", "MUTUAL FUND"}},
+    {"Loans & EMIs", []string{"EMI", "LOAN", "SIMPL", "LAZYPAY", "LAZY PAY"}},
+    {"Credit card bills", []string{"CREDIT CARD", "CRED", "CREDCLUB", "DREAMPLUG"}},
+    {"Rent", []string{"RENT"}},
+    {"Food & dining", []string{"SWIGGY", "ZOMATO", "SAAPAAD", "DOMINOS", "STARBUCKS", "RESTAURANT", "CAFE", "HOTEL", "FOOD"}},
+    {"Groceries", []string{"BLINKIT", "BIGBASKET", "ZEPTO", "DUNZO", "DMART", "MINI MART", "SUPERMARKET", "RELIANCE FRESH", "GROCERY", "KIRANA", "FRUIT"}},
+    {"Shopping", []string{"AMAZON", "AMAZONPAY", "FLIPKART", "MYNTRA", "AJIO", "NYKAA", "SHOPPING"}},
+    {"Transport", []string{"UBER", "OLA", "RAPIDO", "IRCTC", "FASTAG", "PETROL", "FUEL", "INDIAN OIL", "HPCL", "BPCL", "METRO"}},
+    {"Bills & utilities", []string{"ELECTRICITY", "POWER", "BROADBAND", "AIRTEL", "JIO", "RECHARGE", "POSTPAID", "BESCOM", "GAS", "WATER"}},
+    {"Entertainment", []string{"NETFLIX", "SPOTIFY", "HOTSTAR", "SONYLIV", "BOOKMYSHOW", "PRIME VIDEO"}},
+    {"Health", []string{"MEDICAL", "PHARMACY", "PHARMEASY", "APOLLO", "HOSPITAL", "CLINIC", "DENTAL", "DENTIS"}},
+    {"Wallets", []string{"WALLET", "PAYTM WALLET"}},
+    {"Cash", []string{"CASH WDL", "ATM", "NWD"}},
+    {"Bank charges", []string{"CHARGE", "CHG", "CHGS"}},
+}
+
+// spendCategoryOf names the category of one spend row.
+func spendCategoryOf(t bankTxn) string {
+    words := " " + strings.Join(strings.FieldsFunc(strings.ToUpper(t.Narration), func(c rune) bool {
+        return !unicode.IsLetter(c) && !unicode.IsDigit(c)
+    }), " ") + " "
+    for _, rule := range spendCategoryRules {
+        for _, kw := range rule.Keywords {
+            if strings.Contains(words, " "+kw+" ") {
+                return rule.Category
+            }
+        }
+    }
+    switch t.Type {
+    case bankTxnInstallment:
+        return "Loans & EMIs"
+    case bankTxnTDS:
+        return "Taxes"
+    }
+    return "Other"
+}
+
+// maxCategoryMerchants caps the merchants listed under each category.
+const maxCategoryMerchants = 3
+
+type categoryTotal struct {
+    Category  string   `json:"category"`
+    Amount    float64  `json:"amount"`
+    Count     int      `json:"count"`
+    Percent   float64  `json:"percent"`
+    Merchants []string `json:"merchants"` // the biggest ones first
+    // byMerchant is the category's spend by merchantKey.
+    byMerchant map[string]float64
+}
+
+// categoriesHandler serves /api/analytics/categories: the bank fixture's
+// spend summed by category, largest first, with the dates it spans.
+func categoriesHandler(w http.ResponseWriter, r *http.Request) {
+    phone := r.Context().Value("phone").(string)
+    bt, err := loadBankTransactions(r.Context(), phone)
+    if err != nil {
+        http.Error(w, "data not found", http.StatusInternalServerError)
+        return
+    }
+    byCat := map[string]*categoryTotal{}
+    var total float64
+    var from, to *string // null when there is no spend
+    for _, acc := range bt.BankTransactions {
+        for _, t := range acc.Txns {
+            if !t.isSpend() {
+                continue
+            }
+            if t.Date != "" {
+                if from == nil || t.Date < *from {
+                    from = &t.Date
+                }
+                if to == nil || t.Date > *to {
+                    to = &t.Date
+                }
+            }
+            cat := spendCategoryOf(t)
+            c, ok := byCat[cat]
+            if !ok {
+                c = &categoryTotal{Category: cat, byMerchant: map[string]float64{}}
+                byCat[cat] = c
+            }
+            c.Amount += t.Amount
+            c.Count++
+            c.byMerchant[merchantKey(t.Narration)] += t.Amount
+            total += t.Amount
+        }
+    }
+    cats := make([]categoryTotal, 0, len(byCat))
+    for _, c := range byCat {
+        for m := range c.byMerchant {
+            c.Merchants = append(c.Merchants, m)
+        }
+        sort.Slice(c.Merchants, func(i, j int) bool {
+            a, b := c.byMerchant[c.Merchants[i]], c.byMerchant[c.Merchants[j]]
+            if a != b {
+                return a > b
+            }
+            return c.Merchants[i] < c.Merchants[j]
+        })
+        c.Merchants = c.Merchants[:min(len(c.Merchants), maxCategoryMerchants)]
+        if total > 0 {
+            c.Percent = round2(100 * c.Amount / total)
+        }
+        c.Amount = round2(c.Amount)
+        cats = append(cats, *c)
+    }
+    sort.Slice(cats, func(i, j int) bool {
+        if cats[i].Amount != cats[j].Amount {
+            return cats[i].Amount > cats[j].Amount
+        }
+        return cats[i].Category < cats[j].Category
+    })
+    writeJSON(w, map[string]interface{}{
+        "from":       from,
+        "to":         to,
+        "total":      round2(total),
+        "categories": cats,
+    })
+}
+
+// ————— net worth projection —————
+// Unlike /api/net_worth/forecast this is deterministic and only grows what
+// is already held: mutual funds, stocks and EPF each compound at a fixed
+// yearly rate, with no new contributions. Every other asset and all
+// liabilities are held flat.
+
+var projectionGroups = []struct {
+    Name       string
+    Rate       float64
+    Attributes []string
+}{
+    {"mutualFunds", 0.11, []string{"ASSET_TYPE_MUTUAL_FUND"}},
+    {"stocks", 0.12, []string{"ASSET_TYPE_INDIAN_SECURITIES", "ASSET_TYPE_US_SECURITIES", "ASSET_TYPE_ETF"}},
+    {"epf", 0.0825, []string{"ASSET_TYPE_EPF"}},
+}
+
+type projectionYear struct {
+    Year     int                `json:"year"`
+    Holdings map[string]float64 `json:"holdings"`
+    NetWorth float64            `json:"netWorth"`
+}
+
+// netWorthProjectionHandler serves /api/analytics/networth_projection?years=N.
+// Year 0 is today's fixture.
+func netWorthProjectionHandler(w http.ResponseWriter, r *http.Request) {
+    phone := r.Context().Value("phone").(string)
+    years, err := intParam(r.URL.Query().Get("years"), 10, 1, maxForecastYears)
+    if err != nil {
+        http.Error(w, "years must be an integer from 1 to 50", http.StatusBadRequest)
+        return
+    }
+    nw, err := loadNetWorth(r.Context(), phone)
+    if err != nil {
+        http.Error(w, "data not found", http.StatusInternalServerError)
+        return
+    }
+    start := map[string]float64{}
+    rates := map[string]float64{}
+    netWorth := nw.NetWorthResponse.TotalNetWorthValue.Float()
+    flat := netWorth // everything not grown, liabilities included
+    for _, g := range projectionGroups {
+        for _, a := range g.Attributes {
+            start[g.Name] += nw.attribute(a)
+        }
+        rates[g.Name] = g.Rate
+        flat -= start[g.Name]
+    }
+
+    projection := make([]projectionYear, 0, years+1)
+    for y := 0; y <= years; y++ {
+        row := projectionYear{Year: y, Holdings: map[string]float64{}}
+        total := flat
+        for _, g := range projectionGroups {
+            v := start[g.Name] * math.Pow(1+g.Rate, float64(y))
+            row.Holdings[g.Name] = round2(v)
+            total += v
+        }
+        row.NetWorth = round2(total)
+        projection = append(projection, row)
+    }
+    writeJSON(w, map[string]interface{}{
+        "rates":      rates,
+        "heldFlat":   round2(flat),
+        "projection": projection,
+    })
+}
@@ -0,0 +1,111 @@
+package main
+
+import (
+    "math"
+    "net/http"
+    "sort"
+    "strconv"
+
+    "github.com/epifi/fi-mcp-lite/pkg"
+)
+
+// ————— spending anomalies —————
+// Transactions are grouped by type and mode ("DEBIT/CARD_PAYMENT"), the
+// closest thing to a category the bank fixture has. Within each group any
+// amount more than z standard deviations from the mean is flagged. Groups
+// with fewer than minAnomalyGroup rows are too small to judge.
+
+const minAnomalyGroup = 3
+
+type anomaly struct {
+    AccountID string  `json:"accountId"`
+    Category  string  `json:"category"`
+    Date      string  `json:"date"`
+    Narration string  `json:"narration"`
+    Amount    float64 `json:"amount"`
+    Mean      float64 `json:"mean"`
+    StdDev    float64 `json:"stdDev"`
+    ZScore    float64 `json:"zScore"`
+}
+
+func anomaliesHandler(w http.ResponseWriter, r *http.Request) {
+    threshold := pkg.GetAnomalyZScore()
+    if v := r.URL.Query().Get("z"); v != "" {
+        z, err := strconv.ParseFloat(v, 64)
+        if err != nil || z <= 0 {
+            http.Error(w, "z must be a positive number", http.StatusBadRequest)
+            return
+        }
+        threshold = z
+    }
+    phone := r.Context().Value("phone").(string)
+    bt, err := loadBankTransactions(r.Context(), phone)
+    if err != nil {
+        http.Error(w, "data not found", http.StatusInternalServerError)
+        return
+    }
+    writeJSON(w, map[string]interface{}{
+        "threshold": threshold,
+        "anomalies": findAnomalies(bt, threshold),
+    })
+}
+
+func findAnomalies(bt *bankTransactionsFile, threshold float64) []anomaly {
+    type row struct {
+        accountID string
+        txn       bankTxn
+    }
+    groups := map[string][]row{}
+    ids := bankAccountIDs(bt)
+    for i, acc := range bt.BankTransactions {
+        for _, t := range acc.Txns {
+            cat := bankTxnTypeNames[t.Type] + "/" + t.Mode
+            groups[cat] = append(groups[cat], row{ids[i], t})
+        }
+    }
+
+    out := []anomaly{}
+    for cat, rows := range groups {
+        if len(rows) < minAnomalyGroup {
+            continue
+        }
+        var sum, sq float64
+        for _, r := range rows {
+            sum += r.txn.Amount
+        }
+        mean := sum / float64(len(rows))
+        for _, r := range rows {
+            sq += (r.txn.Amount - mean) * (r.txn.Amount - mean)
+        }
+        sd := math.Sqrt(sq / float64(len(rows)))
+        if sd == 0 {
+            continue
+        }
+        for _, r := range rows {
+            z := (r.txn.Amount - mean) / sd
+            if math.Abs(z) > threshold {
+                out = append(out, anomaly{
+                    AccountID: r.accountID,
+                    Category:  cat,
+                    Date:      r.txn.Date,
+                    Narration: r.txn.Narration,
+                    Amount:    r.txn.Amount,
+                    Mean:      round2(mean),
+                    StdDev:    round2(sd),
+                    ZScore:    round2(z),
+                })
+            }
+        }
+    }
+    // strongest first; groups come from a map, so break ties deterministically
+    sort.Slice(out, func(i, j int) bool {
+        if zi, zj := math.Abs(out[i].ZScore), math.Abs(out[j].ZScore); zi != zj {
+            return zi > zj
+        }
+        if out[i].Date != out[j].Date {
+            return out[i].Date < out[j].Date
+        }
+        return out[i].Narration < out[j].Narration
+    })
+    return out
+}
@@ -0,0 +1,206 @@
+package main
+
+import (
+    "bufio"
+    "bytes"
+    "encoding/json"
+    "errors"
+    "io/fs"
+    "log"
+    "net/http"
+    "os"
+    "path/filepath"
+    "reflect"
+    "sort"
+    "strconv"
+    "strings"
+    "sync"
+    "time"
+
+    "github.com/epifi/fi-mcp-lite/pkg"
+)
+
+// ————— audit trail —————
+// Every admin change to a phone's fixtures is appended as one JSON line to
+// FI_MCP_AUDIT_LOG (test_data_dir/.audit.jsonl by default): when, which
+// phone, which endpoint, and a diff of the phone's datasets before and
+// after. Diff paths are JSON pointers that start with the data type name,
+// e.g. /net_worth/netWorthResponse/totalNetWorthValue/units. Lines are only
+// ever appended. GET /admin/audit?phone= reads them back, oldest first.
+
+const auditFile = ".audit.jsonl"
+
+type auditEntry struct {
+    Time     time.Time     `json:"time"`
+    Phone    string        `json:"phone"`
+    Endpoint string        `json:"endpoint"`
+    Diff     []auditChange `json:"diff"`
+}
+
+// auditChange is one difference: "add" has only New, "remove" only Old.
+type auditChange struct {
+    Op   string      `json:"op"` // add, remove or replace
+    Path string      `json:"path"`
+    Old  interface{} `json:"old,omitempty"`
+    New  interface{} `json:"new,omitempty"`
+}
+
+var auditLog sync.Mutex
+
+// auditPath is where entries go, "" when FI_MCP_AUDIT_LOG is off.
+func auditPath() string {
+    switch p := pkg.GetAuditLog(); p {
+    case "":
+        return filepath.Join(dataDir, auditFile)
+    case "off":
+        return ""
+    default:
+        return p
+    }
+}
+
+// phoneDatasets reads every fixture the phone has, keyed by data type name,
+// for the before side of an audit entry.
+func phoneDatasets(phone string) map[string][]byte {
+    out := map[string][]byte{}
+    for _, e := range dataEndpoints {
+        if data, err := os.ReadFile(dataFilePath(phone, e.File)); err == nil {
+            out[e.Name] = data
+        }
+    }
+    return out
+}
+
+// recordAudit appends the change from before to after, both keyed by data
+// type name with a missing key for a missing fixture. Every mutation gets an
+// entry, with an empty diff if it changed no fixture, such as deleting a
+// persona that had none. Write errors are logged; the mutation has already
+// happened by then.
+func recordAudit(phone, endpoint string, before, after map[string][]byte) {
+    path := auditPath()
+    if path == "" {
+        return
+    }
+    diff := []auditChange{}
+    diffJSON("", decodeDatasets(before), decodeDatasets(after), &diff)
+    line, err := json.Marshal(auditEntry{Time: time.Now().UTC(), Phone: phone, Endpoint: endpoint, Diff: diff})
+    if err != nil {
+        log.Printf("audit: %v", err)
+        return
+    }
+    auditLog.Lock()
+    defer auditLog.Unlock()
+    f, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o644)
+    if err == nil {
+        _, err = f.Write(append(line, '\n'))
+        if cerr := f.Close(); err == nil {
+            err = cerr
+        }
+    }
+    if err != nil {
+        log.Printf("audit: %v", readErr(err))
+    }
+}
+
+// decodeDatasets decodes each fixture, keeping numbers as written. One that
+// isn't valid JSON is kept as a string, so its change is still visible.
+func decodeDatasets(datasets map[string][]byte) map[string]interface{} {
+    out := make(map[string]interface{}, len(datasets))
+    for name, data := range datasets {
+        dec := json.NewDecoder(bytes.NewReader(data))
+        dec.UseNumber()
+        var v interface{}
+        if err := dec.Decode(&v); err != nil {
+            v = string(data)
+        }
+        out[name] = v
+    }
+    return out
+}
+
+var pointerEscaper = strings.NewReplacer("~", "~0", "/", "~1")
+
+// diffJSON adds the differences between a and b under path. Objects are
+// compared key by key and arrays of the same length index by index; any
+// other difference replaces the whole value.
+func diffJSON(path string, a, b interface{}, out *[]auditChange) {
+    switch av := a.(type) {
+    case map[string]interface{}:
+        if bv, ok := b.(map[string]interface{}); ok {
+            keys := make([]string, 0, len(av)+len(bv))
+            for k := range av {
+                keys = append(keys, k)
+            }
+            for k := range bv {
+                if _, ok := av[k]; !ok {
+                    keys = append(keys, k)
+                }
+            }
+            sort.Strings(keys)
+            for _, k := range keys {
+                p := path + "/" + pointerEscaper.Replace(k)
+                was, inA := av[k]
+                is, inB := bv[k]
+                switch {
+                case !inA:
+                    *out = append(*out, auditChange{Op: "add", Path: p, New: is})
+                case !inB:
+                    *out = append(*out, auditChange{Op: "remove", Path: p, Old: was})
+                default:
+                    diffJSON(p, was, is, out)
+                }
+            }
+            return
+        }
+    case []interface{}:
+        if bv, ok := b.([]interface{}); ok && len(av) == len(bv) {
+            for i := range av {
+                diffJSON(path+"/"+strconv.Itoa(i), av[i], bv[i], out)
+            }
+            return
+        }
+    }
+    if !reflect.DeepEqual(a, b) {
+        *out = append(*out, auditChange{Op: "replace", Path: path, Old: a, New: b})
+    }
+}
+
+// auditHandler serves GET /admin/audit: every recorded entry, oldest first,
+// or only one phone's with ?phone=.
+func auditHandler(w http.ResponseWriter, r *http.Request) {
+    phone := r.URL.Query().Get("phone")
+    if phone != "" && !phonePattern.MatchString(phone) {
+        http.Error(w, "invalid phone", http.StatusBadRequest)
+        return
+    }
+    path := auditPath()
+    if path == "" {
+        http.Error(w, "audit log disabled", http.StatusNotFound)
+        return
+    }
+    entries := []json.RawMessage{}
+    auditLog.Lock()
+    f, err := os.Open(path)
+    if err == nil {
+        sc := bufio.NewScanner(f)
+        sc.Buffer(nil, 4*maxFixtureUpload)
+        for sc.Scan() {
+            var e struct {
+                Phone string `json:"phone"`
+            }
+            if json.Unmarshal(sc.Bytes(), &e) != nil || (phone != "" && e.Phone != phone) {
+                continue
+            }
+            entries = append(entries, append(json.RawMessage(nil), sc.Bytes()...))
+        }
+        err = sc.Err()
+        f.Close()
+    }
+    auditLog.Unlock()
+    if err != nil && !errors.Is(err, fs.ErrNotExist) {
+        log.Printf("audit: %v", readErr(err))
+        http.Error(w, "could not read audit log", http.StatusInternalServerError)
+        return
+    }
+    writeJSON(w, entries)
+}
@@ -0,0 +1,80 @@
+package main
+
+import (
+    "fmt"
+    "net/http"
+    "strings"
+)
+
+// ————— bank accounts —————
+// The bank fixture has one bankTransactions entry per account but no account
+// identifier, so ids are derived from the bank name and its ordinal in the
+// file: "hdfc-bank-1", "hdfc-bank-2", ...
+
+type bankAccount struct {
+    AccountID        string `json:"accountId"`
+    Bank             string `json:"bank"`
+    TransactionCount int    `json:"transactionCount"`
+}
+
+func bankAccountIDs(bt *bankTransactionsFile) []string {
+    seen := map[string]int{}
+    ids := make([]string, len(bt.BankTransactions))
+    for i, acc := range bt.BankTransactions {
+        slug := slugify(acc.Bank)
+        seen[slug]++
+        ids[i] = fmt.Sprintf("%s-%d", slug, seen[slug])
+    }
+    return ids
+}
+
+func slugify(s string) string {
+    return strings.Join(strings.FieldsFunc(strings.ToLower(s), func(r rune) bool {
+        return !(r >= 'a' && r <= 'z' || r >= '0' && r <= '9')
+    }), "-")
+}
+
+func accountsHandler(w http.ResponseWriter, r *http.Request) {
+    phone := r.Context().Value("phone").(string)
+    bt, err := loadBankTransactions(r.Context(), phone)
+    if err != nil {
+        http.Error(w, "data not found", http.StatusInternalServerError)
+        return
+    }
+    accounts := []bankAccount{}
+    for i, id := range bankAccountIDs(bt) {
+        accounts = append(accounts, bankAccount{
+            AccountID:        id,
+            Bank:             bt.BankTransactions[i].Bank,
+            TransactionCount: len(bt.BankTransactions[i].Txns),
+        })
+    }
+    writeJSON(w, map[string]interface{}{"accounts": accounts})
+}
+
+// bankTransactionsHandler serves the raw fixture unless ?accountId= narrows
+// it to one account. Unknown ids yield an empty list.
+func bankTransactionsHandler() http.Handler {
+    raw := apiHandler("fetch_bank_transactions.json")
+    return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+        accountID := r.URL.Query().Get("accountId")
+        if accountID == "" {
+            raw.ServeHTTP(w, r)
+            return
+        }
+        phone := r.Context().Value("phone").(string)
+        bt, err := loadBankTransactions(r.Context(), phone)
+        if err != nil {
+            http.Error(w, "data not found", http.StatusInternalServerError)
+            return
+        }
+        filtered := []bankAccountTxns{}
+        for i, id := range bankAccountIDs(bt) {
+            if id == accountID {
+                filtered = append(filtered, bt.BankTransactions[i])
+            }
+        }
+        bt.BankTransactions = filtered
+        writeJSON(w, bt)
+    })
+}
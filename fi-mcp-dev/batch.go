@@ -0,0 +1,208 @@
+package main
+
+import (
+    "context"
+    "encoding/json"
+    "fmt"
+    "net/http"
+    "strconv"
+    "strings"
+    "sync"
+    "time"
+)
+
+// ————— batch fetch / summary —————
+
+// batchHandler serves /api/batch?types=a,b: the raw fixture of each requested
+// data type, read in parallel. A type the phone has no (valid) fixture for is
+// null rather than failing the whole batch.
+func batchHandler(w http.ResponseWriter, r *http.Request) {
+    phone := r.Context().Value("phone").(string)
+    var files []string
+    var names []string
+    for _, name := range strings.Split(r.URL.Query().Get("types"), ",") {
+        name = strings.TrimSpace(name)
+        if name == "" || contains(names, name) {
+            continue
+        }
+        e, ok := dataEndpointByName(name)
+        if !ok {
+            http.Error(w, fmt.Sprintf("unknown data type: %s", name), http.StatusBadRequest)
+            return
+        }
+        names = append(names, name)
+        files = append(files, e.File)
+    }
+    if len(names) == 0 {
+        http.Error(w, "types is required", http.StatusBadRequest)
+        return
+    }
+
+    out := readFixtures(r.Context(), phone, names, files)
+    if r.Context().Err() != nil {
+        return
+    }
+    writeJSON(w, out)
+}
+
+// summaryHandler serves /api/summary: every data type's fixture in one
+// object, null where the phone has none, plus the headline numbers. With
+// ?explain=true an explain object gives each headline number's provenance.
+func summaryHandler(w http.ResponseWriter, r *http.Request) {
+    phone := r.Context().Value("phone").(string)
+    var explain map[string]*provenance
+    if s := r.URL.Query().Get("explain"); s != "" {
+        on, err := strconv.ParseBool(s)
+        if err != nil {
+            http.Error(w, "explain must be true or false", http.StatusBadRequest)
+            return
+        }
+        if on {
+            explain = map[string]*provenance{}
+        }
+    }
+    names := make([]string, len(dataEndpoints))
+    files := make([]string, len(dataEndpoints))
+    for i, e := range dataEndpoints {
+        names[i], files[i] = e.Name, e.File
+    }
+    out := map[string]interface{}{}
+    for name, data := range readFixtures(r.Context(), phone, names, files) {
+        out[name] = data
+    }
+    out["headline"] = summaryHeadline(r.Context(), phone, explain)
+    if explain != nil {
+        out["explain"] = explain
+    }
+    if r.Context().Err() != nil {
+        return
+    }
+    writeJSON(w, out)
+}
+
+// headline holds the dashboard numbers; each is null when the fixture
+// it comes from is missing rather than a misleading 0.
+type headline struct {
+    TotalNetWorth       *float64 `json:"totalNetWorth"`
+    TotalLiabilities    *float64 `json:"totalLiabilities"`
+    CreditScore         *float64 `json:"creditScore"`
+    MFPortfolioValue    *float64 `json:"mfPortfolioValue"`
+    StockPortfolioValue *float64 `json:"stockPortfolioValue"`
+    // Last30DaySpend covers the 30 days up to SpendAsOf, the latest bank
+    // transaction, since fixtures are historical.
+    Last30DaySpend *float64 `json:"last30DaySpend"`
+    SpendAsOf      *string  `json:"spendAsOf"`
+}
+
+// provenance is where a headline number came from: the fixture, the place
+// in it, and the values found there as the file has them. Raw is null when
+// the fixture or its values are missing, as is the number.
+type provenance struct {
+    Source string      `json:"source"`
+    Path   string      `json:"path"`
+    Raw    interface{} `json:"raw"`
+}
+
+// summaryHeadline works out the headline numbers. A non-nil explain gets
+// the provenance of each, keyed by its headline field.
+func summaryHeadline(ctx context.Context, phone string, explain map[string]*provenance) headline {
+    var h headline
+    num := func(v float64) *float64 { v = round2(v); return &v }
+    note := func(field, source, path string) *provenance {
+        p := &provenance{Source: source, Path: path}
+        if explain != nil {
+            explain[field] = p
+        }
+        return p
+    }
+    const (
+        netWorthFixture = "fetch_net_worth.json"
+        creditFixture   = "fetch_credit_report.json"
+        bankFixture     = "fetch_bank_transactions.json"
+    )
+    netWorthSrc := note("totalNetWorth", netWorthFixture, "netWorthResponse.totalNetWorthValue")
+    liabilitiesSrc := note("totalLiabilities", netWorthFixture, "netWorthResponse.assetValues and liabilityValues, LIABILITY_TYPE_*")
+    mfSrc := note("mfPortfolioValue", netWorthFixture, "netWorthResponse.assetValues, ASSET_TYPE_MUTUAL_FUND")
+    stockSrc := note("stockPortfolioValue", netWorthFixture, "netWorthResponse.assetValues, ASSET_TYPE_INDIAN_SECURITIES and ASSET_TYPE_US_SECURITIES")
+    scoreSrc := note("creditScore", creditFixture, "creditReports[].creditReportData.score.bureauScore")
+    spendSrc := note("last30DaySpend", bankFixture, "bankTransactions[].txns, spending in the 30 days up to spendAsOf")
+    asOfSrc := note("spendAsOf", bankFixture, "bankTransactions[].txns, latest date")
+
+    if nw, err := loadNetWorth(ctx, phone); err == nil {
+        prefix := func(p string) func(string) bool {
+            return func(name string) bool { return strings.HasPrefix(name, p) }
+        }
+        is := func(names ...string) func(string) bool {
+            return func(name string) bool { return contains(names, name) }
+        }
+        h.TotalNetWorth = num(nw.NetWorthResponse.TotalNetWorthValue.Float())
+        netWorthSrc.Raw = nw.NetWorthResponse.TotalNetWorthValue
+        h.TotalLiabilities = num(nw.totalLiabilities())
+        liabilitiesSrc.Raw = nw.attributes(prefix("LIABILITY_TYPE_"))
+        if nw.hasAttribute("ASSET_TYPE_MUTUAL_FUND") {
+            h.MFPortfolioValue = num(nw.attribute("ASSET_TYPE_MUTUAL_FUND"))
+            mfSrc.Raw = nw.attributes(is("ASSET_TYPE_MUTUAL_FUND"))
+        }
+        if nw.hasAttribute("ASSET_TYPE_INDIAN_SECURITIES") || nw.hasAttribute("ASSET_TYPE_US_SECURITIES") {
+            h.StockPortfolioValue = num(nw.attribute("ASSET_TYPE_INDIAN_SECURITIES") + nw.attribute("ASSET_TYPE_US_SECURITIES"))
+            stockSrc.Raw = nw.attributes(is("ASSET_TYPE_INDIAN_SECURITIES", "ASSET_TYPE_US_SECURITIES"))
+        }
+    }
+    if cr, err := loadCreditReport(ctx, phone); err == nil {
+        if s, ok := cr.creditScore(); ok {
+            h.CreditScore = &s
+            scoreSrc.Raw, _ = cr.bureauScore()
+        }
+    }
+    if bt, err := loadBankTransactions(ctx, phone); err == nil {
+        if end := bt.latestTxnDate(); !end.IsZero() {
+            start := end.AddDate(0, 0, -29).Format(time.DateOnly)
+            var spend float64
+            rows := []bankTxn{}
+            for _, acc := range bt.BankTransactions {
+                for _, t := range acc.Txns {
+                    if t.isSpend() && t.Date >= start {
+                        spend += t.Amount
+                        rows = append(rows, t)
+                    }
+                }
+            }
+            asOf := end.Format(time.DateOnly)
+            h.Last30DaySpend, h.SpendAsOf = num(spend), &asOf
+            spendSrc.Raw, asOfSrc.Raw = rows, asOf
+        }
+    }
+    return h
+}
+
+// readFixtures reads files[i] for names[i] in parallel. A missing, empty or
+// invalid fixture maps to a nil RawMessage, which encodes as null.
+func readFixtures(ctx context.Context, phone string, names, files []string) map[string]json.RawMessage {
+    results := make([]json.RawMessage, len(names))
+    var wg sync.WaitGroup
+    for i := range names {
+        wg.Add(1)
+        go func(i int) {
+            defer wg.Done()
+            data, err := fixtureBody(ctx, phone, files[i])
+            if err == nil && json.Valid(data) {
+                results[i] = data
+            }
+        }(i)
+    }
+    wg.Wait()
+    out := make(map[string]json.RawMessage, len(names))
+    for i, name := range names {
+        out[name] = results[i]
+    }
+    return out
+}
+
+func contains(list []string, s string) bool {
+    for _, v := range list {
+        if v == s {
+            return true
+        }
+    }
+    return false
+}
@@ -0,0 +1,90 @@
+package main
+
+import (
+    "context"
+    "net/http"
+    "sync"
+    "time"
+
+    "github.com/epifi/fi-mcp-lite/pkg"
+)
+
+// ————— cohort benchmarks —————
+// Averages over every allowed phone, recomputed at most once per
+// FI_MCP_BENCHMARK_TTL. Each average only counts phones that have the data
+// behind it, and reports how many did.
+
+type benchmarkAverage struct {
+    Average *float64 `json:"average"` // nil when no phone has the data
+    Count   int      `json:"count"`
+}
+
+type cohortBenchmarks struct {
+    NetWorth     benchmarkAverage `json:"netWorth"`
+    CreditScore  benchmarkAverage `json:"creditScore"`
+    MonthlySpend benchmarkAverage `json:"monthlySpend"`
+    CohortSize   int              `json:"cohortSize"`
+    ComputedAt   time.Time        `json:"computedAt"`
+}
+
+var benchmarkCache struct {
+    sync.Mutex
+    value *cohortBenchmarks
+}
+
+func benchmarksHandler(w http.ResponseWriter, r *http.Request) {
+    writeJSON(w, cachedBenchmarks(time.Now()))
+}
+
+// cachedBenchmarks holds the lock while recomputing so concurrent callers
+// wait for one computation instead of each starting their own.
+func cachedBenchmarks(now time.Time) *cohortBenchmarks {
+    benchmarkCache.Lock()
+    defer benchmarkCache.Unlock()
+    if v := benchmarkCache.value; v != nil && now.Sub(v.ComputedAt) < pkg.GetBenchmarkTTL() {
+        return v
+    }
+    // not the request's context: a cancelled request must not cache a
+    // partial cohort
+    benchmarkCache.value = computeBenchmarks(context.Background(), now)
+    return benchmarkCache.value
+}
+
+func computeBenchmarks(ctx context.Context, now time.Time) *cohortBenchmarks {
+    var netWorth, credit, spend []float64
+    phones := allowedMobileNumbers()
+    for _, phone := range phones {
+        if nw, err := loadNetWorth(ctx, phone); err == nil {
+            netWorth = append(netWorth, nw.NetWorthResponse.TotalNetWorthValue.Float())
+        }
+        if cr, err := loadCreditReport(ctx, phone); err == nil {
+            if s, ok := cr.creditScore(); ok {
+                credit = append(credit, s)
+            }
+        }
+        if bt, err := loadBankTransactions(ctx, phone); err == nil {
+            if cf := bt.monthlyCashflow(); cf.Months > 0 {
+                spend = append(spend, cf.Spend)
+            }
+        }
+    }
+    return &cohortBenchmarks{
+        NetWorth:     average(netWorth),
+        CreditScore:  average(credit),
+        MonthlySpend: average(spend),
+        CohortSize:   len(phones),
+        ComputedAt:   now.UTC(),
+    }
+}
+
+func average(values []float64) benchmarkAverage {
+    if len(values) == 0 {
+        return benchmarkAverage{}
+    }
+    var sum float64
+    for _, v := range values {
+        sum += v
+    }
+    avg := round2(sum / float64(len(values)))
+    return benchmarkAverage{Average: &avg, Count: len(values)}
+}
@@ -0,0 +1,12 @@
+//go:build embedfixtures
+
+package main
+
+import "embed"
+
+//go:embed test_data_dir static
+var bundle embed.FS
+
+func init() {
+    bundledFiles = bundle
+}
@@ -0,0 +1,153 @@
+package main
+
+import (
+    "bytes"
+    "context"
+    "net/http"
+    "strings"
+    "sync"
+    "time"
+
+    "github.com/epifi/fi-mcp-lite/pkg"
+)
+
+// ————— response cache —————
+// Computed endpoints listed in FI_MCP_CACHE keep their 200 responses per
+// scenario, phone and query. Within the TTL the cached copy is served
+// (X-Cache: HIT); within the grace window after it the stale copy is served
+// (STALE) while one background refresh recomputes it; past that the request
+// computes and waits (MISS).
+
+const maxCacheEntries = 1024
+
+type cachedResponse struct {
+    status     int
+    header     http.Header
+    body       []byte
+    at         time.Time
+    refreshing bool
+}
+
+// cacheTTLs reads FI_MCP_CACHE once, so bad entries are only logged once.
+var cacheTTLs = sync.OnceValue(pkg.GetCacheTTLs)
+
+var responses = struct {
+    sync.Mutex
+    entries map[string]*cachedResponse
+}{entries: map[string]*cachedResponse{}}
+
+// purgeCachedResponses drops every cached response for phone, after its
+// fixtures changed.
+func purgeCachedResponses(phone string) {
+    responses.Lock()
+    defer responses.Unlock()
+    for key := range responses.entries {
+        if parts := strings.SplitN(key, "|", 4); len(parts) == 4 && parts[2] == phone {
+            delete(responses.entries, key)
+        }
+    }
+}
+
+// responseCache wraps a computed endpoint; it must run inside withAuth. name
+// selects the endpoint's FI_MCP_CACHE entry, without one caching is off.
+func responseCache(name string, next http.Handler) http.Handler {
+    cfg, ok := cacheTTLs()[name]
+    if !ok {
+        return next
+    }
+    return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+        phone := r.Context().Value("phone").(string)
+        key := name + "|" + scenarioFrom(r.Context()) + "|" + phone + "|" + r.URL.Query().Encode()
+        now := time.Now()
+
+        responses.Lock()
+        e := responses.entries[key]
+        switch {
+        case e != nil && now.Sub(e.at) < cfg.TTL:
+            responses.Unlock()
+            serveCached(w, e, "HIT")
+            return
+        case e != nil && now.Sub(e.at) < cfg.TTL+cfg.Grace:
+            if !e.refreshing {
+                e.refreshing = true
+                // keep the request's values (phone, scenario) but not its
+                // cancellation, which ends as soon as this reply is sent
+                bg := r.Clone(context.WithoutCancel(r.Context()))
+                go func() {
+                    fresh := record(next, bg)
+                    responses.Lock()
+                    e.refreshing = false
+                    if fresh.status == http.StatusOK {
+                        responses.entries[key] = fresh
+                    }
+                    responses.Unlock()
+                }()
+            }
+            responses.Unlock()
+            serveCached(w, e, "STALE")
+            return
+        }
+        responses.Unlock()
+
+        fresh := record(next, r)
+        if fresh.status == http.StatusOK {
+            storeResponse(key, fresh, cfg.TTL+cfg.Grace)
+        }
+        serveCached(w, fresh, "MISS")
+    })
+}
+
+func storeResponse(key string, resp *cachedResponse, lifetime time.Duration) {
+    responses.Lock()
+    defer responses.Unlock()
+    if len(responses.entries) >= maxCacheEntries {
+        for k, e := range responses.entries {
+            if time.Since(e.at) >= lifetime {
+                delete(responses.entries, k)
+            }
+        }
+        for k := range responses.entries {
+            if len(responses.entries) < maxCacheEntries {
+                break
+            }
+            delete(responses.entries, k) // arbitrary victim once nothing has expired
+        }
+    }
+    responses.entries[key] = resp
+}
+
+func serveCached(w http.ResponseWriter, e *cachedResponse, state string) {
+    for k, v := range e.header {
+        w.Header()[k] = v
+    }
+    w.Header().Set("X-Cache", state)
+    w.WriteHeader(e.status)
+    w.Write(e.body)
+}
+
+// record runs the handler into memory.
+func record(h http.Handler, r *http.Request) *cachedResponse {
+    rec := &recorder{header: http.Header{}, status: http.StatusOK}
+    h.ServeHTTP(rec, r)
+    return &cachedResponse{status: rec.status, header: rec.header, body: rec.body.Bytes(), at: time.Now()}
+}
+
+type recorder struct {
+    header http.Header
+    status int
+    wrote  bool
+    body   bytes.Buffer
+}
+
+func (r *recorder) Header() http.Header { return r.header }
+
+func (r *recorder) WriteHeader(code int) {
+    if !r.wrote {
+        r.status, r.wrote = code, true
+    }
+}
+
+func (r *recorder) Write(b []byte) (int, error) {
+    r.wrote = true
+    return r.body.Write(b)
+}
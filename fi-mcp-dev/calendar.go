@@ -0,0 +1,61 @@
+package main
+
+import (
+    "net/http"
+    "time"
+)
+
+// ————— spending calendar —————
+// Daily spend (debits, installments, TDS) for a heatmap over the last ?days=
+// days of the bank fixture. The window ends on the latest transaction date,
+// not today, since fixtures are historical; every day in it is present, zero
+// or not.
+
+const maxCalendarDays = 366
+
+func spendingCalendarHandler(w http.ResponseWriter, r *http.Request) {
+    phone := r.Context().Value("phone").(string)
+    days, err := intParam(r.URL.Query().Get("days"), 90, 1, maxCalendarDays)
+    if err != nil {
+        http.Error(w, "days must be an integer from 1 to 366", http.StatusBadRequest)
+        return
+    }
+    bt, err := loadBankTransactions(r.Context(), phone)
+    if err != nil {
+        http.Error(w, "data not found", http.StatusInternalServerError)
+        return
+    }
+
+    end := bt.latestTxnDate()
+    if end.IsZero() {
+        http.Error(w, "data not found", http.StatusInternalServerError)
+        return
+    }
+    start := end.AddDate(0, 0, -(days - 1))
+
+    calendar := make(map[string]float64, days)
+    for d := start; !d.After(end); d = d.AddDate(0, 0, 1) {
+        calendar[d.Format(time.DateOnly)] = 0
+    }
+    var total float64
+    for _, acc := range bt.BankTransactions {
+        for _, t := range acc.Txns {
+            if !t.isSpend() {
+                continue
+            }
+            if _, ok := calendar[t.Date]; ok {
+                calendar[t.Date] += t.Amount
+                total += t.Amount
+            }
+        }
+    }
+    for d, v := range calendar {
+        calendar[d] = round2(v)
+    }
+    writeJSON(w, map[string]interface{}{
+        "from":  start.Format(time.DateOnly),
+        "to":    end.Format(time.DateOnly),
+        "days":  calendar,
+        "total": round2(total),
+    })
+}
@@ -0,0 +1,159 @@
+package main
+
+import (
+    "context"
+    "fmt"
+    "math/rand"
+    "net/http"
+    "strconv"
+    "strings"
+    "sync"
+    "sync/atomic"
+    "time"
+
+    "github.com/epifi/fi-mcp-lite/pkg"
+)
+
+// ————— fault injection —————
+// With FI_MCP_CHAOS on, requests under FI_MCP_CHAOS_PATHS can be delayed,
+// failed with a 4xx/5xx, cut off part way through the body, or (for SSE
+// streams) dropped after a while, so clients can rehearse bad networks. The
+// FI_MCP_CHAOS_* settings apply to every such request; X-Chaos-Latency,
+// X-Chaos-Error-Rate, X-Chaos-Error-Status, X-Chaos-Truncate-Rate and
+// X-Chaos-Drop-After override them per request. X-Chaos on the response
+// lists what was injected.
+
+var chaosConfig = sync.OnceValue(pkg.GetChaos)
+
+func withChaos(next http.Handler) http.Handler {
+    if !chaosConfig().Enabled {
+        return next
+    }
+    return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+        c := chaosConfig()
+        if !chaosPath(c.Paths, r.URL.Path) {
+            next.ServeHTTP(w, r)
+            return
+        }
+        if err := chaosHeaders(&c, r.Header); err != nil {
+            http.Error(w, err.Error(), http.StatusBadRequest)
+            return
+        }
+        var injected []string
+
+        if d := pickDuration(c.Latency); d > 0 {
+            t := time.NewTimer(d)
+            select {
+            case <-r.Context().Done():
+                t.Stop()
+                return
+            case <-t.C:
+            }
+            injected = append(injected, "latency="+d.String())
+        }
+        if c.ErrorRate > 0 && rand.Float64() < c.ErrorRate {
+            status := c.ErrorStatus[rand.Intn(len(c.ErrorStatus))]
+            w.Header().Set("X-Chaos", strings.Join(append(injected, "error="+strconv.Itoa(status)), ", "))
+            if status == http.StatusTooManyRequests || status == http.StatusServiceUnavailable {
+                w.Header().Set("Retry-After", "1")
+            }
+            http.Error(w, fmt.Sprintf("injected fault: %d %s", status, http.StatusText(status)), status)
+            return
+        }
+
+        if strings.HasPrefix(r.URL.Path, "/stream/") {
+            if d := pickDuration(c.DropAfter); d > 0 {
+                injected = append(injected, "drop="+d.String())
+                w.Header().Set("X-Chaos", strings.Join(injected, ", "))
+                dropStreamAfter(d, next, w, r)
+                return
+            }
+        } else if c.TruncateRate > 0 && rand.Float64() < c.TruncateRate {
+            w.Header().Set("X-Chaos", strings.Join(append(injected, "truncate"), ", "))
+            truncated(next, w, r)
+            return
+        }
+        if len(injected) > 0 {
+            w.Header().Set("X-Chaos", strings.Join(injected, ", "))
+        }
+        next.ServeHTTP(w, r)
+    })
+}
+
+func chaosPath(prefixes []string, path string) bool {
+    for _, p := range prefixes {
+        if strings.HasPrefix(path, p) {
+            return true
+        }
+    }
+    return false
+}
+
+// chaosHeaders applies the request's X-Chaos-* overrides to c.
+func chaosHeaders(c *pkg.Chaos, h http.Header) error {
+    var err error
+    if v := h.Get("X-Chaos-Latency"); v != "" {
+        if c.Latency, err = pkg.ParseDurationRange(v); err != nil {
+            return fmt.Errorf("invalid X-Chaos-Latency: %v", err)
+        }
+    }
+    if v := h.Get("X-Chaos-Error-Rate"); v != "" {
+        if c.ErrorRate, err = pkg.ParseRate(v); err != nil {
+            return fmt.Errorf("invalid X-Chaos-Error-Rate: %v", err)
+        }
+    }
+    if v := h.Get("X-Chaos-Error-Status"); v != "" {
+        if c.ErrorStatus, err = pkg.ParseStatuses(v); err != nil {
+            return fmt.Errorf("invalid X-Chaos-Error-Status: %v", err)
+        }
+    }
+    if v := h.Get("X-Chaos-Truncate-Rate"); v != "" {
+        if c.TruncateRate, err = pkg.ParseRate(v); err != nil {
+            return fmt.Errorf("invalid X-Chaos-Truncate-Rate: %v", err)
+        }
+    }
+    if v := h.Get("X-Chaos-Drop-After"); v != "" {
+        if c.DropAfter, err = pkg.ParseDurationRange(v); err != nil {
+            return fmt.Errorf("invalid X-Chaos-Drop-After: %v", err)
+        }
+    }
+    return nil
+}
+
+func pickDuration(d pkg.DurationRange) time.Duration {
+    if d.Max <= d.Min {
+        return d.Min
+    }
+    return d.Min + time.Duration(rand.Int63n(int64(d.Max-d.Min)))
+}
+
+// truncated sends the full Content-Length but only half the body, then
+// aborts the connection, so the client sees an unexpected EOF.
+func truncated(next http.Handler, w http.ResponseWriter, r *http.Request) {
+    resp := record(next, r)
+    for k, v := range resp.header {
+        w.Header()[k] = v
+    }
+    w.Header().Set("Content-Length", strconv.Itoa(len(resp.body)))
+    w.WriteHeader(resp.status)
+    w.Write(resp.body[:len(resp.body)/2])
+    http.NewResponseController(w).Flush()
+    panic(http.ErrAbortHandler)
+}
+
+// dropStreamAfter ends the stream after d and aborts the connection rather
+// than closing it cleanly, like a dropped mobile network.
+func dropStreamAfter(d time.Duration, next http.Handler, w http.ResponseWriter, r *http.Request) {
+    ctx, cancel := context.WithCancel(r.Context())
+    defer cancel()
+    var dropped atomic.Bool
+    t := time.AfterFunc(d, func() {
+        dropped.Store(true)
+        cancel()
+    })
+    next.ServeHTTP(w, r.WithContext(ctx))
+    t.Stop()
+    if dropped.Load() {
+        panic(http.ErrAbortHandler)
+    }
+}
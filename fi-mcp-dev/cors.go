@@ -0,0 +1,62 @@
+package main
+
+import (
+    "net/http"
+    "strings"
+
+    "github.com/epifi/fi-mcp-lite/pkg"
+)
+
+// ————— CORS —————
+// Origins listed in FI_MCP_CORS_ORIGINS may call the API and streams from a
+// browser, with the session cookie unless FI_MCP_CORS_CREDENTIALS is off.
+// Preflights are answered here, before auth and the data directory check;
+// other origins get no CORS headers, so the browser blocks them.
+// FI_MCP_CORS_HEADERS adds request headers to the ones below.
+
+const (
+    corsMethods = "GET, POST, PUT, DELETE, OPTIONS"
+    corsHeaders = "Authorization, Content-Type, Last-Event-ID, X-Scenario, X-Latency-Budget-Ms, X-Request-ID"
+    corsExpose  = "Retry-After, X-Cache, X-Scenario, X-Latency-Used-Ms, X-Latency-Budget-Met, X-Request-ID"
+)
+
+func withCORS(next http.Handler) http.Handler {
+    allowed := map[string]bool{}
+    for _, o := range pkg.GetCORSOrigins() {
+        allowed[o] = true
+    }
+    if len(allowed) == 0 {
+        return next
+    }
+    headers := corsHeaders
+    if extra := pkg.GetCORSHeaders(); len(extra) > 0 {
+        headers += ", " + strings.Join(extra, ", ")
+    }
+    credentials := pkg.GetCORSCredentials()
+    return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+        origin := r.Header.Get("Origin")
+        w.Header().Add("Vary", "Origin")
+        preflight := r.Method == http.MethodOptions && r.Header.Get("Access-Control-Request-Method") != ""
+        if !allowed[origin] {
+            if preflight {
+                http.Error(w, "origin not allowed", http.StatusForbidden)
+                return
+            }
+            next.ServeHTTP(w, r)
+            return
+        }
+        w.Header().Set("Access-Control-Allow-Origin", origin)
+        if credentials {
+            w.Header().Set("Access-Control-Allow-Credentials", "true")
+        }
+        if preflight {
+            w.Header().Set("Access-Control-Allow-Methods", corsMethods)
+            w.Header().Set("Access-Control-Allow-Headers", headers)
+            w.Header().Set("Access-Control-Max-Age", "600")
+            w.WriteHeader(http.StatusNoContent)
+            return
+        }
+        w.Header().Set("Access-Control-Expose-Headers", corsExpose)
+        next.ServeHTTP(w, r)
+    })
+}
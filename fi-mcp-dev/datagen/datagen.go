@@ -0,0 +1,155 @@
+// Package datagen makes randomized fixtures for test personas: bank
+// transactions, mutual fund SIPs, stock trades, EPF, a credit report and the
+// net worth that ties them together. The numbers agree with each other (the
+// savings balance in net worth is what the bank rows end on, SIP debits match
+// the fund purchases, EMIs match the loans), and the same profile, seed and
+// end date always give the same files.
+package datagen
+
+import (
+    "encoding/json"
+    "fmt"
+    "math/rand"
+    "sort"
+    "strings"
+    "time"
+)
+
+// DefaultProfile is used when Options.Profile is empty.
+const DefaultProfile = "young_salaried"
+
+// Options picks what Generate makes.
+type Options struct {
+    Profile string
+    Seed    int64
+    Months  int       // months of bank history, default 6
+    End     time.Time // date of the latest transaction, default today
+}
+
+// Range is an inclusive range of amounts (or counts) a persona is drawn from.
+type Range struct{ Min, Max float64 }
+
+// profile is a persona template. Amounts are monthly and in rupees.
+type profile struct {
+    Description string
+    Age         Range
+    Income      Range
+    IncomeKind  string // "salary", "pension" or "freelance"
+    Rent        Range  // 0 for none; skipped when there is a home loan
+    SpendShare  Range  // share of what is left after commitments that is spent
+    Opening     Range  // balance of the main account before the first row
+    SIPs        Range  // number of schemes
+    SIPAmount   Range
+    Stocks      Range // number of holdings
+    EPF         Range // EPF balance; zero for no EPF account
+    Score       Range // bureau score
+    Loans       []string
+    LoanChance  float64 // chance of each of Loans
+}
+
+var profiles = map[string]profile{
+    "young_salaried": {
+        Description: "early-career salaried, renting, small SIPs",
+        Age:         Range{22, 30}, Income: Range{40000, 95000}, IncomeKind: "salary",
+        Rent: Range{10000, 25000}, SpendShare: Range{0.45, 0.75}, Opening: Range{15000, 150000},
+        SIPs: Range{1, 3}, SIPAmount: Range{1000, 10000}, Stocks: Range{0, 3},
+        EPF: Range{30000, 300000}, Score: Range{680, 790},
+        Loans: []string{"card", "personal", "vehicle"}, LoanChance: 0.4,
+    },
+    "family": {
+        Description: "mid-career salaried with a home loan and a larger portfolio",
+        Age:         Range{32, 45}, Income: Range{120000, 300000}, IncomeKind: "salary",
+        Rent: Range{20000, 45000}, SpendShare: Range{0.4, 0.7}, Opening: Range{100000, 800000},
+        SIPs: Range{3, 6}, SIPAmount: Range{5000, 25000}, Stocks: Range{2, 8},
+        EPF: Range{500000, 2500000}, Score: Range{720, 840},
+        Loans: []string{"home", "vehicle", "card"}, LoanChance: 0.8,
+    },
+    "retiree": {
+        Description: "pensioner living on savings and interest",
+        Age:         Range{60, 75}, Income: Range{25000, 70000}, IncomeKind: "pension",
+        SpendShare: Range{0.5, 0.8}, Opening: Range{500000, 2500000},
+        SIPs: Range{0, 2}, SIPAmount: Range{2000, 10000}, Stocks: Range{2, 6},
+        Score: Range{700, 820},
+        Loans: []string{"card"}, LoanChance: 0.3,
+    },
+    "freelancer": {
+        Description: "self-employed with irregular client payments",
+        Age:         Range{25, 40}, Income: Range{30000, 200000}, IncomeKind: "freelance",
+        Rent: Range{10000, 30000}, SpendShare: Range{0.4, 0.7}, Opening: Range{50000, 400000},
+        SIPs: Range{0, 3}, SIPAmount: Range{2000, 15000}, Stocks: Range{0, 5},
+        Score: Range{650, 780},
+        Loans: []string{"personal", "card"}, LoanChance: 0.5,
+    },
+}
+
+// Profiles returns the profile names, sorted.
+func Profiles() []string {
+    names := make([]string, 0, len(profiles))
+    for name := range profiles {
+        names = append(names, name)
+    }
+    sort.Strings(names)
+    return names
+}
+
+// Describe returns a one-line description of a profile, or "" if unknown.
+func Describe(name string) string {
+    return profiles[name].Description
+}
+
+// Validate fills in the defaults and reports options Generate would reject.
+func (opts *Options) Validate() error {
+    if opts.Profile == "" {
+        opts.Profile = DefaultProfile
+    }
+    if _, ok := profiles[opts.Profile]; !ok {
+        return fmt.Errorf("unknown profile %q (have %s)", opts.Profile, strings.Join(Profiles(), ", "))
+    }
+    if opts.Months == 0 {
+        opts.Months = 6
+    }
+    if opts.Months < 1 || opts.Months > 120 {
+        return fmt.Errorf("months must be between 1 and 120")
+    }
+    return nil
+}
+
+// Generate returns the fixtures for one persona keyed by file name
+// (fetch_net_worth.json, fetch_bank_transactions.json, ...).
+func Generate(opts Options) (map[string][]byte, error) {
+    if err := opts.Validate(); err != nil {
+        return nil, err
+    }
+    p := profiles[opts.Profile]
+    end := opts.End
+    if end.IsZero() {
+        end = time.Now()
+    }
+    end = time.Date(end.Year(), end.Month(), end.Day(), 0, 0, 0, 0, time.UTC)
+
+    g := &gen{
+        r:     rand.New(rand.NewSource(opts.Seed)),
+        p:     p,
+        end:   end,
+        start: end.AddDate(0, -opts.Months, 1),
+    }
+    g.build()
+
+    docs := map[string]interface{}{
+        "fetch_bank_transactions.json":  g.bankFixture(),
+        "fetch_mf_transactions.json":    g.mfFixture(),
+        "fetch_stock_transactions.json": g.stockFixture(),
+        "fetch_epf_details.json":        g.epfFixture(),
+        "fetch_credit_report.json":      g.creditFixture(),
+        "fetch_net_worth.json":          g.netWorthFixture(),
+    }
+    out := make(map[string][]byte, len(docs))
+    for file, doc := range docs {
+        data, err := json.MarshalIndent(doc, "", "  ")
+        if err != nil {
+            return nil, fmt.Errorf("%s: %w", file, err)
+        }
+        out[file] = data
+    }
+    return out, nil
+}
@@ -0,0 +1,307 @@
+package datagen
+
+import (
+    "math"
+    "sort"
+    "strconv"
+    "time"
+)
+
+// ————— fixture documents —————
+// The shapes, and the schemaDescription strings, are those of the
+// hand-written fixtures in test_data_dir.
+
+const (
+    bankSchema  = "A list of bank transactions. Each 'txns' field is a list of data arrays with schema: [transactionAmount, transactionNarration, transactionDate, transactionType (1 for CREDIT, 2 for DEBIT, 3 for OPENING, 4 for INTEREST, 5 for TDS, 6 for INSTALLMENT, 7 for CLOSING and 8 for OTHERS), transactionMode, currentBalance]."
+    mfSchema    = "A list of mutual fund investments. Each 'txns' field is a list of data arrays with schema: [ orderType(1 for BUY and 2 for SELL), transactionDate, purchasePrice, purchaseUnits, transactionAmount ]."
+    stockSchema = "A list of stock transactions. Each 'txns' field is a list of data arrays with schema: [transactionType (1 for BUY, 2 for SELL, 3 for BONUS, 4 for SPLIT), transactionDate, quantity, navValue]. nav value may not be present in some of the transactions"
+)
+
+type obj = map[string]interface{}
+
+type money struct {
+    CurrencyCode string `json:"currencyCode"`
+    Units        string `json:"units"`
+    Nanos        int64  `json:"nanos,omitempty"`
+}
+
+func inr(v float64) money {
+    v = round2(v)
+    units := math.Trunc(v)
+    return money{"INR", strconv.FormatInt(int64(units), 10), int64(math.Round((v - units) * 1e9))}
+}
+
+// whole formats rupees the way the bureau and EPF files do: an integer string.
+func whole(v float64) string {
+    return strconv.FormatInt(int64(math.Round(v)), 10)
+}
+
+func (g *gen) bankFixture() obj {
+    accounts := []obj{}
+    for _, acc := range g.accounts {
+        accounts = append(accounts, obj{"bank": acc.bank.Name, "txns": acc.rows})
+    }
+    return obj{"schemaDescription": bankSchema, "bankTransactions": accounts}
+}
+
+// A persona without funds, stocks or EPF gets {}: how a phone has no data
+// of a type.
+
+func (g *gen) mfFixture() obj {
+    if len(g.funds) == 0 {
+        return obj{}
+    }
+    list := []obj{}
+    for _, f := range g.funds {
+        list = append(list, obj{"isin": f.scheme.ISIN, "schemeName": f.scheme.Name + " - Direct Plan", "folioId": f.folio, "txns": f.rows})
+    }
+    return obj{"schemaDescription": mfSchema, "mfTransactions": list}
+}
+
+func (g *gen) stockFixture() obj {
+    if len(g.shares) == 0 {
+        return obj{}
+    }
+    list := []obj{}
+    for _, s := range g.shares {
+        list = append(list, obj{"isin": s.stock.ISIN, "txns": s.rows})
+    }
+    return obj{"schemaDescription": stockSchema, "stockTransactions": list}
+}
+
+func (g *gen) epfFixture() obj {
+    if g.epf == nil {
+        return obj{}
+    }
+    epfDate := func(t time.Time) string {
+        if t.IsZero() {
+            return "NA"
+        }
+        return t.Format("02-01-2006")
+    }
+    ests := []obj{}
+    var total, employee float64
+    for _, e := range g.epf.establishments {
+        emp := math.Round(e.balance * 0.54)
+        total += e.balance
+        employee += emp
+        ests = append(ests, obj{
+            "est_name": e.name, "member_id": e.memberID, "office": e.office,
+            "doj_epf": epfDate(e.joined), "doe_epf": epfDate(e.left), "doe_eps": epfDate(e.left),
+            "pf_balance": obj{
+                "net_balance":    whole(e.balance),
+                "employee_share": obj{"credit": whole(emp), "balance": whole(emp)},
+                "employer_share": obj{"credit": whole(e.balance - emp), "balance": whole(e.balance - emp)},
+            },
+        })
+    }
+    return obj{"uanAccounts": []obj{{
+        "phoneNumber": obj{},
+        "rawDetails": obj{
+            "est_details": ests,
+            "overall_pf_balance": obj{
+                "pension_balance":      whole(g.epf.pension),
+                "current_pf_balance":   whole(total),
+                "employee_share_total": obj{"credit": whole(employee), "balance": whole(employee)},
+            },
+        },
+    }}}
+}
+
+func (g *gen) epfBalance() float64 {
+    var total float64
+    if g.epf != nil {
+        for _, e := range g.epf.establishments {
+            total += e.balance
+        }
+    }
+    return total
+}
+
+func (g *gen) creditFixture() obj {
+    bureau := func(t time.Time) string { return t.Format("20060102") }
+    details := []obj{}
+    var secured, unsecured float64
+    for _, l := range g.loans {
+        k := loanKinds[l.kind]
+        portfolio := "I"
+        if k.EMIShare == 0 {
+            portfolio = "R"
+            unsecured += l.balance
+        } else if l.kind == "personal" {
+            unsecured += l.balance
+        } else {
+            secured += l.balance
+        }
+        details = append(details, obj{
+            "subscriberName": l.lender, "portfolioType": portfolio, "accountType": k.AccountType,
+            "openDate": bureau(l.opened), "highestCreditOrOriginalLoanAmount": whole(l.original),
+            "accountStatus": "11", "paymentRating": "0", "paymentHistoryProfile": "000000000000",
+            "currentBalance": whole(l.balance), "amountPastDue": "0", "dateReported": bureau(g.end),
+            "rateOfInterest": strconv.FormatFloat(l.rate, 'f', -1, 64), "repaymentTenure": strconv.Itoa(l.tenure),
+            "currencyCode": "INR", "accountHolderTypeCode": "1",
+        })
+    }
+    all := secured + unsecured
+    pct := func(v float64) string {
+        if all == 0 {
+            return "0"
+        }
+        return whole(v / all * 100)
+    }
+    n := strconv.Itoa(len(details))
+    return obj{"creditReports": []obj{{
+        "creditReportData": obj{
+            "userMessage":         obj{"userMessageText": "Normal Response"},
+            "creditProfileHeader": obj{"reportDate": bureau(g.end), "reportTime": "101500"},
+            "currentApplication": obj{"currentApplicationDetails": obj{
+                "currentApplicantDetails": obj{"dateOfBirthApplicant": bureau(g.dob)},
+            }},
+            "creditAccount": obj{
+                "creditAccountSummary": obj{
+                    "account": obj{
+                        "creditAccountTotal": n, "creditAccountActive": n,
+                        "creditAccountDefault": "0", "creditAccountClosed": "0", "cadSuitFiledCurrentBalance": "0",
+                    },
+                    "totalOutstandingBalance": obj{
+                        "outstandingBalanceSecured": whole(secured), "outstandingBalanceSecuredPercentage": pct(secured),
+                        "outstandingBalanceUnSecured": whole(unsecured), "outstandingBalanceUnSecuredPercentage": pct(unsecured),
+                        "outstandingBalanceAll": whole(all),
+                    },
+                },
+                "creditAccountDetails": details,
+            },
+            "score": obj{"bureauScore": whole(g.score), "bureauScoreConfidenceLevel": "H"},
+        },
+        "vendor": "EXPERIAN",
+    }}}
+}
+
+func (g *gen) netWorthFixture() obj {
+    var savings, mf, equities float64
+    accounts := obj{}
+    balanceDate := g.end.Add(18 * time.Hour).Format(time.RFC3339)
+    for _, acc := range g.accounts {
+        savings += acc.balance
+        accounts[acc.id] = obj{
+            "accountDetails": obj{
+                "fipId": acc.bank.FIP, "maskedAccountNumber": acc.masked, "accInstrumentType": "ACC_INSTRUMENT_TYPE_DEPOSIT",
+                "ifscCode":    acc.bank.IFSC,
+                "accountType": obj{"depositAccountType": "DEPOSIT_ACCOUNT_TYPE_SAVINGS"},
+                "fipMeta":     obj{"name": acc.bank.Name, "displayName": acc.bank.Short, "bank": acc.bank.Short},
+            },
+            "depositSummary": obj{
+                "accountId": acc.id, "currentBalance": inr(acc.balance), "balanceDate": balanceDate,
+                "depositAccountType": "DEPOSIT_ACCOUNT_TYPE_SAVINGS", "ifscCode": acc.bank.IFSC,
+                "depositAccountStatus": "DEPOSIT_ACCOUNT_STATUS_ACTIVE",
+            },
+        }
+    }
+
+    analytics := []obj{}
+    for _, f := range g.funds {
+        value := f.units * f.nav
+        mf += value
+        analytics = append(analytics, obj{
+            "schemeDetail": obj{
+                "amc": f.scheme.AMC, "nameData": obj{"longName": f.scheme.Name}, "planType": "DIRECT",
+                "investmentType": "OPEN", "optionType": "GROWTH", "nav": inr(f.nav), "assetClass": f.scheme.AssetClass,
+                "isinNumber": f.scheme.ISIN, "categoryName": f.scheme.Category, "fundhouseDefinedRiskLevel": f.scheme.Risk,
+            },
+            "enrichedAnalytics": obj{"analytics": obj{"schemeDetails": obj{
+                "currentValue": inr(value), "investedValue": inr(f.invested), "XIRR": xirr(f.flows, g.end, value),
+                "absoluteReturns": inr(value - f.invested), "unrealisedReturns": inr(value - f.invested),
+                "navValue": inr(f.nav), "units": f.units,
+            }}},
+        })
+    }
+
+    if len(g.shares) > 0 {
+        id := g.demat
+        holdings := []obj{}
+        for _, s := range g.shares {
+            equities += float64(s.qty) * s.price
+            holdings = append(holdings, obj{
+                "isin": s.stock.ISIN, "issuerName": s.stock.Issuer, "type": "EQUITY_HOLDING_TYPE_DEMAT",
+                "units": s.qty, "lastTradedPrice": inr(s.price), "isinDescription": s.stock.Issuer,
+            })
+        }
+        accounts[id] = obj{
+            "accountDetails": obj{
+                "fipId": "fip@nsdl", "maskedAccountNumber": "XXXXXX" + id[len(id)-4:], "accInstrumentType": "ACC_INSTRUMENT_TYPE_EQUITIES",
+                "accountType": obj{"equityAccountType": "EQUITY_ACCOUNT_TYPE_DEFAULT_TYPE"},
+                "fipMeta":     obj{"name": "National Securities Depository Limited", "displayName": "NSDL"},
+            },
+            "equitySummary": obj{"accountId": id, "currentValue": inr(equities), "holdingsInfo": holdings},
+        }
+    }
+
+    var assets, liabilities []obj
+    var total float64
+    asset := func(attr string, v float64, present bool) {
+        if present {
+            assets = append(assets, obj{"netWorthAttribute": attr, "value": inr(v)})
+            total += round2(v)
+        }
+    }
+    asset("ASSET_TYPE_MUTUAL_FUND", mf, len(g.funds) > 0)
+    asset("ASSET_TYPE_EPF", g.epfBalance(), g.epf != nil)
+    asset("ASSET_TYPE_INDIAN_SECURITIES", equities, len(g.shares) > 0)
+    asset("ASSET_TYPE_SAVINGS_ACCOUNTS", savings, true)
+
+    owed := map[string]float64{}
+    for _, l := range g.loans {
+        owed[loanKinds[l.kind].Liability] += l.balance
+    }
+    attrs := make([]string, 0, len(owed))
+    for attr := range owed {
+        attrs = append(attrs, attr)
+    }
+    sort.Strings(attrs)
+    for _, attr := range attrs {
+        liabilities = append(liabilities, obj{"netWorthAttribute": attr, "value": inr(owed[attr])})
+        total -= owed[attr]
+    }
+    nw := obj{"assetValues": assets, "totalNetWorthValue": inr(total)}
+    if len(liabilities) > 0 {
+        nw["liabilityValues"] = liabilities
+    }
+    out := obj{
+        "netWorthResponse":           nw,
+        "accountDetailsBulkResponse": obj{"accountDetailsMap": accounts},
+    }
+    if len(analytics) > 0 {
+        out["mfSchemeAnalytics"] = obj{"schemeAnalytics": analytics}
+    }
+    return out
+}
+
+// xirr is the annualised return, in percent, of the purchases in flows
+// valued at value on end, found by bisection.
+func xirr(flows []flow, end time.Time, value float64) float64 {
+    if len(flows) == 0 {
+        return 0
+    }
+    first := flows[0].date
+    years := func(t time.Time) float64 { return t.Sub(first).Hours() / 24 / 365 }
+    npv := func(rate float64) float64 {
+        v := value / math.Pow(1+rate, years(end))
+        for _, f := range flows {
+            v += f.amount / math.Pow(1+rate, years(f.date))
+        }
+        return v
+    }
+    lo, hi := -0.99, 10.0
+    if npv(lo)*npv(hi) > 0 {
+        return 0
+    }
+    for i := 0; i < 100; i++ {
+        mid := (lo + hi) / 2
+        if npv(lo)*npv(mid) <= 0 {
+            hi = mid
+        } else {
+            lo = mid
+        }
+    }
+    return math.Round((lo+hi)/2*10000) / 100
+}
@@ -0,0 +1,494 @@
+package datagen
+
+import (
+    "fmt"
+    "math"
+    "math/rand"
+    "sort"
+    "strconv"
+    "strings"
+    "time"
+)
+
+// gen holds one persona while it is being built. Everything random comes from
+// r, drawn in a fixed order, so a seed always gives the same persona.
+type gen struct {
+    r          *rand.Rand
+    p          profile
+    start, end time.Time
+
+    dob      time.Time
+    income   float64
+    employer string
+    rent     float64
+    accounts []*account
+    loans    []*loan
+    funds    []*fund
+    shares   []*share
+    demat    string // account id of the shares
+    epf      *epfAccount
+    score    float64
+}
+
+type account struct {
+    bank    bank
+    id      string
+    masked  string
+    opening float64
+    balance float64
+    txns    []txn
+    rows    [][]interface{} // newest first, as in the fixtures
+}
+
+type txn struct {
+    date      time.Time
+    amount    float64
+    narration string
+    kind      int // 1 credit, 2 debit, 4 interest, 6 installment
+    mode      string
+    // interest rows are priced off the balance when the ledger is settled
+    interest bool
+}
+
+type loan struct {
+    kind                      string
+    lender                    string
+    balance, original, rate   float64
+    emi                       float64
+    opened                    time.Time
+    tenure                    int
+}
+
+type fund struct {
+    scheme   scheme
+    folio    string
+    sip      float64
+    rows     [][]interface{}
+    units    float64
+    invested float64
+    nav      float64
+    flows    []flow
+}
+
+type flow struct {
+    date   time.Time
+    amount float64 // negative for money put in
+}
+
+type share struct {
+    stock stock
+    price float64
+    qty   int
+    rows  [][]interface{}
+}
+
+type epfEstablishment struct {
+    name, office, memberID string
+    joined, left           time.Time // left is zero for the current employer
+    balance                float64
+}
+
+type epfAccount struct {
+    establishments []epfEstablishment
+    pension        float64
+}
+
+// ————— random helpers —————
+
+func (g *gen) between(r Range) float64 {
+    return r.Min + g.r.Float64()*(r.Max-r.Min)
+}
+
+func (g *gen) count(r Range) int {
+    return int(r.Min) + g.r.Intn(int(r.Max-r.Min)+1)
+}
+
+func (g *gen) rupees(r Range, step float64) float64 {
+    return math.Round(g.between(r)/step) * step
+}
+
+func (g *gen) ref() string {
+    return fmt.Sprintf("%012d", g.r.Int63n(1e12))
+}
+
+func (g *gen) uuid() string {
+    return fmt.Sprintf("%08x-%04x-4%03x-%04x-%012x",
+        g.r.Uint32(), g.r.Intn(1<<16), g.r.Intn(1<<12), 0x8000|g.r.Intn(1<<14), g.r.Int63n(1<<48))
+}
+
+func (g *gen) inWindow(d time.Time) bool {
+    return !d.Before(g.start) && !d.After(g.end)
+}
+
+func monthStart(t time.Time) time.Time {
+    return time.Date(t.Year(), t.Month(), 1, 0, 0, 0, 0, time.UTC)
+}
+
+// dayOf clamps day to the month's length.
+func dayOf(month time.Time, day int) time.Time {
+    last := month.AddDate(0, 1, -1).Day()
+    return time.Date(month.Year(), month.Month(), min(day, last), 0, 0, 0, 0, time.UTC)
+}
+
+func round2(v float64) float64 { return math.Round(v*100) / 100 }
+
+// ————— building a persona —————
+
+func (g *gen) build() {
+    g.dob = g.end.AddDate(-g.count(g.p.Age), -g.r.Intn(12), -g.r.Intn(28))
+    g.income = g.rupees(g.p.Income, 500)
+    g.employer = employers[g.r.Intn(len(employers))]
+
+    order := g.r.Perm(len(banks))
+    g.accounts = append(g.accounts, g.newAccount(banks[order[0]], g.between(g.p.Opening)))
+    second := 0.4
+    if g.p.IncomeKind == "pension" {
+        second = 0.9
+    }
+    if g.r.Float64() < second {
+        g.accounts = append(g.accounts, g.newAccount(banks[order[1]], g.between(g.p.Opening)*g.between(Range{0.3, 1.5})))
+    }
+
+    g.buildLoans()
+    if g.p.Rent.Max > 0 && !g.hasLoan("home") {
+        g.rent = g.rupees(g.p.Rent, 500)
+    }
+    g.buildFunds()
+    g.buildShares()
+    g.buildEPF()
+    g.score = g.rupees(g.p.Score, 1)
+    g.demat = g.uuid()
+    g.buildLedger()
+    for _, acc := range g.accounts {
+        acc.settle()
+    }
+}
+
+func (g *gen) newAccount(b bank, opening float64) *account {
+    return &account{
+        bank:    b,
+        id:      g.uuid(),
+        masked:  fmt.Sprintf("XXXXXX%04d", g.r.Intn(10000)),
+        opening: math.Round(opening),
+    }
+}
+
+func (g *gen) hasLoan(kind string) bool {
+    for _, l := range g.loans {
+        if l.kind == kind {
+            return true
+        }
+    }
+    return false
+}
+
+// buildLoans picks the profile's loans, then shrinks them if the EMIs would
+// take more than they could plausibly afford.
+func (g *gen) buildLoans() {
+    for _, kind := range g.p.Loans {
+        if g.r.Float64() >= g.p.LoanChance {
+            continue
+        }
+        k := loanKinds[kind]
+        l := &loan{
+            kind:    kind,
+            balance: g.rupees(k.Balance, 100),
+            rate:    math.Round(g.between(k.Rate)*100) / 100,
+            opened:  g.start.AddDate(-g.r.Intn(6), -g.r.Intn(12), 0),
+        }
+        switch kind {
+        case "personal":
+            l.lender = personalLenders[g.r.Intn(len(personalLenders))]
+        default:
+            l.lender = g.accounts[0].bank.Name
+        }
+        if k.EMIShare > 0 {
+            l.original = math.Round(l.balance * g.between(Range{1.1, 1.8}))
+            l.emi = math.Round(l.balance * k.EMIShare)
+            l.tenure = int(math.Ceil(l.original / l.emi))
+        } else {
+            // a card's outstanding follows what they earn; original is the limit
+            l.balance = math.Min(l.balance, math.Round(g.income*g.between(Range{0.05, 0.4})))
+            l.original = math.Round(l.balance * g.between(Range{2, 5}) / 1000) * 1000
+        }
+        g.loans = append(g.loans, l)
+    }
+
+    var emis float64
+    for _, l := range g.loans {
+        emis += l.emi
+    }
+    rent := g.p.Rent.Max
+    if g.hasLoan("home") {
+        rent = 0
+    }
+    limit := math.Max(0.45*g.income-rent, 0.1*g.income)
+    if emis > limit {
+        f := limit / emis
+        for _, l := range g.loans {
+            if l.emi > 0 {
+                l.balance, l.original, l.emi = math.Round(l.balance*f), math.Round(l.original*f), math.Round(l.emi*f)
+            }
+        }
+    }
+}
+
+// buildFunds makes monthly SIP purchases, some from before the bank window,
+// on a random walk of the fund's NAV.
+func (g *gen) buildFunds() {
+    n := g.count(g.p.SIPs)
+    picked := g.r.Perm(len(schemes))[:min(n, len(schemes))]
+    var total float64
+    for _, i := range picked {
+        f := &fund{scheme: schemes[i], folio: fmt.Sprintf("%08d", g.r.Intn(1e8)), sip: g.rupees(g.p.SIPAmount, 500)}
+        total += f.sip
+        g.funds = append(g.funds, f)
+    }
+    if limit := 0.25 * g.income; total > limit {
+        for _, f := range g.funds {
+            f.sip = math.Max(500, math.Round(f.sip*limit/total/100)*100)
+        }
+    }
+
+    for _, f := range g.funds {
+        drift, vol := 0.01, 0.045
+        switch f.scheme.AssetClass {
+        case "DEBT":
+            drift, vol = 0.006, 0.008
+        case "HYBRID":
+            drift, vol = 0.008, 0.03
+        }
+        day := 1 + g.r.Intn(28)
+        nav := f.scheme.NAV * g.between(Range{0.7, 1})
+        month := monthStart(g.start).AddDate(0, -g.r.Intn(25), 0)
+        buy := func(d time.Time, amount float64) {
+            units := math.Round(amount/nav*1000) / 1000
+            f.rows = append(f.rows, []interface{}{1, d.Format(time.DateOnly), math.Round(nav*10000) / 10000, units, amount})
+            f.units += units
+            f.invested += amount
+            f.flows = append(f.flows, flow{d, -amount})
+            if g.inWindow(d) {
+                g.accounts[0].txns = append(g.accounts[0].txns, txn{
+                    date: d, amount: amount, kind: 6, mode: "ACH",
+                    narration: fmt.Sprintf("ACH D- %s-%s", f.scheme.House, g.ref()),
+                })
+            }
+        }
+        if g.r.Float64() < 0.3 {
+            buy(dayOf(month, day), g.rupees(Range{5 * f.sip, 20 * f.sip}, 1000))
+        }
+        for ; !month.After(g.end); month = month.AddDate(0, 1, 0) {
+            nav *= math.Exp(drift + vol*g.r.NormFloat64())
+            if d := dayOf(month, day); !d.After(g.end) {
+                buy(d, f.sip)
+            }
+        }
+        f.nav = nav * math.Exp(drift/2+vol/2*g.r.NormFloat64())
+    }
+}
+
+func (g *gen) buildShares() {
+    n := g.count(g.p.Stocks)
+    for _, i := range g.r.Perm(len(stocks))[:min(n, len(stocks))] {
+        s := &share{stock: stocks[i], price: round2(stocks[i].Price * g.between(Range{0.85, 1.15}))}
+        var dates []time.Time
+        for k := 1 + g.r.Intn(3); k > 0; k-- {
+            dates = append(dates, g.end.AddDate(0, 0, -7-g.r.Intn(3*365)))
+        }
+        sort.Slice(dates, func(a, b int) bool { return dates[a].Before(dates[b]) })
+        for _, d := range dates {
+            price := round2(s.price * g.between(Range{0.6, 1.15}))
+            qty := max(1, int(math.Round(g.between(Range{5000, 60000})*g.income/100000/price)))
+            s.rows = append(s.rows, []interface{}{1, d.Format(time.DateOnly), qty, price})
+            s.qty += qty
+        }
+        g.shares = append(g.shares, s)
+    }
+}
+
+func (g *gen) buildEPF() {
+    if g.p.EPF.Max <= 0 || g.p.IncomeKind != "salary" {
+        return
+    }
+    total := g.rupees(g.p.EPF, 1)
+    years := max(1, min(g.end.Year()-g.dob.Year()-22, 15))
+    joined := g.end.AddDate(-1-g.r.Intn(years), -g.r.Intn(12), -g.r.Intn(28))
+    e := &epfAccount{pension: math.Round(total * g.between(Range{0.08, 0.15}))}
+    current := epfEstablishment{name: g.employer, office: epfOffices[g.r.Intn(len(epfOffices))], joined: joined, balance: total}
+    if years > 3 && g.r.Float64() < 0.5 {
+        prev := epfEstablishment{
+            name:   employers[(indexOf(employers, g.employer)+1+g.r.Intn(len(employers)-1))%len(employers)],
+            office: epfOffices[g.r.Intn(len(epfOffices))],
+            joined: joined.AddDate(-1-g.r.Intn(3), -g.r.Intn(12), 0),
+            left:   joined.AddDate(0, 0, -1-g.r.Intn(30)),
+        }
+        prev.balance = math.Round(total * g.between(Range{0.1, 0.3}))
+        current.balance = total - prev.balance
+        e.establishments = append(e.establishments, prev)
+    }
+    e.establishments = append(e.establishments, current)
+    for i := range e.establishments {
+        e.establishments[i].memberID = fmt.Sprintf("MHBAN%017d", g.r.Int63n(1e17))
+    }
+    g.epf = e
+}
+
+func indexOf(list []string, s string) int {
+    for i, v := range list {
+        if v == s {
+            return i
+        }
+    }
+    return -1
+}
+
+// buildLedger adds the income, rent, bills, EMIs, card payments, spending and
+// interest rows; SIP debits were added with the funds.
+func (g *gen) buildLedger() {
+    acc := g.accounts[0]
+    add := func(t txn) {
+        if g.inWindow(t.date) {
+            acc.txns = append(acc.txns, t)
+        }
+    }
+    landlord := landlords[g.r.Intn(len(landlords))]
+    var bills []biller
+    for _, i := range g.r.Perm(len(billers))[:2+g.r.Intn(2)] {
+        bills = append(bills, billers[i])
+    }
+    client := g.r.Perm(len(clients))
+
+    // what goes out every month before any spending, roughly
+    commitments := g.rent
+    for _, b := range bills {
+        commitments += (b.Amount.Min + b.Amount.Max) / 2
+    }
+    for _, l := range g.loans {
+        commitments += l.emi
+        if l.kind == "card" {
+            commitments += 0.8 * l.balance
+        }
+    }
+    for _, f := range g.funds {
+        commitments += f.sip
+    }
+
+    for month := monthStart(g.start); !month.After(g.end); month = month.AddDate(0, 1, 0) {
+        stamp := strings.ToUpper(month.Format("Jan 2006"))
+        switch g.p.IncomeKind {
+        case "salary":
+            add(txn{date: dayOf(month, 1), amount: g.income, kind: 1, mode: "NEFT",
+                narration: fmt.Sprintf("NEFT CR-%s-%s-SALARY %s", acc.bank.IFSC, g.employer, stamp)})
+        case "pension":
+            add(txn{date: dayOf(month, 1), amount: g.income, kind: 1, mode: "OTHERS",
+                narration: fmt.Sprintf("PENSION CREDIT-%s", stamp)})
+        case "freelance":
+            k := 1 + g.r.Intn(4)
+            total := g.income * g.between(Range{0.4, 1.6})
+            for i := 0; i < k; i++ {
+                add(txn{date: dayOf(month, 1+g.r.Intn(28)), amount: math.Round(total / float64(k)), kind: 1, mode: "IMPS",
+                    narration: fmt.Sprintf("IMPS-%s-%s-INVOICE", g.ref(), clients[client[i%len(client)]])})
+            }
+        }
+        if g.rent > 0 {
+            vpa := strings.ReplaceAll(landlord, " ", "") + "@OKSBI"
+            add(txn{date: dayOf(month, 3), amount: g.rent, kind: 2, mode: "OTHERS",
+                narration: fmt.Sprintf("UPI-%s-%s-SBIN0010411-%s-RENT", landlord, vpa, g.ref())})
+        }
+        for i, b := range bills {
+            add(txn{date: dayOf(month, 10+i), amount: g.rupees(b.Amount, 1), kind: 2, mode: "BILLPAY",
+                narration: fmt.Sprintf("BILLPAY-%s-%s", b.Name, g.ref())})
+        }
+        for _, l := range g.loans {
+            if l.kind == "card" {
+                add(txn{date: dayOf(month, 20), amount: g.rupees(Range{0.6 * l.balance, l.balance}, 1), kind: 2, mode: "OTHERS",
+                    narration: fmt.Sprintf("UPI-CRED CLUB-CRED.CLUB@AXISB-UTIB0000114-%s-CREDIT CARD BILL", g.ref())})
+                continue
+            }
+            add(txn{date: dayOf(month, 7), amount: l.emi, kind: 6, mode: "ACH",
+                narration: fmt.Sprintf("ACH D- %s %s-%s", strings.ToUpper(l.lender), loanKinds[l.kind].Label, g.ref())})
+        }
+
+        budget := math.Max((g.income-commitments)*g.between(g.p.SpendShare), 0.15*g.income)
+        g.spend(month, budget, add)
+        for _, a := range g.accounts {
+            if m := month.Month(); m%3 == 0 {
+                if d := dayOf(month, 31); g.inWindow(d) {
+                    a.txns = append(a.txns, txn{date: d, kind: 4, mode: "INTEREST", narration: "CREDIT INTEREST CAPITALISED", interest: true})
+                }
+            }
+        }
+    }
+}
+
+// spend adds a month of UPI payments and the odd cash withdrawal.
+func (g *gen) spend(month time.Time, budget float64, add func(txn)) {
+    weights := 0
+    for _, m := range merchants {
+        weights += m.Weight
+    }
+    for spent, n := 0.0, 0; spent < budget && n < 80; n++ {
+        d := dayOf(month, 1+g.r.Intn(31))
+        if g.r.Intn(12) == 0 {
+            amount := float64(1000 + 500*g.r.Intn(9))
+            add(txn{date: d, amount: amount, kind: 2, mode: "ATM",
+                narration: fmt.Sprintf("CASH WDL-ATM %s-%s", g.accounts[0].bank.Short, g.ref())})
+            spent += amount
+            continue
+        }
+        pick := g.r.Intn(weights)
+        m := merchants[0]
+        for _, c := range merchants {
+            if pick -= c.Weight; pick < 0 {
+                m = c
+                break
+            }
+        }
+        amount := g.rupees(m.Amount, 1)
+        add(txn{date: d, amount: amount, kind: 2, mode: "OTHERS",
+            narration: fmt.Sprintf("UPI-%s-%s-%s-%s-%s", m.Name, m.VPA, m.IFSC, g.ref(), m.Note)})
+        spent += amount
+    }
+}
+
+// settle orders the account's transactions, prices interest, drops spending
+// the balance can't cover and writes the rows with running balances.
+func (acc *account) settle() {
+    sort.SliceStable(acc.txns, func(i, j int) bool {
+        a, b := acc.txns[i], acc.txns[j]
+        if !a.date.Equal(b.date) {
+            return a.date.Before(b.date)
+        }
+        return credit(a.kind) && !credit(b.kind)
+    })
+    acc.balance = acc.opening
+    var rows [][]interface{}
+    for _, t := range acc.txns {
+        if t.interest {
+            t.amount = round2(acc.balance * 0.03 / 4)
+            if t.amount < 1 {
+                continue
+            }
+        }
+        if credit(t.kind) {
+            acc.balance += t.amount
+        } else {
+            if t.kind == 2 && t.amount > acc.balance {
+                continue
+            }
+            acc.balance -= t.amount
+        }
+        acc.balance = round2(acc.balance)
+        rows = append(rows, []interface{}{amountString(t.amount), t.narration, t.date.Format(time.DateOnly), t.kind, t.mode, amountString(acc.balance)})
+    }
+    for i, j := 0, len(rows)-1; i < j; i, j = i+1, j-1 {
+        rows[i], rows[j] = rows[j], rows[i]
+    }
+    acc.rows = rows
+}
+
+func credit(kind int) bool { return kind == 1 || kind == 4 }
+
+func amountString(v float64) string {
+    return strconv.FormatFloat(round2(v), 'f', -1, 64)
+}
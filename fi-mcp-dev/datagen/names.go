@@ -0,0 +1,122 @@
+package datagen
+
+// ————— name pools —————
+// Everything a persona is made of is picked from these lists. Narrations
+// follow the shapes in the hand-written fixtures so merchant grouping and
+// spend categories work on generated data too.
+
+type bank struct {
+    Name, Short, IFSC, FIP string
+}
+
+var banks = []bank{
+    {"HDFC Bank", "HDFC", "HDFC0001234", "HDFC-FIP"},
+    {"ICICI Bank", "ICICI", "ICIC0000456", "ICICI-FIP"},
+    {"State Bank of India", "SBI", "SBIN0010411", "SBI-FIP"},
+    {"Axis Bank", "AXIS", "UTIB0000114", "AXIS-FIP"},
+    {"Kotak Mahindra Bank", "KOTAK", "KKBK0000958", "KOTAK-FIP"},
+}
+
+type merchant struct {
+    Name, VPA, IFSC, Note string
+    Amount                Range
+    Weight                int // relative number of payments
+}
+
+var merchants = []merchant{
+    {"SWIGGY", "SWIGGY@YBL", "YESB0YBLUPI", "FOOD", Range{150, 900}, 6},
+    {"ZOMATO LIMITED", "ZOMATO@HDFCBANK", "HDFC0000499", "FOOD", Range{150, 900}, 5},
+    {"BLINKIT", "BLINKIT.PAYU@HDFCBANK", "HDFC0000499", "GROCERIES", Range{200, 1500}, 5},
+    {"BIGBASKET", "BIGBASKET@ICICI", "ICIC0000104", "GROCERIES", Range{500, 3000}, 3},
+    {"AMAZON PAY", "AMAZON@APL", "UTIB0000100", "SHOPPING", Range{300, 5000}, 3},
+    {"FLIPKART INTERNET", "FKRT@YBL", "YESB0YBLUPI", "SHOPPING", Range{300, 6000}, 2},
+    {"UBER INDIA SYSTEMS P", "UBERRIDES@HDFCBANK", "HDFC0000499", "RIDE", Range{120, 700}, 4},
+    {"ANI TECHNOLOGIES", "OLACABS@YBL", "YESB0YBLUPI", "RIDE", Range{100, 600}, 2},
+    {"APOLLO PHARMACY", "APOLLOPHARMACY@ICICI", "ICIC0000104", "MEDICINES", Range{200, 2000}, 2},
+    {"INDIAN OIL", "IOCL.FUEL@SBI", "SBIN0000691", "FUEL", Range{500, 3000}, 2},
+    {"BOOKMYSHOW", "BOOKMYSHOW@AXISBANK", "UTIB0000114", "MOVIES", Range{300, 1500}, 1},
+    {"DMART AVENUE", "DMART@ICICI", "ICIC0000104", "GROCERIES", Range{800, 4000}, 2},
+}
+
+type biller struct {
+    Name   string
+    Amount Range
+}
+
+var billers = []biller{
+    {"BESCOM", Range{800, 3500}},
+    {"AIRTEL POSTPAID", Range{499, 1199}},
+    {"ACT FIBERNET", Range{700, 1300}},
+    {"MAHANAGAR GAS", Range{400, 1200}},
+}
+
+var employers = []string{
+    "INFOSYS LIMITED", "TATA CONSULTANCY SERVICES", "ZETA FINTECH PVT LTD",
+    "FLIPKART INTERNET PVT LTD", "KARZA TECHNOLOGIES PVT LTD", "WIPRO LIMITED",
+    "RAZORPAY SOFTWARE PVT LTD", "LARSEN AND TOUBRO LTD",
+}
+
+var clients = []string{
+    "BRIGHTLEAF DESIGN STUDIO", "NORTHWIND MEDIA", "KALPA CONSULTING", "ORBIT LABS LLP",
+    "SAFFRON INTERACTIVE", "BLUEPEAK VENTURES",
+}
+
+var landlords = []string{
+    "RAMESH KUMAR", "SUNITA SHARMA", "ANIL MEHTA", "PRIYA NAIR", "VIKRAM RAO",
+}
+
+var epfOffices = []string{
+    "(RO)BANDRA(MUMBAI-I)", "(RO)KORAMANGALA(BENGALURU)", "(RO)GURUGRAM", "(RO)HYDERABAD",
+}
+
+type scheme struct {
+    ISIN, Name, AMC, House, AssetClass, Category, Risk string
+    NAV                                              float64
+}
+
+var schemes = []scheme{
+    {"INF179K01YV8", "HDFC Index Fund - NIFTY 50 Plan", "HDFC", "HDFC MUTUAL FUND", "EQUITY", "INDEX_FUNDS", "VERY_HIGH_RISK", 210.5},
+    {"INF879O01027", "Parag Parikh Flexi Cap Fund", "PPFAS", "PPFAS MUTUAL FUND", "EQUITY", "FLEXI_CAP_FUND", "VERY_HIGH_RISK", 78.2},
+    {"INF204K01K15", "Nippon India Small Cap Fund", "NIPPON_INDIA", "NIPPON INDIA MUTUAL FUND", "EQUITY", "SMALL_CAP_FUND", "VERY_HIGH_RISK", 165.3},
+    {"INF109K016L0", "ICICI Prudential Bluechip Fund", "ICICI_PRUDENTIAL", "ICICI PRUDENTIAL MF", "EQUITY", "LARGE_CAP_FUND", "VERY_HIGH_RISK", 104.8},
+    {"INF846K01EW2", "Axis ELSS Tax Saver Fund", "AXIS", "AXIS MUTUAL FUND", "EQUITY", "ELSS", "VERY_HIGH_RISK", 92.4},
+    {"INF769K01010", "Mirae Asset Large Cap Fund", "MIRAE_ASSET", "MIRAE ASSET MF", "EQUITY", "LARGE_CAP_FUND", "VERY_HIGH_RISK", 112.6},
+    {"INF200K01RJ1", "SBI Equity Hybrid Fund", "SBI", "SBI MUTUAL FUND", "HYBRID", "AGGRESSIVE_HYBRID_FUND", "VERY_HIGH_RISK", 298.1},
+    {"INF174K01LS2", "Kotak Corporate Bond Fund", "KOTAK_MAHINDRA", "KOTAK MUTUAL FUND", "DEBT", "CORPORATE_BOND", "MODERATE_RISK", 3520.4},
+    {"INF760K01FC4", "Canara Robeco Gilt Fund", "CANARA_ROBECO", "CANARA ROBECO MF", "DEBT", "GOVERNMENT_BOND", "MODERATE_RISK", 81.4},
+}
+
+type stock struct {
+    ISIN, Issuer string
+    Price        float64
+}
+
+var stocks = []stock{
+    {"INE002A01018", "RELIANCE INDUSTRIES LIMITED", 1420},
+    {"INE467B01029", "TATA CONSULTANCY SERVICES LIMITED", 3450},
+    {"INE040A01034", "HDFC BANK LIMITED", 1950},
+    {"INE009A01021", "INFOSYS LIMITED", 1580},
+    {"INE090A01021", "ICICI BANK LIMITED", 1410},
+    {"INE062A01020", "STATE BANK OF INDIA", 810},
+    {"INE154A01025", "ITC LIMITED", 415},
+    {"INE030A01027", "HINDUSTAN UNILEVER LIMITED", 2380},
+    {"INE397D01024", "BHARTI AIRTEL LIMITED", 1890},
+    {"INE018A01030", "LARSEN & TOUBRO LIMITED", 3620},
+    {"INE585B01010", "MARUTI SUZUKI INDIA LIMITED", 12500},
+    {"INE075A01022", "WIPRO LIMITED", 265},
+}
+
+// loanKinds maps the profile's loan names to bureau account types and the
+// net worth liability they show up as.
+var loanKinds = map[string]struct {
+    AccountType, Liability, Label string
+    Balance, Rate                  Range
+    EMIShare                       float64 // EMI as a share of the balance; 0 for revolving
+}{
+    "home":     {"02", "LIABILITY_TYPE_HOME_LOAN", "HOME LOAN", Range{1500000, 6000000}, Range{8.3, 9.6}, 0.009},
+    "vehicle":  {"01", "LIABILITY_TYPE_VEHICLE_LOAN", "AUTO LOAN", Range{200000, 900000}, Range{8.8, 11}, 0.025},
+    "personal": {"05", "LIABILITY_TYPE_OTHER_LOAN", "PERSONAL LOAN", Range{50000, 400000}, Range{11, 16}, 0.035},
+    "card":     {"10", "LIABILITY_TYPE_CREDIT_CARD", "CREDIT CARD", Range{3000, 80000}, Range{36, 42}, 0},
+}
+
+var personalLenders = []string{"Bajaj Finance", "Tata Capital", "Aditya Birla Finance"}
@@ -0,0 +1,145 @@
+package main
+
+import (
+    "net/http"
+    "sort"
+    "strconv"
+    "strings"
+    "time"
+)
+
+// ————— net worth composition drift —————
+// /api/net_worth/drift?days=&period=&seed= gives each asset type's share of
+// total assets at every period over the window, for a stacked-area chart.
+// Fixtures are a single snapshot, so the history is walked back from it one
+// day at a time. Mutual funds, stocks and EPF move by their projectionGroups
+// rate plus a seeded daily wobble; every other asset is held flat. A phone
+// with a simulation.yaml walks its mutual funds and stocks with the
+// config's market drift, volatility and seed instead. The same seed and
+// window always give the same shares.
+
+const maxDriftDays = 366
+
+// driftVolatility is the daily wobble of each projection group.
+var driftVolatility = map[string]float64{
+    "mutualFunds": 0.008,
+    "stocks":      0.012,
+}
+
+var driftSteps = map[string]func(time.Time) time.Time{
+    "day":   func(t time.Time) time.Time { return t.AddDate(0, 0, -1) },
+    "week":  func(t time.Time) time.Time { return t.AddDate(0, 0, -7) },
+    "month": func(t time.Time) time.Time { return t.AddDate(0, -1, 0) },
+}
+
+type driftPoint struct {
+    Date   string             `json:"date"`
+    Total  float64            `json:"total"`
+    Shares map[string]float64 `json:"shares"` // asset type → percent of total
+}
+
+func netWorthDriftHandler(w http.ResponseWriter, r *http.Request) {
+    phone := r.Context().Value("phone").(string)
+    q := r.URL.Query()
+    days, err := intParam(q.Get("days"), 90, 1, maxDriftDays)
+    if err != nil {
+        http.Error(w, "days must be an integer from 1 to 366", http.StatusBadRequest)
+        return
+    }
+    period := q.Get("period")
+    if period == "" {
+        period = "week"
+    }
+    step, ok := driftSteps[period]
+    if !ok {
+        http.Error(w, "period must be day, week or month", http.StatusBadRequest)
+        return
+    }
+
+    // walk is the daily drift and volatility per projection group
+    walk := map[string]simWalk{}
+    for _, g := range projectionGroups {
+        walk[g.Name] = simWalk{Drift: g.Rate / 365, Volatility: driftVolatility[g.Name]}
+    }
+    var seed int64 = 1
+    end := time.Now().UTC()
+    if cfg := simulationFor(r.Context(), phone); cfg != nil {
+        walk["mutualFunds"], walk["stocks"], seed = cfg.Market.MF, cfg.Market.Stock, cfg.Seed
+        end = cfg.run(time.Now()).now.UTC()
+    }
+    if s := q.Get("seed"); s != "" {
+        if seed, err = strconv.ParseInt(s, 10, 64); err != nil {
+            http.Error(w, "seed must be an integer", http.StatusBadRequest)
+            return
+        }
+    }
+
+    nw, err := loadNetWorth(r.Context(), phone)
+    if err != nil {
+        http.Error(w, "data not found", http.StatusInternalServerError)
+        return
+    }
+    group := map[string]string{} // asset type → projection group
+    for _, g := range projectionGroups {
+        for _, a := range g.Attributes {
+            group[a] = g.Name
+        }
+    }
+    today := map[string]float64{}
+    for _, a := range nw.NetWorthResponse.AssetValues {
+        if v := a.Value.Float(); strings.HasPrefix(a.NetWorthAttribute, "ASSET_TYPE_") && v > 0 {
+            today[a.NetWorthAttribute] += v
+        }
+    }
+    types := make([]string, 0, len(today))
+    for t := range today {
+        types = append(types, t)
+    }
+    sort.Strings(types)
+
+    // values[d] are the asset values d days before end
+    values := make([]map[string]float64, days+1)
+    values[0] = today
+    for d := 1; d <= days; d++ {
+        values[d] = make(map[string]float64, len(types))
+        for _, t := range types {
+            v := values[d-1][t]
+            if g, ok := group[t]; ok {
+                wk := walk[g]
+                v /= 1 + wk.Drift + wk.Volatility*hashUnit(seed, t, d)
+            }
+            values[d][t] = v
+        }
+    }
+
+    end = time.Date(end.Year(), end.Month(), end.Day(), 0, 0, 0, 0, time.UTC)
+    start := end.AddDate(0, 0, -days)
+    points := []driftPoint{}
+    for t := end; !t.Before(start); t = step(t) {
+        v := values[int(end.Sub(t).Hours()/24)]
+        p := driftPoint{Date: t.Format(time.DateOnly), Shares: make(map[string]float64, len(types))}
+        for _, typ := range types {
+            p.Total += v[typ]
+        }
+        for _, typ := range types {
+            if p.Total > 0 {
+                p.Shares[typ] = round2(v[typ] / p.Total * 100)
+            }
+        }
+        p.Total = round2(p.Total)
+        points = append(points, p)
+    }
+    // oldest first, as a chart reads it
+    for i, j := 0, len(points)-1; i < j; i, j = i+1, j-1 {
+        points[i], points[j] = points[j], points[i]
+    }
+
+    writeJSON(w, map[string]interface{}{
+        "from":       start.Format(time.DateOnly),
+        "to":         end.Format(time.DateOnly),
+        "period":     period,
+        "seed":       seed,
+        "assetTypes": types,
+        "points":     points,
+    })
+}
@@ -0,0 +1,154 @@
+package main
+
+import (
+    "encoding/xml"
+    "fmt"
+    "net/http"
+    "strings"
+    "time"
+)
+
+// ————— OFX export —————
+// A minimal OFX 2.1.1 (XML) bank statement per account, enough for
+// personal-finance apps to import the bank fixture.
+
+type ofxDoc struct {
+    XMLName xml.Name    `xml:"OFX"`
+    SignOn  ofxSignOn   `xml:"SIGNONMSGSRSV1>SONRS"`
+    Stmts   []ofxStmtRs `xml:"BANKMSGSRSV1>STMTTRNRS"`
+}
+
+type ofxStatus struct {
+    Code     int    `xml:"CODE"`
+    Severity string `xml:"SEVERITY"`
+}
+
+type ofxSignOn struct {
+    Status   ofxStatus `xml:"STATUS"`
+    DTServer string    `xml:"DTSERVER"`
+    Language string    `xml:"LANGUAGE"`
+}
+
+type ofxStmtRs struct {
+    TrnUID string    `xml:"TRNUID"`
+    Status ofxStatus `xml:"STATUS"`
+    Stmt   struct {
+        CurDef  string `xml:"CURDEF"`
+        Account struct {
+            BankID   string `xml:"BANKID"`
+            AcctID   string `xml:"ACCTID"`
+            AcctType string `xml:"ACCTTYPE"`
+        } `xml:"BANKACCTFROM"`
+        TranList struct {
+            DTStart string   `xml:"DTSTART"`
+            DTEnd   string   `xml:"DTEND"`
+            Txns    []ofxTxn `xml:"STMTTRN"`
+        } `xml:"BANKTRANLIST"`
+        LedgerBal struct {
+            BalAmt string `xml:"BALAMT"`
+            DTAsOf string `xml:"DTASOF"`
+        } `xml:"LEDGERBAL"`
+    } `xml:"STMTRS"`
+}
+
+type ofxTxn struct {
+    TrnType  string `xml:"TRNTYPE"`
+    DTPosted string `xml:"DTPOSTED"`
+    TrnAmt   string `xml:"TRNAMT"`
+    FITID    string `xml:"FITID"`
+    Name     string `xml:"NAME"`
+    Memo     string `xml:"MEMO"`
+}
+
+// ofxTrnTypes maps fixture transaction types to OFX TRNTYPE; opening and
+// closing rows are balance markers, not transactions, and are left out.
+var ofxTrnTypes = map[int]string{
+    bankTxnCredit:      "CREDIT",
+    bankTxnDebit:       "DEBIT",
+    bankTxnInterest:    "INT",
+    bankTxnTDS:         "FEE",
+    bankTxnInstallment: "REPEATPMT",
+    bankTxnOthers:      "OTHER",
+}
+
+func ofxExportHandler(w http.ResponseWriter, r *http.Request) {
+    phone := r.Context().Value("phone").(string)
+    bt, err := loadBankTransactions(r.Context(), phone)
+    if err != nil {
+        http.Error(w, "data not found", http.StatusInternalServerError)
+        return
+    }
+    doc := buildOFX(bt, time.Now())
+    w.Header().Set("Content-Type", "application/x-ofx")
+    w.Header().Set("Content-Disposition", fmt.Sprintf(`attachment; filename="%s.ofx"`, phone))
+    fmt.Fprint(w, xml.Header)
+    fmt.Fprint(w, `<?OFX OFXHEADER="200" VERSION="211" SECURITY="NONE" OLDFILEUID="NONE" NEWFILEUID="NONE"?>`+"\n")
+    enc := xml.NewEncoder(w)
+    enc.Indent("", "  ")
+    enc.Encode(doc)
+}
+
+func buildOFX(bt *bankTransactionsFile, now time.Time) ofxDoc {
+    doc := ofxDoc{SignOn: ofxSignOn{
+        Status:   ofxStatus{Severity: "INFO"},
+        DTServer: now.UTC().Format("20060102150405"),
+        Language: "ENG",
+    }}
+    ids := bankAccountIDs(bt)
+    for i, acc := range bt.BankTransactions {
+        var rs ofxStmtRs
+        rs.TrnUID = ids[i]
+        rs.Status.Severity = "INFO"
+        rs.Stmt.CurDef = "INR"
+        rs.Stmt.Account.BankID = acc.Bank
+        rs.Stmt.Account.AcctID = ids[i]
+        rs.Stmt.Account.AcctType = "SAVINGS"
+
+        var start, end string
+        var latest *bankTxn
+        for n := range acc.Txns {
+            t := &acc.Txns[n]
+            trnType, ok := ofxTrnTypes[t.Type]
+            if !ok {
+                continue
+            }
+            date := strings.ReplaceAll(t.Date, "-", "")
+            if start == "" || date < start {
+                start = date
+            }
+            if date > end {
+                end = date
+            }
+            if latest == nil || t.Date > latest.Date {
+                latest = t
+            }
+            amt := t.Amount
+            if t.Type != bankTxnCredit && t.Type != bankTxnInterest {
+                amt = -amt
+            }
+            rs.Stmt.TranList.Txns = append(rs.Stmt.TranList.Txns, ofxTxn{
+                TrnType:  trnType,
+                DTPosted: date,
+                TrnAmt:   fmt.Sprintf("%.2f", amt),
+                FITID:    fmt.Sprintf("%s-%d", ids[i], n),
+                Name:     truncate(t.Narration, 32), // OFX caps NAME at 32 chars
+                Memo:     t.Narration,
+            })
+        }
+        rs.Stmt.TranList.DTStart, rs.Stmt.TranList.DTEnd = start, end
+        if latest != nil {
+            rs.Stmt.LedgerBal.BalAmt = fmt.Sprintf("%.2f", latest.Balance)
+            rs.Stmt.LedgerBal.DTAsOf = strings.ReplaceAll(latest.Date, "-", "")
+        }
+        doc.Stmts = append(doc.Stmts, rs)
+    }
+    return doc
+}
+
+func truncate(s string, n int) string {
+    r := []rune(s)
+    if len(r) <= n {
+        return s
+    }
+    return string(r[:n])
+}
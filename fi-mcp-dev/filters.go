@@ -0,0 +1,321 @@
+package main
+
+import (
+    "encoding/json"
+    "fmt"
+    "net/http"
+    "net/url"
+    "sort"
+    "strconv"
+    "strings"
+    "time"
+)
+
+// ————— stream filters —————
+// A streamFilter turns a stream's query params into a payload rewrite, or
+// rejects them. A nil rewrite means nothing to filter.
+type streamFilter func(q url.Values) (func([]byte) ([]byte, error), error)
+
+// minAmountFilter keeps only rows of listKey[].txns whose amountIdx column
+// is at least ?minAmount=. Everything else in the fixture passes through as
+// is.
+func minAmountFilter(listKey string, amountIdx int) streamFilter {
+    return func(q url.Values) (func([]byte) ([]byte, error), error) {
+        s := q.Get("minAmount")
+        if s == "" {
+            return nil, nil
+        }
+        min, err := strconv.ParseFloat(s, 64)
+        if err != nil {
+            return nil, err
+        }
+        return func(data []byte) ([]byte, error) {
+            return filterTxnRows(data, listKey, amountIdx, min)
+        }, nil
+    }
+}
+
+func filterTxnRows(data []byte, listKey string, amountIdx int, min float64) ([]byte, error) {
+    var doc map[string]json.RawMessage
+    if err := json.Unmarshal(data, &doc); err != nil {
+        return nil, err
+    }
+    var groups []map[string]json.RawMessage
+    if err := json.Unmarshal(doc[listKey], &groups); err != nil {
+        return nil, err
+    }
+    for _, g := range groups {
+        var rows []json.RawMessage
+        if err := json.Unmarshal(g["txns"], &rows); err != nil {
+            continue // no txns in this group
+        }
+        kept := []json.RawMessage{}
+        for _, raw := range rows {
+            var row []interface{}
+            if json.Unmarshal(raw, &row) == nil && len(row) > amountIdx && toFloat(row[amountIdx]) >= min {
+                kept = append(kept, raw)
+            }
+        }
+        g["txns"], _ = json.Marshal(kept)
+    }
+    doc[listKey], _ = json.Marshal(groups)
+    return json.Marshal(doc)
+}
+
+// ————— transaction paging —————
+// /api/<txn type> honours ?from=&to= (RFC3339 or YYYY-MM-DD, both inclusive),
+// ?type= (a code or name, comma-separated), ?sort= (date, -date, amount,
+// -amount) and ?limit=&offset= over the rows of every group. Rows are taken in
+// fixture order (or sorted), paged, then put back under their groups; groups
+// left with no rows are dropped. Groups are listed in order of their first
+// row on the page, so with ?sort= each group's rows are in order but reading
+// the groups one after another is not: the sort holds across groups only in
+// the flattened CSV/NDJSON output (see withTxnFormat). The page keeps the
+// fixture's shape and adds "total", the number of matching rows, and
+// "next_offset", null on the last page. Without any of these params the
+// response is untouched.
+
+var txnPageParams = []string{"from", "to", "type", "sort", "limit", "offset"}
+
+// txnRows describes one fixture's row layout. AmountIdx is -1 when rows have
+// no amount to sort by. GroupCols are the group fields repeated on every
+// flattened row and Cols name the row's columns, both in CSV/NDJSON order.
+type txnRows struct {
+    ListKey   string
+    DateIdx   int
+    TypeIdx   int
+    AmountIdx int
+    TypeNames map[int]string
+    GroupCols []string
+    Cols      []string
+}
+
+var (
+    mfTxnRows = txnRows{ListKey: "mfTransactions", DateIdx: 1, TypeIdx: 0, AmountIdx: 4,
+        TypeNames: map[int]string{1: "BUY", 2: "SELL"},
+        GroupCols: []string{"isin", "schemeName", "folioId"},
+        Cols:      []string{"orderType", "transactionDate", "purchasePrice", "purchaseUnits", "transactionAmount"}}
+    bankTxnRows = txnRows{ListKey: "bankTransactions", DateIdx: 2, TypeIdx: 3, AmountIdx: 0,
+        TypeNames: bankTxnTypeNames,
+        GroupCols: []string{"bank"},
+        Cols:      []string{"transactionAmount", "transactionNarration", "transactionDate", "transactionType", "transactionMode", "currentBalance"}}
+    stockTxnRows = txnRows{ListKey: "stockTransactions", DateIdx: 1, TypeIdx: 0, AmountIdx: -1,
+        TypeNames: map[int]string{1: "BUY", 2: "SELL", 3: "BONUS", 4: "SPLIT"},
+        GroupCols: []string{"isin"},
+        Cols:      []string{"transactionType", "transactionDate", "quantity", "navValue"}}
+)
+
+type txnPage struct {
+    from, to      time.Time    // zero when open
+    types         map[int]bool // nil when open
+    sortBy        string       // "", "date" or "amount"
+    desc          bool
+    limit, offset int // limit -1 when open
+}
+
+func parseTxnPage(q url.Values, rows txnRows) (*txnPage, error) {
+    open := true
+    for _, k := range txnPageParams {
+        if q.Get(k) != "" {
+            open = false
+        }
+    }
+    if open {
+        return nil, nil
+    }
+    p := &txnPage{limit: -1}
+    var err error
+    if s := q.Get("from"); s != "" {
+        if p.from, _, err = parseTxnDate(s); err != nil {
+            return nil, fmt.Errorf("invalid from %q: use RFC3339 or YYYY-MM-DD", s)
+        }
+    }
+    if s := q.Get("to"); s != "" {
+        var dateOnly bool
+        if p.to, dateOnly, err = parseTxnDate(s); err != nil {
+            return nil, fmt.Errorf("invalid to %q: use RFC3339 or YYYY-MM-DD", s)
+        }
+        if dateOnly {
+            p.to = p.to.Add(24*time.Hour - time.Nanosecond) // the whole day
+        }
+    }
+    if s := q.Get("type"); s != "" {
+        p.types = map[int]bool{}
+        for _, t := range strings.Split(s, ",") {
+            code, ok := txnTypeCode(strings.TrimSpace(t), rows.TypeNames)
+            if !ok {
+                return nil, fmt.Errorf("invalid type %q", t)
+            }
+            p.types[code] = true
+        }
+    }
+    if s := q.Get("sort"); s != "" {
+        p.sortBy, p.desc = strings.TrimPrefix(s, "-"), strings.HasPrefix(s, "-")
+        switch {
+        case p.sortBy == "date":
+        case p.sortBy == "amount" && rows.AmountIdx >= 0:
+        default:
+            return nil, fmt.Errorf("invalid sort %q", s)
+        }
+    }
+    if s := q.Get("limit"); s != "" {
+        // at least 1: an empty page would hand back next_offset == offset
+        if p.limit, err = strconv.Atoi(s); err != nil || p.limit < 1 {
+            return nil, fmt.Errorf("invalid limit %q", s)
+        }
+    }
+    if s := q.Get("offset"); s != "" {
+        if p.offset, err = strconv.Atoi(s); err != nil || p.offset < 0 {
+            return nil, fmt.Errorf("invalid offset %q", s)
+        }
+    }
+    return p, nil
+}
+
+// txnTypeCode accepts a type's code ("2") or its name ("debit").
+func txnTypeCode(s string, names map[int]string) (int, bool) {
+    if code, err := strconv.Atoi(s); err == nil {
+        _, ok := names[code]
+        return code, ok
+    }
+    for code, name := range names {
+        if strings.EqualFold(name, s) {
+            return code, true
+        }
+    }
+    return 0, false
+}
+
+func parseTxnDate(s string) (t time.Time, dateOnly bool, err error) {
+    if t, err = time.Parse("2006-01-02", s); err == nil {
+        return t, true, nil
+    }
+    t, err = time.Parse(time.RFC3339, s)
+    return t, false, err
+}
+
+func (p *txnPage) matches(row []interface{}, rows txnRows) bool {
+    if p.types != nil && (len(row) <= rows.TypeIdx || !p.types[int(toFloat(row[rows.TypeIdx]))]) {
+        return false
+    }
+    if p.from.IsZero() && p.to.IsZero() {
+        return true
+    }
+    s, _ := row[rows.DateIdx].(string)
+    d, err := time.Parse("2006-01-02", s)
+    if err != nil {
+        return false
+    }
+    return !d.Before(p.from) && (p.to.IsZero() || !d.After(p.to))
+}
+
+func pageTxnRows(data []byte, rows txnRows, p *txnPage) ([]byte, error) {
+    var doc map[string]json.RawMessage
+    if err := json.Unmarshal(data, &doc); err != nil {
+        return nil, err
+    }
+    var groups []map[string]json.RawMessage
+    if raw, ok := doc[rows.ListKey]; ok { // fixtures like {} have no rows at all
+        if err := json.Unmarshal(raw, &groups); err != nil {
+            return nil, err
+        }
+    }
+
+    type match struct {
+        group int
+        raw   json.RawMessage
+        date  string
+        amt   float64
+    }
+    var matched []match
+    for gi, g := range groups {
+        var txns []json.RawMessage
+        if err := json.Unmarshal(g["txns"], &txns); err != nil {
+            continue // no txns in this group
+        }
+        for _, raw := range txns {
+            var row []interface{}
+            if json.Unmarshal(raw, &row) != nil || len(row) <= rows.DateIdx || !p.matches(row, rows) {
+                continue
+            }
+            m := match{group: gi, raw: raw}
+            m.date, _ = row[rows.DateIdx].(string)
+            if rows.AmountIdx >= 0 && len(row) > rows.AmountIdx {
+                m.amt = toFloat(row[rows.AmountIdx])
+            }
+            matched = append(matched, m)
+        }
+    }
+    if p.sortBy != "" {
+        sort.SliceStable(matched, func(i, j int) bool {
+            a, b := matched[i], matched[j]
+            if p.desc {
+                a, b = b, a
+            }
+            if p.sortBy == "amount" {
+                return a.amt < b.amt
+            }
+            return a.date < b.date
+        })
+    }
+
+    total := len(matched)
+    page := matched[min(p.offset, total):]
+    if p.limit >= 0 && len(page) > p.limit {
+        page = page[:p.limit]
+    }
+    // regroup, groups in order of their first row on the page
+    var order []int
+    byGroup := map[int][]json.RawMessage{}
+    for _, m := range page {
+        if _, seen := byGroup[m.group]; !seen {
+            order = append(order, m.group)
+        }
+        byGroup[m.group] = append(byGroup[m.group], m.raw)
+    }
+    kept := []map[string]json.RawMessage{}
+    for _, gi := range order {
+        g := groups[gi]
+        g["txns"], _ = json.Marshal(byGroup[gi])
+        kept = append(kept, g)
+    }
+
+    doc[rows.ListKey], _ = json.Marshal(kept)
+    doc["total"], _ = json.Marshal(total)
+    doc["next_offset"] = json.RawMessage("null")
+    if end := min(p.offset, total) + len(page); end < total {
+        doc["next_offset"], _ = json.Marshal(end)
+    }
+    return json.Marshal(doc)
+}
+
+// withTxnPage pages whatever next writes, so it composes with handlers that
+// already filter (bank_transactions?accountId=).
+func withTxnPage(rows txnRows, next http.Handler) http.Handler {
+    return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+        p, err := parseTxnPage(r.URL.Query(), rows)
+        if err != nil {
+            http.Error(w, err.Error(), http.StatusBadRequest)
+            return
+        }
+        if p == nil {
+            next.ServeHTTP(w, r)
+            return
+        }
+        rec := &recorder{header: w.Header().Clone(), status: http.StatusOK}
+        next.ServeHTTP(rec, r)
+        body := rec.body.Bytes()
+        if rec.status == http.StatusOK {
+            if body, err = pageTxnRows(body, rows, p); err != nil {
+                http.Error(w, "transactions unavailable", http.StatusInternalServerError)
+                return
+            }
+            rec.header.Del("Content-Length")
+        }
+        for k, v := range rec.header {
+            w.Header()[k] = v
+        }
+        w.WriteHeader(rec.status)
+        w.Write(body)
+    })
+}
@@ -0,0 +1,137 @@
+package main
+
+import (
+    "io/fs"
+    "log"
+    "os"
+    "path/filepath"
+    "strings"
+    "sync"
+    "time"
+)
+
+// ————— fixture cache —————
+// Fixture bytes are kept in memory by path and served again while the
+// file's mtime and size are unchanged, so a tick costs a stat instead of a
+// read. Admin writes drop the phone's entries as well, in case a rewrite
+// keeps both. With FI_MCP_PRELOAD, main preloads every fixture at startup.
+
+type cachedFixture struct {
+    data []byte
+    mod  time.Time
+    size int64
+}
+
+var fixtureCache = struct {
+    sync.Mutex
+    byPath       map[string]cachedFixture
+    hits, misses uint64
+}{byPath: map[string]cachedFixture{}}
+
+// cachedReadFile is os.ReadFile through the cache. The slice is shared and
+// must not be modified.
+func cachedReadFile(path string) ([]byte, error) {
+    fi, err := os.Stat(path)
+    if err != nil {
+        fixtureCache.Lock()
+        delete(fixtureCache.byPath, path)
+        fixtureCache.Unlock()
+        return nil, err
+    }
+    fixtureCache.Lock()
+    e, ok := fixtureCache.byPath[path]
+    if ok && e.mod.Equal(fi.ModTime()) && e.size == fi.Size() {
+        fixtureCache.hits++
+        fixtureCache.Unlock()
+        return e.data, nil
+    }
+    fixtureCache.misses++
+    fixtureCache.Unlock()
+
+    // stamped with the stat from before the read, so a write in between is
+    // picked up on the next call
+    data, err := os.ReadFile(path)
+    if err != nil {
+        return nil, err
+    }
+    fixtureCache.Lock()
+    fixtureCache.byPath[path] = cachedFixture{data: data, mod: fi.ModTime(), size: fi.Size()}
+    fixtureCache.Unlock()
+    return data, nil
+}
+
+// forgetFixtures drops the cached fixtures of one phone's data directory.
+func forgetFixtures(phone string) {
+    prefix := dataFilePath(phone, "")
+    fixtureCache.Lock()
+    defer fixtureCache.Unlock()
+    for path := range fixtureCache.byPath {
+        if strings.HasPrefix(path, prefix) {
+            delete(fixtureCache.byPath, path)
+        }
+    }
+}
+
+// preloadFixtures reads every phone's data fixtures into the cache.
+func preloadFixtures() {
+    start := time.Now()
+    n := 0
+    for _, phone := range allowedMobileNumbers() {
+        for _, e := range dataEndpoints {
+            if _, err := cachedReadFile(dataFilePath(phone, e.File)); err == nil {
+                n++
+            }
+        }
+    }
+    log.Printf("preloaded %d fixture(s) in %s", n, time.Since(start).Round(time.Millisecond))
+}
+
+// fixtureCacheStats is the cache's part of /stats.json.
+func fixtureCacheStats() map[string]interface{} {
+    fixtureCache.Lock()
+    defer fixtureCache.Unlock()
+    return map[string]interface{}{
+        "entries": len(fixtureCache.byPath),
+        "hits":    fixtureCache.hits,
+        "misses":  fixtureCache.misses,
+    }
+}
+
+// ————— bundled fixtures —————
+// Built with -tags embedfixtures, the binary carries test_data_dir and
+// static (see bundle.go) and writes them out at startup wherever they are
+// missing, so it runs from any directory. Files already on disk are left
+// alone.
+
+// bundledFiles is nil unless the binary was built with the bundle.
+var bundledFiles fs.FS
+
+func unpackBundled() {
+    if bundledFiles == nil {
+        return
+    }
+    for _, root := range []string{dataDir, "static"} {
+        if _, err := os.Stat(root); err == nil {
+            continue
+        }
+        n := 0
+        err := fs.WalkDir(bundledFiles, root, func(path string, d fs.DirEntry, err error) error {
+            if err != nil {
+                return err
+            }
+            if d.IsDir() {
+                return os.MkdirAll(path, 0o755)
+            }
+            data, err := fs.ReadFile(bundledFiles, path)
+            if err != nil {
+                return err
+            }
+            n++
+            return os.WriteFile(filepath.FromSlash(path), data, 0o644)
+        })
+        if err != nil {
+            log.Fatalf("unpack bundled %s: %v", root, err)
+        }
+        log.Printf("unpacked %d bundled file(s) into %s", n, root)
+    }
+}
@@ -0,0 +1,416 @@
+package main
+
+import (
+    "context"
+    "encoding/json"
+    "errors"
+    "math"
+    "strconv"
+    "strings"
+    "time"
+)
+
+// ————— fixture shapes —————
+// Only the fields the computed endpoints need are modelled here; the raw
+// endpoints keep serving the files untouched.
+
+// money mirrors the {currencyCode, units, nanos} value used across fixtures.
+type money struct {
+    CurrencyCode string `json:"currencyCode"`
+    Units        string `json:"units"`
+    Nanos        int64  `json:"nanos"`
+}
+
+func (m money) Float() float64 {
+    units, _ := strconv.ParseFloat(m.Units, 64)
+    return units + float64(m.Nanos)/1e9
+}
+
+type netWorthAttribute struct {
+    NetWorthAttribute string `json:"netWorthAttribute"`
+    Value             money  `json:"value"`
+}
+
+type netWorthResponse struct {
+    AssetValues        []netWorthAttribute `json:"assetValues"`
+    LiabilityValues    []netWorthAttribute `json:"liabilityValues"`
+    TotalNetWorthValue money               `json:"totalNetWorthValue"`
+}
+
+type netWorthFile struct {
+    NetWorthResponse *netWorthResponse `json:"netWorthResponse"`
+    MfSchemeAnalytics struct {
+        SchemeAnalytics []mfSchemeAnalytic `json:"schemeAnalytics"`
+    } `json:"mfSchemeAnalytics"`
+    AccountDetailsBulkResponse struct {
+        AccountDetailsMap map[string]accountDetailsEntry `json:"accountDetailsMap"`
+    } `json:"accountDetailsBulkResponse"`
+}
+
+type mfSchemeAnalytic struct {
+    SchemeDetail struct {
+        NameData struct {
+            LongName string `json:"longName"`
+        } `json:"nameData"`
+        AssetClass string `json:"assetClass"`
+    } `json:"schemeDetail"`
+    EnrichedAnalytics struct {
+        Analytics struct {
+            SchemeDetails struct {
+                CurrentValue  money `json:"currentValue"`
+                InvestedValue money `json:"investedValue"`
+            } `json:"schemeDetails"`
+        } `json:"analytics"`
+    } `json:"enrichedAnalytics"`
+}
+
+// accountDetailsEntry is one linked account. Only the summaries the derived
+// endpoints use are modelled; each entry carries at most one of them.
+type accountDetailsEntry struct {
+    AccountDetails struct {
+        MaskedAccountNumber string `json:"maskedAccountNumber"`
+        AccInstrumentType   string `json:"accInstrumentType"`
+        FipMeta             struct {
+            Name string `json:"name"`
+        } `json:"fipMeta"`
+    } `json:"accountDetails"`
+    EquitySummary *struct {
+        CurrentValue money `json:"currentValue"`
+        HoldingsInfo []struct {
+            Isin            string  `json:"isin"`
+            IssuerName      string  `json:"issuerName"`
+            Units           float64 `json:"units"`
+            LastTradedPrice money   `json:"lastTradedPrice"`
+        } `json:"holdingsInfo"`
+    } `json:"equitySummary"`
+    DepositSummary *struct {
+        CurrentBalance money `json:"currentBalance"`
+    } `json:"depositSummary"`
+}
+
+func loadNetWorth(ctx context.Context, phone string) (*netWorthFile, error) {
+    data, err := readDataFile(ctx, phone, "fetch_net_worth.json")
+    if err != nil {
+        return nil, err
+    }
+    var nw netWorthFile
+    if err := json.Unmarshal(data, &nw); err != nil {
+        return nil, err
+    }
+    // a fixture of the wrong shape would otherwise decode as a zero net worth
+    if nw.NetWorthResponse == nil {
+        return nil, errors.New("fetch_net_worth.json has no netWorthResponse")
+    }
+    return &nw, nil
+}
+
+// bankTxn is one row of a bankTransactions "txns" array:
+// [amount, narration, date, type, mode, balance]. The original row is kept so
+// filtered responses re-encode exactly what was on disk.
+type bankTxn struct {
+    Amount    float64
+    Narration string
+    Date      string
+    Type      int
+    Mode      string
+    Balance   float64
+    raw       json.RawMessage
+}
+
+func (t *bankTxn) UnmarshalJSON(b []byte) error {
+    var row []interface{}
+    if err := json.Unmarshal(b, &row); err != nil {
+        return err
+    }
+    for len(row) < 6 {
+        row = append(row, nil)
+    }
+    t.Amount = toFloat(row[0])
+    t.Narration, _ = row[1].(string)
+    t.Date, _ = row[2].(string)
+    t.Type = int(toFloat(row[3]))
+    t.Mode, _ = row[4].(string)
+    t.Balance = toFloat(row[5])
+    t.raw = append(json.RawMessage(nil), b...)
+    return nil
+}
+
+func (t bankTxn) MarshalJSON() ([]byte, error) {
+    return t.raw, nil
+}
+
+type bankAccountTxns struct {
+    Bank string    `json:"bank"`
+    Txns []bankTxn `json:"txns"`
+}
+
+type bankTransactionsFile struct {
+    SchemaDescription string            `json:"schemaDescription,omitempty"`
+    BankTransactions  []bankAccountTxns `json:"bankTransactions"`
+}
+
+func loadBankTransactions(ctx context.Context, phone string) (*bankTransactionsFile, error) {
+    data, err := readDataFile(ctx, phone, "fetch_bank_transactions.json")
+    if err != nil {
+        return nil, err
+    }
+    var bt bankTransactionsFile
+    if err := json.Unmarshal(data, &bt); err != nil {
+        return nil, err
+    }
+    return &bt, nil
+}
+
+// round2 rounds to two decimals for response values.
+func round2(f float64) float64 {
+    r := math.Round(f*100) / 100
+    if r == 0 {
+        return 0 // avoid "-0" in responses
+    }
+    return r
+}
+
+// toFloat accepts the numeric-or-string values the fixtures mix freely.
+func toFloat(v interface{}) float64 {
+    switch n := v.(type) {
+    case float64:
+        return n
+    case string:
+        f, _ := strconv.ParseFloat(n, 64)
+        return f
+    }
+    return 0
+}
+
+type epfDetailsFile struct {
+    UanAccounts []struct {
+        RawDetails struct {
+            OverallPfBalance struct {
+                CurrentPfBalance string `json:"current_pf_balance"`
+            } `json:"overall_pf_balance"`
+        } `json:"rawDetails"`
+    } `json:"uanAccounts"`
+}
+
+func loadEPFDetails(ctx context.Context, phone string) (*epfDetailsFile, error) {
+    data, err := readDataFile(ctx, phone, "fetch_epf_details.json")
+    if err != nil {
+        return nil, err
+    }
+    var epf epfDetailsFile
+    if err := json.Unmarshal(data, &epf); err != nil {
+        return nil, err
+    }
+    return &epf, nil
+}
+
+type creditReportFile struct {
+    CreditReports []struct {
+        CreditReportData struct {
+            Score struct {
+                BureauScore string `json:"bureauScore"`
+            } `json:"score"`
+            CreditAccount struct {
+                CreditAccountDetails []creditTradeline `json:"creditAccountDetails"`
+            } `json:"creditAccount"`
+        } `json:"creditReportData"`
+    } `json:"creditReports"`
+}
+
+// creditTradeline is one account on the bureau report. Reports carry no
+// account number, only the lender's name.
+type creditTradeline struct {
+    SubscriberName string `json:"subscriberName"`
+    AccountType    string `json:"accountType"`
+    AccountStatus  string `json:"accountStatus"`
+    CurrentBalance string `json:"currentBalance"`
+}
+
+func loadCreditReport(ctx context.Context, phone string) (*creditReportFile, error) {
+    data, err := readDataFile(ctx, phone, "fetch_credit_report.json")
+    if err != nil {
+        return nil, err
+    }
+    var cr creditReportFile
+    if err := json.Unmarshal(data, &cr); err != nil {
+        return nil, err
+    }
+    return &cr, nil
+}
+
+// creditScore returns the first bureau score in the report, or false when the
+// phone has no credit history.
+func (cr *creditReportFile) creditScore() (float64, bool) {
+    raw, ok := cr.bureauScore()
+    if !ok {
+        return 0, false
+    }
+    s, _ := strconv.ParseFloat(raw, 64)
+    return s, true
+}
+
+// bureauScore is the first report's score that parses, as written.
+func (cr *creditReportFile) bureauScore() (string, bool) {
+    for _, rep := range cr.CreditReports {
+        if _, err := strconv.ParseFloat(rep.CreditReportData.Score.BureauScore, 64); err == nil {
+            return rep.CreditReportData.Score.BureauScore, true
+        }
+    }
+    return "", false
+}
+
+// attribute sums every asset or liability value of the given type.
+func (nw *netWorthFile) attribute(name string) float64 {
+    var total float64
+    for _, values := range [][]netWorthAttribute{nw.NetWorthResponse.AssetValues, nw.NetWorthResponse.LiabilityValues} {
+        for _, a := range values {
+            if a.NetWorthAttribute == name {
+                total += a.Value.Float()
+            }
+        }
+    }
+    return total
+}
+
+// attributes lists the asset and liability values whose type matches.
+func (nw *netWorthFile) attributes(match func(string) bool) []netWorthAttribute {
+    out := []netWorthAttribute{}
+    for _, values := range [][]netWorthAttribute{nw.NetWorthResponse.AssetValues, nw.NetWorthResponse.LiabilityValues} {
+        for _, a := range values {
+            if match(a.NetWorthAttribute) {
+                out = append(out, a)
+            }
+        }
+    }
+    return out
+}
+
+// hasAttribute reports whether any asset or liability value has the type.
+func (nw *netWorthFile) hasAttribute(name string) bool {
+    for _, values := range [][]netWorthAttribute{nw.NetWorthResponse.AssetValues, nw.NetWorthResponse.LiabilityValues} {
+        for _, a := range values {
+            if a.NetWorthAttribute == name {
+                return true
+            }
+        }
+    }
+    return false
+}
+
+// totalLiabilities sums every LIABILITY_TYPE_* value. Some fixtures list
+// loans under assetValues with negative units, so both arrays are scanned and
+// the magnitude is used.
+func (nw *netWorthFile) totalLiabilities() float64 {
+    var total float64
+    for _, values := range [][]netWorthAttribute{nw.NetWorthResponse.AssetValues, nw.NetWorthResponse.LiabilityValues} {
+        for _, a := range values {
+            if strings.HasPrefix(a.NetWorthAttribute, "LIABILITY_TYPE_") {
+                total += math.Abs(a.Value.Float())
+            }
+        }
+    }
+    return total
+}
+
+// latestTxnDate is the newest transaction date across accounts, zero when
+// there is none. Fixtures are historical, so windows end here, not today.
+func (bt *bankTransactionsFile) latestTxnDate() time.Time {
+    var end time.Time
+    for _, acc := range bt.BankTransactions {
+        for _, t := range acc.Txns {
+            if d, err := time.Parse(time.DateOnly, t.Date); err == nil && d.After(end) {
+                end = d
+            }
+        }
+    }
+    return end
+}
+
+// isSpend reports whether the row is money going out as spending: debits,
+// installments and TDS.
+func (t bankTxn) isSpend() bool {
+    switch t.Type {
+    case bankTxnDebit, bankTxnTDS, bankTxnInstallment:
+        return true
+    }
+    return false
+}
+
+// latestBalances returns each account's balance on its most recent
+// transaction. Fixtures list rows newest first, so the first row seen on the
+// latest date wins.
+func (bt *bankTransactionsFile) latestBalances() []float64 {
+    out := make([]float64, 0, len(bt.BankTransactions))
+    for _, acc := range bt.BankTransactions {
+        var latest *bankTxn
+        for i := range acc.Txns {
+            if latest == nil || acc.Txns[i].Date > latest.Date {
+                latest = &acc.Txns[i]
+            }
+        }
+        if latest != nil {
+            out = append(out, latest.Balance)
+        }
+    }
+    return out
+}
+
+// Bank transaction types, from the fixture's schemaDescription.
+const (
+    bankTxnCredit      = 1
+    bankTxnDebit       = 2
+    bankTxnOpening     = 3
+    bankTxnInterest    = 4
+    bankTxnTDS         = 5
+    bankTxnInstallment = 6
+    bankTxnClosing     = 7
+    bankTxnOthers      = 8
+)
+
+// bankTxnTypeNames labels the numeric transaction types.
+var bankTxnTypeNames = map[int]string{
+    1: "CREDIT", 2: "DEBIT", 3: "OPENING", 4: "INTEREST",
+    5: "TDS", 6: "INSTALLMENT", 7: "CLOSING", 8: "OTHERS",
+}
+
+// cashflow is the fixture's monthly average of money in and out. Spend covers
+// every outflow (debits, installments, TDS); installments are also reported
+// on their own since they are the EMIs and SIPs that drive debt-to-income.
+type cashflow struct {
+    Income       float64
+    Spend        float64
+    Installments float64
+    Months       int
+}
+
+// monthlyCashflow averages over the calendar months the bank fixture covers.
+// Months is 0 when there are no dated transactions.
+func (bt *bankTransactionsFile) monthlyCashflow() cashflow {
+    var cf cashflow
+    seen := map[string]bool{}
+    for _, acc := range bt.BankTransactions {
+        for _, t := range acc.Txns {
+            if len(t.Date) >= 7 {
+                seen[t.Date[:7]] = true
+            }
+            switch t.Type {
+            case bankTxnCredit:
+                cf.Income += t.Amount
+            case bankTxnDebit, bankTxnTDS:
+                cf.Spend += t.Amount
+            case bankTxnInstallment:
+                cf.Spend += t.Amount
+                cf.Installments += t.Amount
+            }
+        }
+    }
+    cf.Months = len(seen)
+    if cf.Months == 0 {
+        return cashflow{}
+    }
+    n := float64(cf.Months)
+    cf.Income /= n
+    cf.Spend /= n
+    cf.Installments /= n
+    return cf
+}
@@ -0,0 +1,122 @@
+package main
+
+import (
+    "math"
+    "math/rand"
+    "net/http"
+    "sort"
+    "strconv"
+
+    "github.com/epifi/fi-mcp-lite/pkg"
+)
+
+// ————— net worth forecast —————
+// Each simulation grows today's assets by a normally distributed yearly
+// return and adds a year of the fixture's monthly savings; liabilities are
+// held flat. Assets never go below zero. The same seed always gives the same
+// bands.
+
+const (
+    forecastMeanReturn = 0.08
+    forecastVolatility = 0.12
+    maxForecastYears   = 50
+    maxForecastSims    = 10000
+)
+
+type forecastBand struct {
+    Year int     `json:"year"`
+    P10  float64 `json:"p10"`
+    P50  float64 `json:"p50"`
+    P90  float64 `json:"p90"`
+}
+
+func netWorthForecastHandler(w http.ResponseWriter, r *http.Request) {
+    phone := r.Context().Value("phone").(string)
+    q := r.URL.Query()
+    years, err := intParam(q.Get("years"), 10, 1, maxForecastYears)
+    if err != nil {
+        http.Error(w, "years must be an integer from 1 to 50", http.StatusBadRequest)
+        return
+    }
+    sims, err := intParam(q.Get("simulations"), pkg.GetForecastSimulations(), 1, maxForecastSims)
+    if err != nil {
+        http.Error(w, "simulations must be an integer from 1 to 10000", http.StatusBadRequest)
+        return
+    }
+    var seed int64 = 1
+    if s := q.Get("seed"); s != "" {
+        if seed, err = strconv.ParseInt(s, 10, 64); err != nil {
+            http.Error(w, "seed must be an integer", http.StatusBadRequest)
+            return
+        }
+    }
+
+    nw, err := loadNetWorth(r.Context(), phone)
+    if err != nil {
+        http.Error(w, "data not found", http.StatusInternalServerError)
+        return
+    }
+    liabilities := nw.totalLiabilities()
+    assets := math.Max(0, nw.NetWorthResponse.TotalNetWorthValue.Float()+liabilities)
+    // without bank data nothing is added, rather than guessing a savings rate
+    var yearlySavings float64
+    if bt, err := loadBankTransactions(r.Context(), phone); err == nil {
+        cf := bt.monthlyCashflow()
+        yearlySavings = math.Max(0, cf.Income-cf.Spend) * 12
+    }
+
+    writeJSON(w, map[string]interface{}{
+        "netWorth":      round2(assets - liabilities),
+        "yearlySavings": round2(yearlySavings),
+        "meanReturn":    forecastMeanReturn,
+        "volatility":    forecastVolatility,
+        "simulations":   sims,
+        "seed":          seed,
+        "bands":         simulateForecast(assets, liabilities, yearlySavings, years, sims, seed),
+    })
+}
+
+func simulateForecast(assets, liabilities, yearlySavings float64, years, sims int, seed int64) []forecastBand {
+    rng := rand.New(rand.NewSource(seed))
+    paths := make([][]float64, years) // paths[year][sim]
+    for y := range paths {
+        paths[y] = make([]float64, sims)
+    }
+    for s := 0; s < sims; s++ {
+        a := assets
+        for y := 0; y < years; y++ {
+            ret := forecastMeanReturn + forecastVolatility*rng.NormFloat64()
+            a = math.Max(0, a*(1+ret)+yearlySavings)
+            paths[y][s] = a - liabilities
+        }
+    }
+    bands := make([]forecastBand, years)
+    for y, values := range paths {
+        sort.Float64s(values)
+        bands[y] = forecastBand{
+            Year: y + 1,
+            P10:  round2(percentile(values, 10)),
+            P50:  round2(percentile(values, 50)),
+            P90:  round2(percentile(values, 90)),
+        }
+    }
+    return bands
+}
+
+// percentile is the nearest-rank percentile of sorted values.
+func percentile(sorted []float64, p float64) float64 {
+    i := int(math.Ceil(p/100*float64(len(sorted)))) - 1
+    return sorted[max(0, min(i, len(sorted)-1))]
+}
+
+// intParam parses an optional integer query param within [lo, hi].
+func intParam(s string, def, lo, hi int) (int, error) {
+    if s == "" {
+        return def, nil
+    }
+    n, err := strconv.Atoi(s)
+    if err != nil || n < lo || n > hi {
+        return 0, strconv.ErrRange
+    }
+    return n, nil
+}
@@ -0,0 +1,155 @@
+package main
+
+import (
+    "encoding/json"
+    "errors"
+    "fmt"
+    "log"
+    "net/http"
+    "strconv"
+    "time"
+
+    "github.com/epifi/fi-mcp-lite/datagen"
+)
+
+// ————— generated personas —————
+// POST /admin/personas/generate and the -generate flag fill new phones with
+// datagen fixtures. Persona i of a request is made from seed+i, so the same
+// request (with an explicit seed and end date) makes the same people again.
+
+const (
+    maxGeneratedPersonas = 500
+    firstGeneratedPhone  = 7000000000 // generated phones count up from here
+)
+
+type generateRequest struct {
+    Profile string `json:"profile"`
+    Seed    int64  `json:"seed"`   // 0 for a random one
+    Months  int    `json:"months"`
+    Count   int    `json:"count"`
+    Phone   string `json:"phone"` // only with a count of 1
+    End     string `json:"end"`   // YYYY-MM-DD, default today
+}
+
+type generatedPersona struct {
+    personaInfo
+    Profile string `json:"profile"`
+    Seed    int64  `json:"seed"`
+}
+
+// options checks the request and fills in its defaults.
+func (req *generateRequest) options() (datagen.Options, error) {
+    if req.Count == 0 {
+        req.Count = 1
+    }
+    if req.Count < 1 || req.Count > maxGeneratedPersonas {
+        return datagen.Options{}, fmt.Errorf("count must be between 1 and %d", maxGeneratedPersonas)
+    }
+    if req.Phone != "" && (req.Count != 1 || !phonePattern.MatchString(req.Phone)) {
+        return datagen.Options{}, errors.New("phone must be a valid number and needs a count of 1")
+    }
+    if req.Seed == 0 {
+        req.Seed = time.Now().UnixNano()
+    }
+    opts := datagen.Options{Profile: req.Profile, Seed: req.Seed, Months: req.Months}
+    if req.End != "" {
+        end, err := time.Parse(time.DateOnly, req.End)
+        if err != nil {
+            return datagen.Options{}, errors.New("end must be a YYYY-MM-DD date")
+        }
+        opts.End = end
+    }
+    if err := opts.Validate(); err != nil {
+        return datagen.Options{}, err
+    }
+    return opts, nil
+}
+
+// generatePersonas makes req.Count personas. It stops at the first error,
+// returning the personas created so far. endpoint is for the audit trail.
+func generatePersonas(req generateRequest, opts datagen.Options, endpoint string) ([]generatedPersona, error) {
+    out := []generatedPersona{}
+    next := int64(firstGeneratedPhone)
+    for i := 0; i < req.Count; i++ {
+        o := opts
+        o.Seed += int64(i)
+        files, err := datagen.Generate(o)
+        if err != nil {
+            return out, err
+        }
+        datasets := map[string][]byte{}
+        for _, e := range dataEndpoints {
+            data, ok := files[e.File]
+            if !ok {
+                continue
+            }
+            // a failure here is a datagen bug, not bad input
+            if err := validateFixture(e, data); err != nil {
+                return out, fmt.Errorf("generated %s: %v", e.Name, err)
+            }
+            datasets[e.Name] = data
+        }
+        for {
+            phone := req.Phone
+            if phone == "" {
+                phone = strconv.FormatInt(next, 10)
+                next++
+            }
+            err := createPersona(phone, datasets, endpoint)
+            if errors.Is(err, errPersonaExists) && req.Phone == "" {
+                continue
+            }
+            if err != nil {
+                return out, err
+            }
+            out = append(out, generatedPersona{describePersona(phone), o.Profile, o.Seed})
+            break
+        }
+    }
+    return out, nil
+}
+
+// generatePersonasHandler serves POST /admin/personas/generate with
+// {"profile": "...", "seed": n, "months": n, "count": n, "phone": "...", "end": "YYYY-MM-DD"};
+// every field is optional.
+func generatePersonasHandler(w http.ResponseWriter, r *http.Request) {
+    var req generateRequest
+    if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, 1<<20)).Decode(&req); err != nil {
+        http.Error(w, "invalid JSON body", http.StatusBadRequest)
+        return
+    }
+    opts, err := req.options()
+    if err != nil {
+        http.Error(w, err.Error(), http.StatusBadRequest)
+        return
+    }
+    out, err := generatePersonas(req, opts, r.Pattern)
+    if err != nil {
+        if errors.Is(err, errPersonaExists) {
+            http.Error(w, err.Error(), http.StatusConflict)
+            return
+        }
+        log.Printf("generate personas: %v (%d created)", err, len(out))
+        http.Error(w, "could not generate personas", http.StatusInternalServerError)
+        return
+    }
+    setDefaultContentType(w, defaultContentType)
+    w.WriteHeader(http.StatusCreated)
+    writeJSON(w, out)
+}
+
+// runGenerate is the -generate flag: it writes the personas and prints one
+// line per phone instead of starting the server.
+func runGenerate(req generateRequest) {
+    opts, err := req.options()
+    if err != nil {
+        log.Fatalf("-generate: %v", err)
+    }
+    out, err := generatePersonas(req, opts, "-generate")
+    for _, p := range out {
+        fmt.Printf("%s\t%s\tseed=%d\t%d dataset(s)\n", p.Phone, p.Profile, p.Seed, len(p.Datasets))
+    }
+    if err != nil {
+        log.Fatalf("-generate: %v", err)
+    }
+}
@@ -0,0 +1,89 @@
+package main
+
+import (
+    "context"
+    "math"
+    "net/http"
+    "sync"
+
+    "github.com/epifi/fi-mcp-lite/pkg"
+)
+
+// ————— financial health score —————
+// Each signal is mapped to a 0–100 sub-score. Signals whose fixtures are
+// missing are left out and the remaining weights are renormalised; confidence
+// reports the share of total weight that could actually be scored.
+
+// healthWeights reads the env once so bad entries are logged once, not per request.
+var healthWeights = sync.OnceValue(pkg.GetHealthScoreWeights)
+
+type healthComponent struct {
+    Score  float64 `json:"score"`
+    Weight float64 `json:"weight"`
+    Input  float64 `json:"input"`
+}
+
+func healthScoreHandler(w http.ResponseWriter, r *http.Request) {
+    phone := r.Context().Value("phone").(string)
+    comps := healthComponents(r.Context(), phone)
+    weights := healthWeights()
+
+    var total, used, composite float64
+    for name, wt := range weights {
+        total += wt
+        if c, ok := comps[name]; ok {
+            c.Weight = wt
+            comps[name] = c
+            used += wt
+            composite += wt * c.Score
+        }
+    }
+    if used > 0 {
+        composite /= used
+    }
+    confidence := 0.0
+    if total > 0 {
+        confidence = used / total
+    }
+    writeJSON(w, map[string]interface{}{
+        "score":      round2(composite),
+        "confidence": round2(confidence),
+        "components": comps,
+    })
+}
+
+// healthComponents computes every sub-score the phone's fixtures allow.
+func healthComponents(ctx context.Context, phone string) map[string]healthComponent {
+    comps := map[string]healthComponent{}
+
+    var cf cashflow
+    if bt, err := loadBankTransactions(ctx, phone); err == nil {
+        cf = bt.monthlyCashflow()
+    }
+    if cf.Income > 0 {
+        rate := (cf.Income - cf.Spend) / cf.Income
+        // a 30% savings rate or better is full marks
+        comps["savings_rate"] = healthComponent{Input: round2(rate), Score: clampScore(rate / 0.3 * 100)}
+
+        dti := cf.Installments / cf.Income
+        // installments of half the monthly income or more score zero
+        comps["dti"] = healthComponent{Input: round2(dti), Score: clampScore((1 - dti/0.5) * 100)}
+    }
+
+    if nw, err := loadNetWorth(ctx, phone); err == nil && cf.Spend > 0 {
+        cover := nw.attribute("ASSET_TYPE_SAVINGS_ACCOUNTS") / cf.Spend
+        // six months of spend in savings is full marks
+        comps["emergency_fund"] = healthComponent{Input: round2(cover), Score: clampScore(cover / 6 * 100)}
+    }
+
+    if cr, err := loadCreditReport(ctx, phone); err == nil {
+        if s, ok := cr.creditScore(); ok {
+            comps["credit_score"] = healthComponent{Input: s, Score: clampScore((s - 300) / 600 * 100)}
+        }
+    }
+    return comps
+}
+
+func clampScore(s float64) float64 {
+    return round2(math.Max(0, math.Min(100, s)))
+}
@@ -0,0 +1,281 @@
+package main
+
+import (
+    "bytes"
+    "context"
+    "encoding/json"
+    "errors"
+    "fmt"
+    "log"
+    "math"
+    "net/http"
+    "net/url"
+    "sort"
+    "strconv"
+    "strings"
+
+    "github.com/epifi/fi-mcp-lite/pkg"
+)
+
+// ————— insights —————
+// Plain-English bullets and savings suggestions about the phone's finances,
+// plus spend by category and flagged transactions. Everything is computed
+// from the fixtures first; with GOOGLE_API_KEY set Gemini rephrases the
+// bullets and suggestions and sorts merchants into spend categories, but the
+// amounts are always summed here. Any model failure falls back to the rules,
+// so the endpoint also works offline.
+
+// geminiBaseURL is the generateContent API root.
+const geminiBaseURL = "https://generativelanguage.googleapis.com/v1beta/models/"
+
+// maxModelMerchants caps how many merchants are sent for categorising.
+const maxModelMerchants = 40
+
+type spendCategory struct {
+    Category string  `json:"category"`
+    Amount   float64 `json:"amount"`
+}
+
+func insightsHandler(w http.ResponseWriter, r *http.Request) {
+    phone := r.Context().Value("phone").(string)
+    facts := ruleInsights(r.Context(), phone)
+    if len(facts) == 0 {
+        http.Error(w, "data not found", http.StatusInternalServerError)
+        return
+    }
+    var merchants []merchantTotal
+    var cf cashflow
+    flags := []anomaly{}
+    if bt, err := loadBankTransactions(r.Context(), phone); err == nil {
+        merchants = merchantTotals(bt)
+        cf = bt.monthlyCashflow()
+        flags = findAnomalies(bt, pkg.GetAnomalyZScore())
+    }
+    bullets, suggestions := facts, savingsSuggestions(cf, merchants)
+    categories := spendByMerchantCategory(merchants, nil)
+
+    out := map[string]interface{}{"source": "rules"}
+    if googleAPIKey != "" {
+        if m, err := modelInsights(r.Context(), facts, suggestions, merchants); err == nil && len(m.Insights) > 0 {
+            bullets = m.Insights
+            if len(m.SavingsSuggestions) > 0 {
+                suggestions = m.SavingsSuggestions
+            }
+            if len(m.MerchantCategories) > 0 {
+                categories = spendByMerchantCategory(merchants, m.MerchantCategories)
+            }
+            out["source"], out["model"] = "model", pkg.GetGeminiModel()
+        } else if err != nil {
+            log.Printf("insights: phone=%s: %v", logPhone(phone), err)
+        }
+    }
+    out["insights"] = bullets
+    out["savingsSuggestions"] = suggestions
+    out["spendCategories"] = categories
+    out["anomalyFlags"] = flags
+    writeJSON(w, out)
+}
+
+// savingsSuggestions turns the monthly cashflow and biggest merchants into
+// concrete steps, each with its own numbers.
+func savingsSuggestions(cf cashflow, merchants []merchantTotal) []string {
+    out := []string{}
+    if cf.Income > 0 {
+        if gap := 0.2*cf.Income - (cf.Income - cf.Spend); gap > 0 {
+            out = append(out, fmt.Sprintf("Cutting monthly spending by %s would get you to a 20%% savings rate.", formatINR(gap)))
+        } else {
+            out = append(out, fmt.Sprintf("Move at least %s a month into investments on payday so your surplus doesn't sit idle.", formatINR(0.2*cf.Income)))
+        }
+        if cf.Installments/cf.Income >= 0.4 {
+            out = append(out, fmt.Sprintf("Your EMIs and other installments are %s a month; prepaying the costliest loan first lowers the interest you pay.", formatINR(cf.Installments)))
+        }
+    }
+    var top *merchantTotal
+    for i, m := range merchants {
+        if m.Merchant != "UNKNOWN" && m.plainDebit > 0 && (top == nil || m.plainDebit > top.plainDebit) {
+            top = &merchants[i]
+        }
+    }
+    if top != nil {
+        out = append(out, fmt.Sprintf("Your biggest spend is %s at %s; trimming it by 10%% saves %s.",
+            top.Merchant, formatINR(top.plainDebit), formatINR(top.plainDebit/10)))
+    }
+    return out
+}
+
+// spendByMerchantCategory sums merchant debits by category, largest first.
+// With no categories (or a merchant missing from them) the merchant falls
+// under "Uncategorised".
+func spendByMerchantCategory(merchants []merchantTotal, categories map[string]string) []spendCategory {
+    sums := map[string]float64{}
+    for _, m := range merchants {
+        if m.TotalDebit <= 0 {
+            continue
+        }
+        cat := strings.TrimSpace(categories[m.Merchant])
+        if cat == "" {
+            cat = "Uncategorised"
+        }
+        sums[cat] += m.TotalDebit
+    }
+    out := make([]spendCategory, 0, len(sums))
+    for cat, amt := range sums {
+        out = append(out, spendCategory{cat, round2(amt)})
+    }
+    sort.Slice(out, func(i, j int) bool {
+        if out[i].Amount != out[j].Amount {
+            return out[i].Amount > out[j].Amount
+        }
+        return out[i].Category < out[j].Category
+    })
+    return out
+}
+
+// ruleInsights builds one deterministic bullet per signal the fixtures have.
+func ruleInsights(ctx context.Context, phone string) []string {
+    var out []string
+    comps := healthComponents(ctx, phone)
+
+    if nw, err := loadNetWorth(ctx, phone); err == nil {
+        total := nw.NetWorthResponse.TotalNetWorthValue.Float()
+        line := fmt.Sprintf("Your net worth is %s.", formatINR(total))
+        if debt := nw.totalLiabilities(); debt > 0 {
+            line = fmt.Sprintf("Your net worth is %s, after %s of loans and card dues.", formatINR(total), formatINR(debt))
+        }
+        out = append(out, line)
+    }
+    if c, ok := comps["savings_rate"]; ok {
+        switch pct := c.Input * 100; {
+        case pct <= -1:
+            out = append(out, fmt.Sprintf("You spend about %.0f%% more than you earn each month.", -pct))
+        case pct < 1:
+            out = append(out, "You save almost none of your monthly income; 20% or more is a healthier target.")
+        case pct < 10:
+            out = append(out, fmt.Sprintf("You save only about %.0f%% of your monthly income; 20%% or more is a healthier target.", pct))
+        default:
+            out = append(out, fmt.Sprintf("You save about %.0f%% of your monthly income.", pct))
+        }
+    }
+    if c, ok := comps["dti"]; ok && c.Input > 0 {
+        pct := c.Input * 100
+        if pct >= 40 {
+            out = append(out, fmt.Sprintf("EMIs and other installments take %.0f%% of your income, which is high; paying down loans would free up cash.", pct))
+        } else {
+            out = append(out, fmt.Sprintf("EMIs and other installments take %.0f%% of your income.", pct))
+        }
+    }
+    if c, ok := comps["emergency_fund"]; ok {
+        if c.Input < 3 {
+            out = append(out, fmt.Sprintf("Your savings cover %.1f months of spending; aim for 3 to 6 months as an emergency fund.", c.Input))
+        } else {
+            out = append(out, fmt.Sprintf("Your savings cover %.1f months of spending.", c.Input))
+        }
+    }
+    if c, ok := comps["credit_score"]; ok {
+        out = append(out, fmt.Sprintf("Your credit score is %.0f, which is %s.", c.Input, creditBand(c.Input)))
+    }
+    return out
+}
+
+func creditBand(score float64) string {
+    switch {
+    case score >= 750:
+        return "good"
+    case score >= 650:
+        return "fair"
+    default:
+        return "poor"
+    }
+}
+
+// formatINR writes a rupee amount with Indian digit grouping (₹12,34,567).
+func formatINR(v float64) string {
+    sign := ""
+    if v < 0 {
+        sign, v = "-", -v
+    }
+    s := strconv.FormatInt(int64(math.Round(v)), 10)
+    if len(s) > 3 {
+        head, tail := s[:len(s)-3], s[len(s)-3:]
+        var groups []string
+        for len(head) > 2 {
+            groups = append([]string{head[len(head)-2:]}, groups...)
+            head = head[:len(head)-2]
+        }
+        s = strings.Join(append([]string{head}, groups...), ",") + "," + tail
+    }
+    return sign + "₹" + s
+}
+
+type modelAnswer struct {
+    Insights           []string          `json:"insights"`
+    SavingsSuggestions []string          `json:"savingsSuggestions"`
+    MerchantCategories map[string]string `json:"merchantCategories"`
+}
+
+// modelInsights asks Gemini, within FI_MCP_GEMINI_TIMEOUT, to rephrase the
+// facts and suggestions and to put each merchant in a spend category.
+func modelInsights(ctx context.Context, facts, suggestions []string, merchants []merchantTotal) (*modelAnswer, error) {
+    ctx, cancel := context.WithTimeout(ctx, pkg.GetGeminiTimeout())
+    defer cancel()
+
+    var names []string
+    for _, m := range merchants {
+        if m.TotalDebit > 0 && m.Merchant != "UNKNOWN" && len(names) < maxModelMerchants {
+            names = append(names, m.Merchant)
+        }
+    }
+    prompt := "You are summarising one person's finances. Reply with JSON only, of the form " +
+        `{"insights": [...], "savingsSuggestions": [...], "merchantCategories": {"<merchant>": "<category>"}}. ` +
+        "insights: 3 to 5 short plain-English sentences using only the facts below and keeping every number. " +
+        "savingsSuggestions: the suggestions below, rephrased, keeping every number. " +
+        "merchantCategories: each merchant below mapped to one spend category such as Food, Groceries, Shopping, " +
+        "Travel, Bills & Utilities, Rent, Transfers, Entertainment, Health or Other.\n\nFacts:\n- " +
+        strings.Join(facts, "\n- ") + "\n\nSuggestions:\n- " + strings.Join(suggestions, "\n- ") +
+        "\n\nMerchants:\n- " + strings.Join(names, "\n- ")
+    body, _ := json.Marshal(map[string]interface{}{
+        "contents": []interface{}{
+            map[string]interface{}{"parts": []interface{}{map[string]string{"text": prompt}}},
+        },
+        "generationConfig": map[string]string{"responseMimeType": "application/json"},
+    })
+    endpoint := geminiBaseURL + url.PathEscape(pkg.GetGeminiModel()) +
+        ":generateContent?key=" + url.QueryEscape(googleAPIKey)
+    req, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint, bytes.NewReader(body))
+    if err != nil {
+        return nil, err
+    }
+    req.Header.Set("Content-Type", "application/json")
+    resp, err := http.DefaultClient.Do(req)
+    if err != nil {
+        return nil, errors.New("gemini: request failed") // the error would echo the key in the URL
+    }
+    defer resp.Body.Close()
+    if resp.StatusCode != http.StatusOK {
+        return nil, fmt.Errorf("gemini: %s", resp.Status)
+    }
+    var out struct {
+        Candidates []struct {
+            Content struct {
+                Parts []struct {
+                    Text string `json:"text"`
+                } `json:"parts"`
+            } `json:"content"`
+        } `json:"candidates"`
+    }
+    if err := json.NewDecoder(resp.Body).Decode(&out); err != nil {
+        return nil, err
+    }
+    if len(out.Candidates) == 0 || len(out.Candidates[0].Content.Parts) == 0 {
+        return nil, errors.New("gemini: empty answer")
+    }
+    var text strings.Builder
+    for _, p := range out.Candidates[0].Content.Parts {
+        text.WriteString(p.Text)
+    }
+    var ans modelAnswer
+    if err := json.Unmarshal([]byte(strings.TrimSpace(text.String())), &ans); err != nil {
+        return nil, fmt.Errorf("gemini: answer is not the requested JSON: %w", err)
+    }
+    return &ans, nil
+}
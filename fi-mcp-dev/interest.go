@@ -0,0 +1,79 @@
+package main
+
+import (
+    "math"
+    "net/http"
+    "strconv"
+)
+
+// ————— savings interest projection —————
+// Interest on today's savings balance, compounded monthly at ?rate= percent
+// a year for ?months= months. No deposits or withdrawals are assumed.
+
+const maxProjectionMonths = 600
+
+type interestMonth struct {
+    Month    int     `json:"month"`
+    Opening  float64 `json:"opening"`
+    Interest float64 `json:"interest"`
+    Closing  float64 `json:"closing"`
+}
+
+func interestProjectionHandler(w http.ResponseWriter, r *http.Request) {
+    phone := r.Context().Value("phone").(string)
+    q := r.URL.Query()
+    rate := 3.5
+    if s := q.Get("rate"); s != "" {
+        f, err := strconv.ParseFloat(s, 64)
+        if err != nil || f < 0 || f > 100 || math.IsNaN(f) {
+            http.Error(w, "rate must be a yearly percentage from 0 to 100", http.StatusBadRequest)
+            return
+        }
+        rate = f
+    }
+    months, err := intParam(q.Get("months"), 12, 1, maxProjectionMonths)
+    if err != nil {
+        http.Error(w, "months must be an integer from 1 to 600", http.StatusBadRequest)
+        return
+    }
+
+    balance := savingsFromBank(r.Context(), phone)
+    source := "fetch_bank_transactions.json"
+    if balance == nil {
+        nw, err := loadNetWorth(r.Context(), phone)
+        if err != nil || !nw.hasAttribute("ASSET_TYPE_SAVINGS_ACCOUNTS") {
+            http.Error(w, "data not found", http.StatusInternalServerError)
+            return
+        }
+        b := nw.attribute("ASSET_TYPE_SAVINGS_ACCOUNTS")
+        balance, source = &b, "fetch_net_worth.json"
+    }
+
+    // an overdrawn account earns nothing
+    schedule, total := projectInterest(math.Max(0, *balance), rate, months)
+    writeJSON(w, map[string]interface{}{
+        "balance":       round2(*balance),
+        "source":        source,
+        "rate":          rate,
+        "months":        months,
+        "totalInterest": total,
+        "schedule":      schedule,
+    })
+}
+
+func projectInterest(balance, rate float64, months int) ([]interestMonth, float64) {
+    monthly := rate / 100 / 12
+    schedule := make([]interestMonth, months)
+    start := balance
+    for m := range schedule {
+        interest := balance * monthly
+        schedule[m] = interestMonth{
+            Month:    m + 1,
+            Opening:  round2(balance),
+            Interest: round2(interest),
+            Closing:  round2(balance + interest),
+        }
+        balance += interest
+    }
+    return schedule, round2(balance - start)
+}
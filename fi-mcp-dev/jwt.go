@@ -0,0 +1,127 @@
+package main
+
+import (
+    "crypto/hmac"
+    "crypto/sha256"
+    "encoding/base64"
+    "encoding/json"
+    "net/http"
+    "strings"
+    "time"
+
+    "github.com/epifi/fi-mcp-lite/pkg"
+)
+
+// ————— bearer tokens —————
+// /auth/token trades a logged-in session for an HS256 JWT, so CLI tools and
+// agent runtimes can send "Authorization: Bearer <jwt>" instead of carrying
+// the cookie. Tokens are signed with FI_MCP_JWT_SECRET and last
+// FI_MCP_JWT_TTL; they are not tied to the session, so logging out does not
+// revoke them. No secret, no tokens.
+
+// jwtClaims are access token claims; the OIDC ID tokens from /oauth/token
+// add the omitempty ones.
+type jwtClaims struct {
+    Subject     string `json:"sub"` // phone
+    IssuedAt    int64  `json:"iat"`
+    Expires     int64  `json:"exp"`
+    Issuer      string `json:"iss,omitempty"`
+    Audience    string `json:"aud,omitempty"` // client_id
+    Nonce       string `json:"nonce,omitempty"`
+    PhoneNumber string `json:"phone_number,omitempty"`
+}
+
+// jwtHeader is the only header signJWT writes and verifyJWT accepts.
+var jwtHeader = base64.RawURLEncoding.EncodeToString([]byte(`{"alg":"HS256","typ":"JWT"}`))
+
+func signJWT(secret string, c jwtClaims) string {
+    payload, _ := json.Marshal(c)
+    unsigned := jwtHeader + "." + base64.RawURLEncoding.EncodeToString(payload)
+    mac := hmac.New(sha256.New, []byte(secret))
+    mac.Write([]byte(unsigned))
+    return unsigned + "." + base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
+
+// verifyJWT returns the claims of a valid, unexpired token. Only the HS256
+// header signJWT writes is accepted, which rules out "alg": "none" and key
+// confusion.
+func verifyJWT(secret, token string, now time.Time) (jwtClaims, bool) {
+    parts := strings.Split(token, ".")
+    if secret == "" || len(parts) != 3 || parts[0] != jwtHeader {
+        return jwtClaims{}, false
+    }
+    sig, err := base64.RawURLEncoding.DecodeString(parts[2])
+    if err != nil {
+        return jwtClaims{}, false
+    }
+    mac := hmac.New(sha256.New, []byte(secret))
+    mac.Write([]byte(parts[0] + "." + parts[1]))
+    if !hmac.Equal(sig, mac.Sum(nil)) {
+        return jwtClaims{}, false
+    }
+    payload, err := base64.RawURLEncoding.DecodeString(parts[1])
+    if err != nil {
+        return jwtClaims{}, false
+    }
+    var c jwtClaims
+    if err := json.Unmarshal(payload, &c); err != nil || c.Subject == "" || now.Unix() >= c.Expires {
+        return jwtClaims{}, false
+    }
+    return c, true
+}
+
+// bearerToken returns the token of an "Authorization: Bearer" header.
+func bearerToken(r *http.Request) (string, bool) {
+    scheme, token, ok := strings.Cut(r.Header.Get("Authorization"), " ")
+    if !ok || !strings.EqualFold(scheme, "Bearer") {
+        return "", false
+    }
+    return strings.TrimSpace(token), true
+}
+
+// bearerPhone checks a bearer token. ID tokens (the ones with an audience)
+// are not access tokens, and the phone must still be allowed, since an admin
+// may have deleted the persona after the token was issued.
+func bearerPhone(token string) (string, bool) {
+    c, ok := verifyJWT(pkg.GetJWTSecret(), token, time.Now())
+    if !ok || c.Audience != "" || !allowedPhone(c.Subject) {
+        return "", false
+    }
+    return c.Subject, true
+}
+
+// authTokenHandler serves POST /auth/token with sessionId and phoneNumber,
+// the same fields as /login, once that session is logged in as that phone.
+func authTokenHandler(w http.ResponseWriter, r *http.Request) {
+    if r.Method != http.MethodPost {
+        http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+        return
+    }
+    secret := pkg.GetJWTSecret()
+    if secret == "" {
+        http.Error(w, "bearer tokens disabled", http.StatusNotFound)
+        return
+    }
+    sid := r.FormValue("sessionId")
+    ph := r.FormValue("phoneNumber")
+    if sid == "" || ph == "" {
+        http.Error(w, "sessionId & phoneNumber required", http.StatusBadRequest)
+        return
+    }
+    phone := authMW.GetPhoneNumber(sid)
+    if phone == "" && authMW.IsPending(sid) {
+        http.Error(w, "mfa verification required", http.StatusForbidden)
+        return
+    }
+    if phone == "" || phone != ph {
+        http.Error(w, "login required", http.StatusUnauthorized)
+        return
+    }
+    ttl := pkg.GetJWTTTL()
+    now := time.Now()
+    writeJSON(w, map[string]interface{}{
+        "access_token": signJWT(secret, jwtClaims{Subject: phone, IssuedAt: now.Unix(), Expires: now.Add(ttl).Unix()}),
+        "token_type":   "Bearer",
+        "expires_in":   int64(ttl.Seconds()),
+    })
+}
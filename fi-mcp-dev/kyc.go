@@ -0,0 +1,45 @@
+package main
+
+import (
+    "net/http"
+    "sync"
+)
+
+// ————— simulated KYC —————
+// Each poll of /api/kyc_status moves the phone one step along
+// NOT_STARTED → IN_PROGRESS → VERIFIED, where it stays until reset.
+
+var kycStates = []string{"NOT_STARTED", "IN_PROGRESS", "VERIFIED"}
+
+var kyc = struct {
+    sync.Mutex
+    polls map[string]int
+}{polls: map[string]int{}}
+
+func kycStatusHandler(w http.ResponseWriter, r *http.Request) {
+    phone := r.Context().Value("phone").(string)
+    kyc.Lock()
+    step := kyc.polls[phone]
+    if step < len(kycStates)-1 {
+        kyc.polls[phone] = step + 1
+    }
+    kyc.Unlock()
+    writeJSON(w, map[string]string{"status": kycStates[step]})
+}
+
+// kycResetHandler puts ?phone= back to NOT_STARTED.
+func kycResetHandler(w http.ResponseWriter, r *http.Request) {
+    if r.Method != http.MethodPost {
+        http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+        return
+    }
+    phone := r.URL.Query().Get("phone")
+    if phone == "" {
+        http.Error(w, "phone is required", http.StatusBadRequest)
+        return
+    }
+    kyc.Lock()
+    delete(kyc.polls, phone)
+    kyc.Unlock()
+    writeJSON(w, map[string]string{"status": kycStates[0]})
+}
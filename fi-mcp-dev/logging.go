@@ -0,0 +1,41 @@
+package main
+
+import (
+    "crypto/hmac"
+    "crypto/sha256"
+    "encoding/hex"
+    "errors"
+    "io/fs"
+
+    "github.com/epifi/fi-mcp-lite/pkg"
+)
+
+// ————— log hygiene —————
+
+// logPhone is how a phone appears in logs. With FI_MCP_HASH_PHONE_LOGS on it
+// is the first 16 hex chars of HMAC-SHA256(FI_MCP_LOG_PHONE_SECRET, phone),
+// stable across restarts so lines can still be correlated. Hashing without a
+// secret would be trivially reversible over 10-digit numbers, so the phone is
+// redacted instead.
+func logPhone(phone string) string {
+    if !pkg.GetHashPhoneLogs() {
+        return phone
+    }
+    secret := pkg.GetLogPhoneSecret()
+    if secret == "" {
+        return "redacted"
+    }
+    mac := hmac.New(sha256.New, []byte(secret))
+    mac.Write([]byte(phone))
+    return "h:" + hex.EncodeToString(mac.Sum(nil))[:16]
+}
+
+// readErr drops the file path from fixture read errors; the path embeds the
+// phone, which is logged separately through logPhone.
+func readErr(err error) error {
+    var pe *fs.PathError
+    if errors.As(err, &pe) {
+        return pe.Err
+    }
+    return err
+}
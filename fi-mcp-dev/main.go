@@ -1,117 +1,454 @@
 package main
 
 import (
+    "bytes"
     "context"
+    "crypto/subtle"
+    "crypto/tls"
+    "encoding/json"
+    "errors"
+    "flag"
     "fmt"
     "html/template"
     "log"
+    "log/slog"
     "net/http"
     "os"
+    "os/signal"
+    "regexp"
+    "strings"
+    "syscall"
     "time"
 
+    "github.com/epifi/fi-mcp-lite/datagen"
     "github.com/epifi/fi-mcp-lite/middlewares"
     "github.com/epifi/fi-mcp-lite/pkg"
+    "golang.org/x/sync/singleflight"
 )
 
 var (
-    authMW        = middlewares.NewAuthMiddleware()
+    authMW        = newAuthMiddleware()
+    metrics       = middlewares.NewMetrics(logPhone)
     googleAPIKey  string
 )
 
 func main() {
+    var gen generateRequest
+    flag.IntVar(&gen.Count, "generate", 0, "write `n` generated personas to test_data_dir and exit")
+    flag.StringVar(&gen.Profile, "profile", datagen.DefaultProfile, "datagen profile for -generate: "+strings.Join(datagen.Profiles(), ", "))
+    flag.Int64Var(&gen.Seed, "seed", 0, "seed of the first -generate persona (0 for a random one)")
+    flag.IntVar(&gen.Months, "months", 6, "months of bank history for -generate")
+    flag.Parse()
 
+    googleAPIKey = pkg.GetGoogleAPIKey()
+    unpackBundled()
+    loadPersonas()
+    if gen.Count > 0 {
+        runGenerate(gen)
+        return
+    }
+    if pkg.GetPreload() {
+        preloadFixtures()
+    }
+    validateFixturesAtStartup()
     mux := http.NewServeMux()
 
     // ————— Login UI —————
     mux.Handle("/static/", http.StripPrefix("/static/", http.FileServer(http.Dir("static"))))
     mux.HandleFunc("/mockWebPage", webPageHandler)
     mux.HandleFunc("/login", loginHandler)
+    mux.HandleFunc("/login/verify", loginVerifyHandler)
+    mux.HandleFunc("/logout", logoutHandler)
+    mux.HandleFunc("/auth/token", authTokenHandler)
+
+    // ————— mock OAuth2 / OIDC —————
+    mux.HandleFunc("/oauth/authorize", oauthAuthorizeHandler)
+    mux.HandleFunc("/oauth/token", oauthTokenHandler)
+    mux.HandleFunc("/oauth/userinfo", oauthUserinfoHandler)
+    mux.HandleFunc("GET /.well-known/openid-configuration", oidcDiscoveryHandler)
 
-    // ————— Polling JSON endpoints —————
-    mux.Handle("/api/net_worth", withAuth(apiHandler("fetch_net_worth.json")))
-    mux.Handle("/api/credit_report", withAuth(apiHandler("fetch_credit_report.json")))
-    mux.Handle("/api/epf_details", withAuth(apiHandler("fetch_epf_details.json")))
-    mux.Handle("/api/mf_transactions", withAuth(apiHandler("fetch_mf_transactions.json")))
-    mux.Handle("/api/bank_transactions", withAuth(apiHandler("fetch_bank_transactions.json")))
-    mux.Handle("/api/stock_transactions", withAuth(apiHandler("fetch_stock_transactions.json")))
+    // ————— API docs —————
+    mux.HandleFunc("GET /openapi.json", openAPIHandler)
+    mux.HandleFunc("GET /docs", docsHandler)
 
+    // ————— MCP —————
+    mux.Handle("/mcp/stream", withShutdown(newMCPHandler()))
 
-    // ————— SSE streaming endpoints —————
-    mux.Handle("/stream/net_worth", withAuth(sseStream("fetch_net_worth.json", 2*time.Second)))
-    mux.Handle("/stream/credit_report", withAuth(sseStream("fetch_credit_report.json", 5*time.Second)))
-    mux.Handle("/stream/epf_details", withAuth(sseStream("fetch_epf_details.json", 2*time.Second)))
-    mux.Handle("/stream/mf_transactions", withAuth(sseStream("fetch_mf_transactions.json", 2*time.Second)))
-    mux.Handle("/stream/bank_transactions", withAuth(sseStream("fetch_bank_transactions.json", 2*time.Second)))
-    mux.Handle("/stream/stock_transactions", withAuth(sseStream("fetch_stock_transactions.json", 2*time.Second)))
-    
+    // ————— Polling JSON + SSE streaming endpoints —————
+    registerDataEndpoints(mux)
+    mux.Handle("/ws", allowParams([]string{"token"}, withStreamAuth(wsHandler(""))))
+
+    // ————— Derived JSON endpoints —————
+    mux.Handle("/api/net_worth/percentile", allowParams(nil, withAuth(http.HandlerFunc(netWorthPercentileHandler))))
+    mux.Handle("/api/net_worth/forecast", allowParams([]string{"years", "simulations", "seed"}, withAuth(responseCache("forecast", http.HandlerFunc(netWorthForecastHandler)))))
+    mux.Handle("/api/net_worth/by_account_type", allowParams(nil, withAuth(http.HandlerFunc(netWorthByAccountTypeHandler))))
+    mux.Handle("/api/net_worth/drift", allowParams([]string{"days", "period", "seed"}, withAuth(http.HandlerFunc(netWorthDriftHandler))))
+    mux.Handle("/api/accounts", allowParams(nil, withAuth(http.HandlerFunc(accountsHandler))))
+    mux.Handle("/api/health_score", allowParams(nil, withAuth(http.HandlerFunc(healthScoreHandler))))
+    mux.Handle("/api/kyc_status", allowParams(nil, withAuth(http.HandlerFunc(kycStatusHandler))))
+    mux.Handle("/api/anomalies", allowParams([]string{"z"}, withAuth(http.HandlerFunc(anomaliesHandler))))
+    mux.Handle("/api/bank_transactions/by_merchant", allowParams(nil, withAuth(http.HandlerFunc(bankTransactionsByMerchantHandler))))
+    mux.Handle("/api/benchmarks", allowParams(nil, withAuth(responseCache("benchmarks", http.HandlerFunc(benchmarksHandler)))))
+    mux.Handle("/api/sip_recommendation", allowParams([]string{"goal", "years", "rate"}, withAuth(http.HandlerFunc(sipRecommendationHandler))))
+    mux.Handle("/api/spending_calendar", allowParams([]string{"days"}, withAuth(http.HandlerFunc(spendingCalendarHandler))))
+    mux.Handle("/api/insights", allowParams(nil, withAuth(http.HandlerFunc(insightsHandler))))
+    mux.Handle("/api/interest_projection", allowParams([]string{"rate", "months"}, withAuth(http.HandlerFunc(interestProjectionHandler))))
+    mux.Handle("/api/reconcile", allowParams(nil, withAuth(http.HandlerFunc(reconcileHandler))))
+    mux.Handle("/api/analytics/cashflow", allowParams(nil, withAuth(http.HandlerFunc(cashflowHandler))))
+    mux.Handle("/api/analytics/categories", allowParams(nil, withAuth(http.HandlerFunc(categoriesHandler))))
+    mux.Handle("/api/analytics/networth_projection", allowParams([]string{"years"}, withAuth(http.HandlerFunc(netWorthProjectionHandler))))
+    mux.Handle("/api/simulation", allowParams(nil, withAuth(http.HandlerFunc(simulationHandler))))
+    mux.Handle("/api/summary", allowParams([]string{"explain"}, withAuth(responseCache("summary", http.HandlerFunc(summaryHandler)))))
+    mux.Handle("/api/batch", allowParams([]string{"types"}, withAuth(http.HandlerFunc(batchHandler))))
+    mux.Handle("/api/export/ofx", allowParams(nil, withAuth(http.HandlerFunc(ofxExportHandler))))
+    mux.Handle("/api/stream_token", allowParams([]string{"ttl"}, withAuth(http.HandlerFunc(streamTokenHandler))))
+    mux.Handle("GET /api/webhooks", allowParams(nil, withAuth(http.HandlerFunc(listWebhooksHandler))))
+    mux.Handle("POST /api/webhooks", allowParams(nil, withAuth(http.HandlerFunc(createWebhookHandler))))
+    mux.Handle("GET /api/webhooks/{id}", allowParams(nil, withAuth(http.HandlerFunc(getWebhookHandler))))
+    mux.Handle("PUT /api/webhooks/{id}", allowParams(nil, withAuth(http.HandlerFunc(updateWebhookHandler))))
+    mux.Handle("DELETE /api/webhooks/{id}", allowParams(nil, withAuth(http.HandlerFunc(deleteWebhookHandler))))
+
+    // ————— Derived SSE endpoints —————
+    mux.Handle("/stream/net_worth/ticker", allowParams([]string{"token", "startDelay", "interval"}, withStreamAuth(netWorthTicker(2*time.Second))))
+    mux.Handle("/stream/portfolio_value", allowParams([]string{"token", "startDelay", "interval", "seed"}, withStreamAuth(portfolioValueStream(2*time.Second))))
+
+    // ————— Admin endpoints —————
+    mux.Handle("/admin/kyc_status/reset", allowParams([]string{"phone"}, withAdmin(http.HandlerFunc(kycResetHandler))))
+    mux.Handle("/stats.json", withAdmin(http.HandlerFunc(statsHandler)))
+    mux.Handle("GET /metrics", metrics)
+    mux.HandleFunc("GET /healthz", healthzHandler)
+    mux.HandleFunc("GET /readyz", readyzHandler)
+    mux.Handle("/admin/simulation/reset", withAdmin(http.HandlerFunc(simulationResetHandler)))
+    mux.Handle("GET /admin/personas", withAdmin(http.HandlerFunc(listPersonasHandler)))
+    mux.Handle("POST /admin/personas", withAdmin(http.HandlerFunc(createPersonaHandler)))
+    mux.Handle("POST /admin/personas/generate", withAdmin(http.HandlerFunc(generatePersonasHandler)))
+    mux.Handle("PUT /admin/personas/{phone}/{dataset}", withAdmin(http.HandlerFunc(putDatasetHandler)))
+    mux.Handle("DELETE /admin/personas/{phone}/{dataset}", withAdmin(http.HandlerFunc(deleteDatasetHandler)))
+    mux.Handle("DELETE /admin/personas/{phone}", withAdmin(http.HandlerFunc(deletePersonaHandler)))
+    mux.Handle("GET /admin/audit", allowParams([]string{"phone"}, withAdmin(http.HandlerFunc(auditHandler))))
 
     port := pkg.GetPort()
-    log.Printf("Listening on :%s\n", port)
-    log.Fatal(http.ListenAndServe(":"+port, mux))
+    limits := pkg.GetServerLimits()
+    // No WriteTimeout: it would cut every SSE stream off. ReadTimeout only
+    // covers reading the request; net/http clears it once a GET is read.
+    srv := &http.Server{
+        Addr:              ":" + port,
+        Handler:           middlewares.AccessLog(accessLogger(), logPhone, metrics.Wrap(middlewares.Latency(withCORS(withRateLimit(withChaos(withDataDir(withScenario(middlewares.RecordPattern(mux))))))))),
+        ReadHeaderTimeout: limits.ReadHeaderTimeout,
+        ReadTimeout:       limits.ReadTimeout,
+        IdleTimeout:       limits.IdleTimeout,
+        MaxHeaderBytes:    limits.MaxHeaderBytes,
+    }
+    srv.RegisterOnShutdown(stopStreams)
+    srv.RegisterOnShutdown(stopWebhooks)
+    startWebhooks()
+
+    // with FI_MCP_TLS_CERT and FI_MCP_TLS_KEY the same server speaks HTTPS,
+    // for apps that only talk to https origins
+    cert, key := pkg.GetTLSFiles()
+    if (cert == "") != (key == "") {
+        log.Fatal("FI_MCP_TLS_CERT and FI_MCP_TLS_KEY must be set together")
+    }
+    listen := srv.ListenAndServe
+    scheme := "http"
+    if cert != "" {
+        srv.TLSConfig = &tls.Config{MinVersion: tls.VersionTLS12}
+        listen = func() error { return srv.ListenAndServeTLS(cert, key) }
+        scheme = "https"
+    }
+
+    ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+    defer stop()
+    serveErr := make(chan error, 1)
+    go func() { serveErr <- listen() }()
+    log.Printf("Listening on :%s (%s)\n", port, scheme)
+    select {
+    case err := <-serveErr:
+        log.Fatal(err)
+    case <-ctx.Done():
+    }
+
+    // in-flight requests get FI_MCP_SHUTDOWN_TIMEOUT to finish
+    log.Printf("shutting down")
+    start := time.Now()
+    shutdownCtx, cancel := context.WithTimeout(context.Background(), pkg.GetShutdownTimeout())
+    defer cancel()
+    metrics.StartDraining()
+    drainCtx, stopDrain := context.WithCancel(shutdownCtx)
+    drained := make(chan struct{})
+    go func() {
+        logDrain(drainCtx)
+        close(drained)
+    }()
+    if err := srv.Shutdown(shutdownCtx); err != nil {
+        log.Printf("shutdown: %v", err)
+    }
+    waitForSockets(shutdownCtx)
+    stopDrain()
+    <-drained
+    if n := metrics.ActiveStreams(); n > 0 {
+        log.Printf("shutdown: grace period over, %d stream(s) forced closed", n)
+    } else {
+        log.Printf("shutdown: all streams drained in %s", time.Since(start).Round(time.Millisecond))
+    }
+}
+
+// accessLogger writes FI_MCP_ACCESS_LOG lines as JSON on stdout, apart from
+// the plain log on stderr; nil turns them off.
+func accessLogger() *slog.Logger {
+    if !pkg.GetAccessLog() {
+        return nil
+    }
+    return slog.New(slog.NewJSONHandler(os.Stdout, nil))
+}
+
+// newAuthMiddleware keeps sessions in the FI_MCP_SESSION_STORE backend:
+// memory (the default), a JSON file, Redis or SQLite. FI_MCP_SESSION_SLIDING
+// renews them on use.
+func newAuthMiddleware() *middlewares.AuthMiddleware {
+    var store middlewares.SessionStore
+    var err error
+    switch backend := pkg.GetSessionStore(); backend {
+    case "memory":
+        store = middlewares.NewMemorySessionStore()
+    case "file":
+        store, err = middlewares.NewFileSessionStore(pkg.GetSessionFile())
+    case "redis":
+        store, err = middlewares.NewRedisSessionStore(pkg.GetRedisURL())
+    case "sqlite":
+        store, err = middlewares.NewSQLiteSessionStore(pkg.GetSessionDB())
+    default:
+        log.Fatalf("FI_MCP_SESSION_STORE: unknown backend %q (memory, file, redis or sqlite)", backend)
+    }
+    if err != nil {
+        log.Fatalf("FI_MCP_SESSION_STORE: %v", err)
+    }
+    m := middlewares.NewAuthMiddlewareWithStore(store, pkg.GetSessionTTL())
+    m.Sliding = pkg.GetSessionSliding()
+    return m
 }
 
 // ————— auth wrapper —————
 func withAuth(next http.Handler) http.Handler {
     return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-        c, err := r.Cookie("sessionid")
+        if token, ok := bearerToken(r); ok {
+            phone, ok := bearerPhone(token)
+            if !ok {
+                w.Header().Set("WWW-Authenticate", `Bearer error="invalid_token"`)
+                http.Error(w, "invalid or expired token", http.StatusUnauthorized)
+                return
+            }
+            middlewares.SetRequestPhone(r.Context(), phone)
+            next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), "phone", phone)))
+            return
+        }
+        c, err := r.Cookie(pkg.GetCookieName())
         if err != nil {
             http.Error(w, "login required", http.StatusUnauthorized)
             return
         }
         phone := authMW.GetPhoneNumber(c.Value)
+        if phone == "" && authMW.IsPending(c.Value) {
+            http.Error(w, "mfa verification required", http.StatusForbidden)
+            return
+        }
         if phone == "" {
             http.Error(w, "login required", http.StatusUnauthorized)
             return
         }
+        middlewares.SetRequestPhone(r.Context(), phone)
         ctx := context.WithValue(r.Context(), "phone", phone)
         next.ServeHTTP(w, r.WithContext(ctx))
     })
 }
 
-// ————— generic JSON file server —————
-func apiHandler(fileName string) http.Handler {
+// sessionKey identifies the caller for per-session state: the session cookie,
+// or the phone when a stream token was used instead.
+func sessionKey(r *http.Request) string {
+    if c, err := r.Cookie(pkg.GetCookieName()); err == nil {
+        return c.Value
+    }
+    return r.Context().Value("phone").(string)
+}
+
+// withAdmin guards operator endpoints with the X-Admin-Token header. With no
+// FI_MCP_ADMIN_TOKEN configured the admin endpoints are disabled.
+func withAdmin(next http.Handler) http.Handler {
     return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-        phone := r.Context().Value("phone").(string)
-        data, err := os.ReadFile(fmt.Sprintf("test_data_dir/%s/%s", phone, fileName))
-        if err != nil {
-            http.Error(w, "data not found", http.StatusInternalServerError)
+        token := pkg.GetAdminToken()
+        if token == "" {
+            http.Error(w, "admin API disabled", http.StatusNotFound)
+            return
+        }
+        if subtle.ConstantTimeCompare([]byte(r.Header.Get("X-Admin-Token")), []byte(token)) != 1 {
+            http.Error(w, "admin token required", http.StatusForbidden)
             return
         }
-        w.Header().Set("Content-Type", "application/json")
-        w.Write(data)
+        next.ServeHTTP(w, r)
     })
 }
 
-// ————— SSE helper —————
-func sseStream(fileName string, interval time.Duration) http.Handler {
-    return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-        phone := r.Context().Value("phone").(string)
-        w.Header().Set("Content-Type", "text/event-stream")
-        w.Header().Set("Cache-Control", "no-cache")
-        w.Header().Set("Connection", "keep-alive")
+// ————— fixture access —————
+// readGroup coalesces concurrent reads of the same fixture (many streams
+// ticking on one phone) into a single cachedReadFile.
+var readGroup singleflight.Group
+
+const dataDir = "test_data_dir"
+
+// phonePattern is what a phone must look like before it goes into a path.
+var phonePattern = regexp.MustCompile(`^[0-9]{1,15}$`)
+
+var errInvalidPhone = errors.New("invalid phone number")
+
+// allowedPhone reports whether ph is all digits and one of the allowed
+// numbers, i.e. safe to use as a data directory name.
+func allowedPhone(ph string) bool {
+    if !phonePattern.MatchString(ph) {
+        return false
+    }
+    for _, allowed := range allowedMobileNumbers() {
+        if ph == allowed {
+            return true
+        }
+    }
+    return false
+}
+
+func dataFilePath(phone, fileName string) string {
+    return fmt.Sprintf("%s/%s/%s", dataDir, phone, fileName)
+}
+
+// dataDirAvailable reports whether the fixture directory is still there; it
+// can vanish under a running server when a volume is unmounted.
+func dataDirAvailable() bool {
+    fi, err := os.Stat(dataDir)
+    return err == nil && fi.IsDir()
+}
 
-        fl, ok := w.(http.Flusher)
-        if !ok {
-            http.Error(w, "streaming unsupported", http.StatusInternalServerError)
+// withDataDir answers /api/ and /stream/ requests with 503 while the data
+// directory is missing, instead of letting every handler fail its read.
+func withDataDir(next http.Handler) http.Handler {
+    return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+        if (strings.HasPrefix(r.URL.Path, "/api/") || strings.HasPrefix(r.URL.Path, "/stream/")) && !dataDirAvailable() {
+            w.Header().Set("Retry-After", "5")
+            http.Error(w, "data directory unavailable", http.StatusServiceUnavailable)
             return
         }
-        ticker := time.NewTicker(interval)
-        defer ticker.Stop()
+        next.ServeHTTP(w, r)
+    })
+}
 
-        for {
-            select {
-            case <-r.Context().Done():
-                return
-            case <-ticker.C:
-                data, err := os.ReadFile(fmt.Sprintf("test_data_dir/%s/%s", phone, fileName))
-                if err != nil {
-                    log.Println("read error:", err)
-                    continue
-                }
-                fmt.Fprintf(w, "data: %s\n\n", data)
-                fl.Flush()
-            }
+// readDataFile returns the fixture for a phone, with its simulation applied
+// if it has one. The slice may be shared with concurrent callers and must
+// not be modified. A cancelled ctx returns straight away with ctx.Err(); the
+// shared read itself still completes for any other caller waiting on it.
+func readDataFile(ctx context.Context, phone, fileName string) ([]byte, error) {
+    if err := ctx.Err(); err != nil {
+        return nil, err
+    }
+    if !phonePattern.MatchString(phone) {
+        // never let a session value walk out of the data directory
+        return nil, errInvalidPhone
+    }
+    path := scenarioFilePath(ctx, phone, fileName)
+    ch := readGroup.DoChan(path, func() (interface{}, error) {
+        return cachedReadFile(path)
+    })
+    select {
+    case <-ctx.Done():
+        return nil, ctx.Err()
+    case res := <-ch:
+        if res.Err != nil {
+            return nil, res.Err
         }
+        return simulate(ctx, phone, fileName, res.Val.([]byte)), nil
+    }
+}
+
+var errEmptyFixture = errors.New("fixture file is empty")
+
+// servedFixture substitutes FI_MCP_EMPTY_FIXTURE for an empty fixture, or
+// returns errEmptyFixture, so clients never get a blank JSON body.
+func servedFixture(data []byte) ([]byte, error) {
+    if len(bytes.TrimSpace(data)) > 0 {
+        return minifyJSON(data), nil
+    }
+    if v := pkg.GetEmptyFixture(); v != "error" {
+        return []byte(v), nil
+    }
+    return nil, errEmptyFixture
+}
+
+// fixtureBody is a phone's fixture as served to clients: read, then passed
+// through servedFixture.
+func fixtureBody(ctx context.Context, phone, fileName string) ([]byte, error) {
+    data, err := readDataFile(ctx, phone, fileName)
+    if err != nil {
+        return nil, err
+    }
+    return servedFixture(data)
+}
+
+// minifyJSON compacts fixtures when FI_MCP_MINIFY_JSON is on. Files that
+// aren't valid JSON, or the flag being off, leave the bytes as on disk.
+func minifyJSON(data []byte) []byte {
+    if !pkg.GetMinifyJSON() {
+        return data
+    }
+    var buf bytes.Buffer
+    if err := json.Compact(&buf, data); err != nil {
+        return data
+    }
+    return buf.Bytes()
+}
+
+// writeJSON keeps a Content-Type set earlier (see withContentType).
+func writeJSON(w http.ResponseWriter, v interface{}) {
+    setDefaultContentType(w, "application/json")
+    json.NewEncoder(w).Encode(v)
+}
+
+func setDefaultContentType(w http.ResponseWriter, ct string) {
+    if w.Header().Get("Content-Type") == "" {
+        w.Header().Set("Content-Type", ct)
+    }
+}
+
+// ————— generic JSON file server —————
+func apiHandler(fileName string) http.Handler {
+    return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+        phone := r.Context().Value("phone").(string)
+        body, err := fixtureBody(r.Context(), phone, fileName)
+        if errors.Is(err, errEmptyFixture) {
+            http.Error(w, err.Error(), http.StatusInternalServerError)
+            return
+        }
+        if err != nil {
+            http.Error(w, "data not found", http.StatusInternalServerError)
+            return
+        }
+        setDefaultContentType(w, "application/json")
+        w.Write(body)
+    })
+}
+
+// ————— stats —————
+// statsHandler is a plain JSON view of the server counters for setups
+// without a metrics scraper.
+func statsHandler(w http.ResponseWriter, r *http.Request) {
+    reqs := metrics.Requests()
+    var total uint64
+    for _, n := range reqs {
+        total += n
+    }
+    writeJSON(w, map[string]interface{}{
+        "requests": map[string]interface{}{
+            "total":     total,
+            "byPattern": reqs,
+        },
+        "activeStreams": metrics.ActiveStreams(),
+        "sessions":      authMW.SessionCount(),
+        "fixtureCache":  fixtureCacheStats(),
     })
 }
 
@@ -126,7 +463,7 @@ func webPageHandler(w http.ResponseWriter, r *http.Request) {
     data := struct {
         SessionId string
         Allowed   []string
-    }{sid, pkg.GetAllowedMobileNumbers()}
+    }{sid, allowedMobileNumbers()}
     tmpl.Execute(w, data)
 }
 
@@ -141,8 +478,69 @@ func loginHandler(w http.ResponseWriter, r *http.Request) {
         http.Error(w, "sessionId & phoneNumber required", http.StatusBadRequest)
         return
     }
+    if !allowedPhone(ph) {
+        http.Error(w, "invalid phoneNumber", http.StatusBadRequest)
+        return
+    }
+    http.SetCookie(w, &http.Cookie{Name: pkg.GetCookieName(), Value: sid, Path: pkg.GetCookiePath(), Secure: r.TLS != nil})
+    if pkg.GetMFA() {
+        authMW.AddPendingSession(sid, ph)
+        log.Printf("login: phone=%s mfa challenge issued", logPhone(ph))
+        setDefaultContentType(w, "application/json")
+        w.WriteHeader(http.StatusAccepted)
+        writeJSON(w, map[string]string{"status": "challenge_required", "verify": "/login/verify"})
+        return
+    }
     authMW.AddSession(sid, ph)
-    http.SetCookie(w, &http.Cookie{Name: "sessionid", Value: sid, Path: "/"})
+    log.Printf("login: phone=%s", logPhone(ph))
+    tmpl, _ := template.ParseFiles("static/login_successful.html")
+    tmpl.Execute(w, nil)
+}
+
+// logoutHandler ends the cookie's session. It succeeds even when the session
+// is already gone.
+func logoutHandler(w http.ResponseWriter, r *http.Request) {
+    if r.Method != http.MethodPost {
+        http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+        return
+    }
+    if c, err := r.Cookie(pkg.GetCookieName()); err == nil {
+        if ph := authMW.GetPhoneNumber(c.Value); ph != "" {
+            log.Printf("logout: phone=%s", logPhone(ph))
+        }
+        authMW.RemoveSession(c.Value)
+    }
+    http.SetCookie(w, &http.Cookie{Name: pkg.GetCookieName(), Value: "", Path: pkg.GetCookiePath(), MaxAge: -1, Secure: r.TLS != nil})
+    w.WriteHeader(http.StatusOK)
+}
+
+// loginVerifyHandler completes an MFA login: POST sessionId (or the session
+// cookie) and otp. Wrong codes get 401 until the pending login is dropped.
+func loginVerifyHandler(w http.ResponseWriter, r *http.Request) {
+    if r.Method != http.MethodPost {
+        http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+        return
+    }
+    sid := r.FormValue("sessionId")
+    if c, err := r.Cookie(pkg.GetCookieName()); sid == "" && err == nil {
+        sid = c.Value
+    }
+    otp := r.FormValue("otp")
+    if sid == "" || otp == "" {
+        http.Error(w, "sessionId & otp required", http.StatusBadRequest)
+        return
+    }
+    ok := subtle.ConstantTimeCompare([]byte(otp), []byte(pkg.GetMFACode())) == 1
+    ph, found := authMW.VerifySession(sid, ok)
+    switch {
+    case !found:
+        http.Error(w, "no pending login for session", http.StatusBadRequest)
+        return
+    case !ok:
+        http.Error(w, "invalid otp", http.StatusUnauthorized)
+        return
+    }
+    log.Printf("login: phone=%s mfa verified", logPhone(ph))
     tmpl, _ := template.ParseFiles("static/login_successful.html")
     tmpl.Execute(w, nil)
 }
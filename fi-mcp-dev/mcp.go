@@ -0,0 +1,213 @@
+package main
+
+import (
+    "bytes"
+    "context"
+    "encoding/json"
+    "errors"
+    "fmt"
+    "io"
+    "net/http"
+    "os"
+    "strings"
+
+    "github.com/mark3labs/mcp-go/mcp"
+    "github.com/mark3labs/mcp-go/server"
+
+    "github.com/epifi/fi-mcp-lite/middlewares"
+    "github.com/epifi/fi-mcp-lite/pkg"
+)
+
+// ————— MCP tools —————
+// /mcp/stream speaks MCP (JSON-RPC 2.0 over streamable HTTP) with one
+// fetch_<name> tool per data endpoint. A tool call is authorised by the
+// session cookie, or by the MCP session ID having been logged in through
+// /mockWebPage?sessionId=<id>; until then tools answer with that login URL.
+
+func newMCPHandler() http.Handler {
+    s := server.NewMCPServer("fi-mcp-dev", "0.1.0", server.WithToolCapabilities(false))
+    for _, e := range dataEndpoints {
+        s.AddTool(mcp.NewTool("fetch_"+e.Name,
+            mcp.WithDescription(e.Description),
+            mcp.WithReadOnlyHintAnnotation(true),
+        ), mcpFetchTool(e))
+    }
+    return withMCPBatch(server.NewStreamableHTTPServer(s, server.WithHTTPContextFunc(mcpContext)))
+}
+
+// mcpContext carries over what tools need from the HTTP request: the bearer
+// token's or cookie's phone, if any, and the base URL for login links.
+func mcpContext(ctx context.Context, r *http.Request) context.Context {
+    if token, ok := bearerToken(r); ok {
+        if phone, ok := bearerPhone(token); ok {
+            middlewares.SetRequestPhone(ctx, phone)
+            ctx = context.WithValue(ctx, "phone", phone)
+        }
+    } else if c, err := r.Cookie(pkg.GetCookieName()); err == nil {
+        if phone := authMW.GetPhoneNumber(c.Value); phone != "" {
+            middlewares.SetRequestPhone(ctx, phone)
+            ctx = context.WithValue(ctx, "phone", phone)
+        }
+    }
+    return context.WithValue(ctx, "baseURL", requestBaseURL(r))
+}
+
+// requestBaseURL is the scheme and host the client reached the server on.
+func requestBaseURL(r *http.Request) string {
+    scheme := "http"
+    if r.TLS != nil {
+        scheme = "https"
+    }
+    return scheme + "://" + r.Host
+}
+
+func mcpFetchTool(e dataEndpoint) server.ToolHandlerFunc {
+    return func(ctx context.Context, req mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+        phone, _ := ctx.Value("phone").(string)
+        sid := ""
+        if session := server.ClientSessionFromContext(ctx); session != nil {
+            sid = session.SessionID()
+        }
+        if phone == "" && sid != "" {
+            phone = authMW.GetPhoneNumber(sid)
+        }
+        if phone == "" {
+            base, _ := ctx.Value("baseURL").(string)
+            prompt, _ := json.Marshal(map[string]string{
+                "status":    "login_required",
+                "login_url": base + "/mockWebPage?sessionId=" + sid,
+                "message":   "Ask the user to open login_url and log in, then call this tool again.",
+            })
+            return mcp.NewToolResultText(string(prompt)), nil
+        }
+        body, err := fixtureBody(ctx, phone, e.File)
+        if errors.Is(err, os.ErrNotExist) {
+            return mcp.NewToolResultError("no " + e.Name + " data for this phone"), nil
+        }
+        if err != nil {
+            return mcp.NewToolResultError(e.Name + " data unavailable"), nil
+        }
+        return mcp.NewToolResultText(string(body)), nil
+    }
+}
+
+// ————— JSON-RPC batches —————
+// mcp-go's streamable HTTP server takes one message per POST. withMCPBatch
+// also takes a JSON array: each element goes to next as a POST of its own,
+// with the batch's headers, and the responses come back as an array in the
+// same order. Notifications get no entry, as JSON-RPC says, and a batch of
+// nothing but notifications is 202 with no body. An element next refuses
+// outright, such as one with an unknown session, gets an error object with
+// its id instead of failing the batch.
+
+const (
+    maxMCPBatch = 100
+    maxMCPBody  = 4 << 20
+)
+
+type rpcError struct {
+    JSONRPC string          `json:"jsonrpc"`
+    ID      json.RawMessage `json:"id"`
+    Error   struct {
+        Code    int    `json:"code"`
+        Message string `json:"message"`
+    } `json:"error"`
+}
+
+func newRPCError(id json.RawMessage, code int, message string) json.RawMessage {
+    if len(id) == 0 {
+        id = json.RawMessage("null")
+    }
+    e := rpcError{JSONRPC: mcp.JSONRPC_VERSION, ID: id}
+    e.Error.Code, e.Error.Message = code, message
+    data, _ := json.Marshal(e)
+    return data
+}
+
+func withMCPBatch(next http.Handler) http.Handler {
+    return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+        if r.Method != http.MethodPost {
+            next.ServeHTTP(w, r)
+            return
+        }
+        body, err := io.ReadAll(http.MaxBytesReader(w, r.Body, maxMCPBody))
+        if err != nil {
+            http.Error(w, "request body too large", http.StatusRequestEntityTooLarge)
+            return
+        }
+        if trimmed := bytes.TrimLeft(body, " \t\r\n"); len(trimmed) == 0 || trimmed[0] != '[' {
+            r.Body = io.NopCloser(bytes.NewReader(body))
+            next.ServeHTTP(w, r)
+            return
+        }
+        var batch []json.RawMessage
+        if err := json.Unmarshal(body, &batch); err != nil {
+            writeRPCBatchError(w, newRPCError(nil, mcp.PARSE_ERROR, "batch is not valid JSON"))
+            return
+        }
+        if len(batch) == 0 || len(batch) > maxMCPBatch {
+            writeRPCBatchError(w, newRPCError(nil, mcp.INVALID_REQUEST, fmt.Sprintf("a batch holds 1 to %d messages", maxMCPBatch)))
+            return
+        }
+        out := []json.RawMessage{}
+        for _, msg := range batch {
+            if res, ok := mcpBatchCall(w, next, r, msg); ok {
+                out = append(out, res)
+            }
+        }
+        if len(out) == 0 {
+            w.WriteHeader(http.StatusAccepted)
+            return
+        }
+        writeJSON(w, out)
+    })
+}
+
+// mcpBatchCall passes one batch element to next. ok is false for a
+// notification, which gets no response. The session ID header next sets,
+// as for an initialize, is copied onto w.
+func mcpBatchCall(w http.ResponseWriter, next http.Handler, r *http.Request, msg json.RawMessage) (json.RawMessage, bool) {
+    var head struct {
+        ID *json.RawMessage `json:"id"`
+    }
+    if err := json.Unmarshal(msg, &head); err != nil {
+        return newRPCError(nil, mcp.INVALID_REQUEST, "batch element is not a JSON-RPC message"), true
+    }
+    sub := r.Clone(r.Context())
+    sub.Body = io.NopCloser(bytes.NewReader(msg))
+    sub.ContentLength = int64(len(msg))
+    rec := &recorder{header: http.Header{}, status: http.StatusOK}
+    next.ServeHTTP(rec, sub)
+    if sid := rec.header.Get("Mcp-Session-Id"); sid != "" {
+        w.Header().Set("Mcp-Session-Id", sid)
+    }
+    if head.ID == nil {
+        return nil, false
+    }
+    res := bytes.TrimSpace(rec.body.Bytes())
+    if strings.HasPrefix(rec.header.Get("Content-Type"), "text/event-stream") {
+        // notifications upgraded the answer to SSE; the response is the
+        // last event
+        res = lastSSEData(res)
+    }
+    if len(res) == 0 || !json.Valid(res) {
+        return newRPCError(*head.ID, mcp.INVALID_REQUEST, strings.TrimSpace(rec.body.String())), true
+    }
+    return res, true
+}
+
+func lastSSEData(events []byte) []byte {
+    var data []byte
+    for _, line := range bytes.Split(events, []byte("\n")) {
+        if d, ok := bytes.CutPrefix(line, []byte("data: ")); ok {
+            data = d
+        }
+    }
+    return data
+}
+
+func writeRPCBatchError(w http.ResponseWriter, e json.RawMessage) {
+    w.Header().Set("Content-Type", "application/json")
+    w.WriteHeader(http.StatusBadRequest)
+    w.Write(e)
+}
@@ -0,0 +1,151 @@
+package main
+
+import (
+    "net/http"
+    "regexp"
+    "sort"
+    "strings"
+)
+
+// ————— transactions by merchant —————
+// Narrations are free text from the bank, e.g.
+// "UPI-SWIGGY-SWIGGY@YBL-YESB0YBLUPI-104831200709-FOOD". merchantKey reduces
+// them to a stable name by dropping the payment rail prefix and anything
+// that looks like a reference: VPAs, IFSC codes, masked numbers, and tokens
+// containing digits.
+
+// paymentRails are narration prefixes naming how money moved, not to whom.
+var paymentRails = map[string]bool{
+    "UPI": true, "IMPS": true, "RTGS": true, "NEFT": true, "NEFT DR": true, "NEFT CR": true,
+    "ACH D": true, "ACH C": true, "BILLPAY": true, "CHQ PAID": true, "CASH WDL": true,
+}
+
+var (
+    ifscPattern   = regexp.MustCompile(`^[A-Z]{4}0[A-Z0-9]{6}$`)
+    maskedPattern = regexp.MustCompile(`^X+\d*$`)
+    monthNames    = map[string]bool{
+        "JAN": true, "FEB": true, "MAR": true, "APR": true, "MAY": true, "JUN": true,
+        "JUL": true, "AUG": true, "SEP": true, "OCT": true, "NOV": true, "DEC": true,
+        "JANUARY": true, "FEBRUARY": true, "MARCH": true, "APRIL": true, "JUNE": true, "JULY": true,
+        "AUGUST": true, "SEPTEMBER": true, "OCTOBER": true, "NOVEMBER": true, "DECEMBER": true,
+    }
+)
+
+// merchantWords drops reference-like words (digits, VPAs, dates, masked
+// numbers, REF codes) from one narration segment.
+func merchantWords(seg string) string {
+    if ifscPattern.MatchString(seg) {
+        return ""
+    }
+    var words []string
+    for _, w := range strings.Fields(seg) {
+        w = strings.Trim(w, ".,:;")
+        switch {
+        case w == "", monthNames[w], maskedPattern.MatchString(w),
+            strings.ContainsAny(w, "0123456789@/#"),
+            strings.HasPrefix(w, "REF") && w != "REFUND":
+            continue
+        }
+        words = append(words, w)
+    }
+    return strings.Join(words, " ")
+}
+
+// merchantKey normalizes a narration into the merchant it was paid to or
+// received from.
+func merchantKey(narration string) string {
+    s := strings.Join(strings.Fields(strings.ToUpper(narration)), " ")
+    var segs []string
+    for _, seg := range strings.Split(strings.TrimLeft(s, ".,"), "-") {
+        if seg = strings.TrimSpace(seg); seg != "" {
+            segs = append(segs, seg)
+        }
+    }
+    if len(segs) == 0 {
+        return "UNKNOWN"
+    }
+    if paymentRails[segs[0]] {
+        // the counterparty is the first segment that isn't a reference
+        for _, seg := range segs[1:] {
+            if m := merchantWords(seg); m != "" {
+                return m
+            }
+        }
+        return segs[0] // nothing but references, e.g. a transfer to a masked account
+    }
+    // free-form narrations ("SALARY CREDIT - ACME CORP - JUNE 2024"): keep
+    // the words, minus dates and reference codes
+    var words []string
+    for _, seg := range segs {
+        if m := merchantWords(seg); m != "" {
+            words = append(words, m)
+        }
+    }
+    if len(words) == 0 {
+        return "UNKNOWN"
+    }
+    return strings.Join(words, " ")
+}
+
+type merchantTotal struct {
+    Merchant    string  `json:"merchant"`
+    Count       int     `json:"count"`
+    TotalDebit  float64 `json:"totalDebit"`
+    TotalCredit float64 `json:"totalCredit"`
+    // plainDebit is TotalDebit without installments (EMIs, SIPs, RDs),
+    // i.e. the spending that could be cut.
+    plainDebit float64
+}
+
+func bankTransactionsByMerchantHandler(w http.ResponseWriter, r *http.Request) {
+    phone := r.Context().Value("phone").(string)
+    bt, err := loadBankTransactions(r.Context(), phone)
+    if err != nil {
+        http.Error(w, "data not found", http.StatusInternalServerError)
+        return
+    }
+    writeJSON(w, map[string]interface{}{"merchants": merchantTotals(bt)})
+}
+
+// merchantTotals sums every payment by merchantKey, most frequent first.
+func merchantTotals(bt *bankTransactionsFile) []merchantTotal {
+    byKey := map[string]*merchantTotal{}
+    for _, acc := range bt.BankTransactions {
+        for _, t := range acc.Txns {
+            if t.Type == bankTxnOpening || t.Type == bankTxnClosing {
+                continue // balance markers, not payments
+            }
+            key := merchantKey(t.Narration)
+            m, ok := byKey[key]
+            if !ok {
+                m = &merchantTotal{Merchant: key}
+                byKey[key] = m
+            }
+            m.Count++
+            if t.Type == bankTxnCredit || t.Type == bankTxnInterest {
+                m.TotalCredit += t.Amount
+            } else {
+                m.TotalDebit += t.Amount
+                if t.Type == bankTxnDebit {
+                    m.plainDebit += t.Amount
+                }
+            }
+        }
+    }
+    merchants := make([]merchantTotal, 0, len(byKey))
+    for _, m := range byKey {
+        m.TotalDebit, m.TotalCredit = round2(m.TotalDebit), round2(m.TotalCredit)
+        merchants = append(merchants, *m)
+    }
+    sort.Slice(merchants, func(i, j int) bool {
+        a, b := merchants[i], merchants[j]
+        if a.Count != b.Count {
+            return a.Count > b.Count
+        }
+        if a.TotalDebit != b.TotalDebit {
+            return a.TotalDebit > b.TotalDebit
+        }
+        return a.Merchant < b.Merchant
+    })
+    return merchants
+}
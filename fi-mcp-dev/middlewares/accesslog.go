@@ -0,0 +1,191 @@
+package middlewares
+
+import (
+    "bufio"
+    "context"
+    "crypto/rand"
+    "encoding/hex"
+    "log/slog"
+    "net"
+    "net/http"
+    "regexp"
+    "time"
+)
+
+// requestIDPattern is what a client-sent X-Request-ID must look like to be
+// kept; anything else is replaced so log lines stay one clean token.
+var requestIDPattern = regexp.MustCompile(`^[A-Za-z0-9._:-]{1,64}$`)
+
+type requestInfoKey struct{}
+
+// requestInfo is filled in as a request goes down the chain and read on the
+// way back up by AccessLog and Metrics.
+type requestInfo struct {
+    id      string
+    phone   string
+    pattern string
+}
+
+// SetRequestPhone records the authenticated phone of the request behind ctx
+// for its access log line and the per-persona metrics.
+func SetRequestPhone(ctx context.Context, phone string) {
+    if info, ok := ctx.Value(requestInfoKey{}).(*requestInfo); ok {
+        info.phone = phone
+    }
+}
+
+// RecordPattern notes the ServeMux pattern that matched, for the access log
+// and metrics. It must wrap the mux directly: the mux sets Pattern on the
+// request it is handed, and middleware in between passes it copies made by
+// WithContext, so the outer request never sees it.
+func RecordPattern(mux http.Handler) http.Handler {
+    return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+        // deferred so a handler that panics still has its route recorded
+        defer func() {
+            if info, ok := r.Context().Value(requestInfoKey{}).(*requestInfo); ok {
+                info.pattern = r.Pattern
+            }
+        }()
+        mux.ServeHTTP(w, r)
+    })
+}
+
+// requestPattern is what RecordPattern saw, or r.Pattern when it didn't run.
+func requestPattern(r *http.Request) string {
+    if info, ok := r.Context().Value(requestInfoKey{}).(*requestInfo); ok && info.pattern != "" {
+        return info.pattern
+    }
+    return r.Pattern
+}
+
+// RequestID returns the ID AccessLog gave the request behind ctx, or "".
+func RequestID(ctx context.Context) string {
+    if info, ok := ctx.Value(requestInfoKey{}).(*requestInfo); ok {
+        return info.id
+    }
+    return ""
+}
+
+func requestPhone(ctx context.Context) string {
+    if info, ok := ctx.Value(requestInfoKey{}).(*requestInfo); ok {
+        return info.phone
+    }
+    return ""
+}
+
+// AccessLog gives every request an ID, sent back as X-Request-ID (a sane one
+// from the client is kept), and with a non-nil logger writes one JSON line
+// per request when it ends; for streams that is when they close. The query
+// string is left out since it can carry stream tokens, and phones go through
+// phoneLabel.
+func AccessLog(logger *slog.Logger, phoneLabel func(string) string, next http.Handler) http.Handler {
+    return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+        id := r.Header.Get("X-Request-ID")
+        if !requestIDPattern.MatchString(id) {
+            id = newRequestID()
+        }
+        info := &requestInfo{id: id}
+        w.Header().Set("X-Request-ID", id)
+        r = r.WithContext(context.WithValue(r.Context(), requestInfoKey{}, info))
+
+        start := time.Now()
+        rec := recordResponse(w)
+        if logger == nil {
+            next.ServeHTTP(rec, r)
+            return
+        }
+        // logged from a defer so a request aborted with a panic (chaos
+        // truncation, a dropped stream) still gets its line
+        aborted := true
+        defer func() {
+            attrs := []slog.Attr{
+                slog.String("id", id),
+                slog.String("method", r.Method),
+                slog.String("path", r.URL.Path),
+                slog.String("pattern", requestPattern(r)),
+                slog.Int("status", rec.statusCode()),
+                slog.Int64("bytes", rec.bytes),
+                slog.Float64("duration_ms", float64(time.Since(start).Microseconds())/1000),
+                slog.String("remote", r.RemoteAddr),
+            }
+            if aborted {
+                attrs = append(attrs, slog.Bool("aborted", true))
+            }
+            if info.phone != "" {
+                attrs = append(attrs, slog.String("phone", phoneLabel(info.phone)))
+            }
+            logger.LogAttrs(r.Context(), slog.LevelInfo, "request", attrs...)
+        }()
+        next.ServeHTTP(rec, r)
+        aborted = false
+    })
+}
+
+func newRequestID() string {
+    b := make([]byte, 8)
+    rand.Read(b)
+    return hex.EncodeToString(b)
+}
+
+// statusRecorder notes the status and size of a response. Flush and Hijack
+// are methods rather than left to Unwrap because mcp-go and gorilla/websocket
+// type-assert for them.
+type statusRecorder struct {
+    http.ResponseWriter
+    status int
+    bytes  int64
+}
+
+// recordResponse wraps w, or returns it as is when an outer middleware
+// already did.
+func recordResponse(w http.ResponseWriter) *statusRecorder {
+    if rec, ok := w.(*statusRecorder); ok {
+        return rec
+    }
+    return &statusRecorder{ResponseWriter: w}
+}
+
+// statusCode is what the client got; net/http sends 200 for a handler that
+// wrote nothing.
+func (w *statusRecorder) statusCode() int {
+    if w.status == 0 {
+        return http.StatusOK
+    }
+    return w.status
+}
+
+func (w *statusRecorder) WriteHeader(code int) {
+    if w.status == 0 && code >= 200 {
+        w.status = code
+    }
+    w.ResponseWriter.WriteHeader(code)
+}
+
+func (w *statusRecorder) Write(b []byte) (int, error) {
+    if w.status == 0 {
+        w.status = http.StatusOK
+    }
+    n, err := w.ResponseWriter.Write(b)
+    w.bytes += int64(n)
+    return n, err
+}
+
+func (w *statusRecorder) Flush() {
+    if w.status == 0 {
+        w.status = http.StatusOK
+    }
+    http.NewResponseController(w.ResponseWriter).Flush()
+}
+
+func (w *statusRecorder) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+    conn, rw, err := http.NewResponseController(w.ResponseWriter).Hijack()
+    if err == nil {
+        w.status = http.StatusSwitchingProtocols
+    }
+    return conn, rw, err
+}
+
+// Unwrap lets http.ResponseController reach deadlines underneath.
+func (w *statusRecorder) Unwrap() http.ResponseWriter {
+    return w.ResponseWriter
+}
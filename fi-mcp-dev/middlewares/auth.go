@@ -1,20 +1,159 @@
 package middlewares
 
-// AuthMiddleware simply tracks sessionID→phoneNumber mappings.
+import (
+    "sync"
+    "time"
+)
+
+// DefaultSessionTTL is how long sessions from NewAuthMiddleware stay valid.
+const DefaultSessionTTL = 24 * time.Hour
+
+// AuthMiddleware simply tracks sessionID→phoneNumber mappings, each valid for
+// ttl after login, in a SessionStore. It is safe for concurrent use: logins
+// write while every authenticated request reads.
 type AuthMiddleware struct {
-    sessionStore map[string]string
+    ttl   time.Duration
+    now   func() time.Time // swapped out to test expiry without sleeping
+    store SessionStore
+    // Sliding renews a session's TTL when it is used; set it before serving.
+    // To keep store writes rare, a session is renewed once less than half
+    // its TTL is left.
+    Sliding bool
+
+    // pending holds logins still waiting on their MFA code. They are short
+    // lived, so they stay in memory whatever the store.
+    mu      sync.Mutex
+    pending map[string]*pendingSession
 }
 
+type pendingSession struct {
+    phoneNumber string
+    attempts    int
+    expiresAt   time.Time
+}
+
+// MaxVerifyAttempts is how many wrong codes a pending session survives.
+const MaxVerifyAttempts = 5
+
 func NewAuthMiddleware() *AuthMiddleware {
-    return &AuthMiddleware{sessionStore: make(map[string]string)}
+    return NewAuthMiddlewareWithTTL(DefaultSessionTTL)
+}
+
+// NewAuthMiddlewareWithTTL expires sessions ttl after they are added, and
+// starts a janitor that evicts expired ones so the store doesn't grow without
+// bound.
+func NewAuthMiddlewareWithTTL(ttl time.Duration) *AuthMiddleware {
+    return NewAuthMiddlewareWithStore(NewMemorySessionStore(), ttl)
+}
+
+// NewAuthMiddlewareWithStore is NewAuthMiddlewareWithTTL keeping its sessions
+// in store, e.g. a FileSessionStore that survives restarts.
+func NewAuthMiddlewareWithStore(store SessionStore, ttl time.Duration) *AuthMiddleware {
+    m := newAuthMiddleware(store, ttl, time.Now)
+    go m.janitor(min(ttl, time.Minute))
+    return m
+}
+
+func newAuthMiddleware(store SessionStore, ttl time.Duration, now func() time.Time) *AuthMiddleware {
+    return &AuthMiddleware{
+        ttl:     ttl,
+        now:     now,
+        store:   store,
+        pending: make(map[string]*pendingSession),
+    }
+}
+
+func (m *AuthMiddleware) janitor(every time.Duration) {
+    for range time.Tick(every) {
+        m.evictExpired()
+    }
+}
+
+// evictExpired drops every session and pending login past its expiry.
+func (m *AuthMiddleware) evictExpired() {
+    now := m.now()
+    m.store.DeleteExpired(now)
+    m.mu.Lock()
+    defer m.mu.Unlock()
+    for id, p := range m.pending {
+        if !now.Before(p.expiresAt) {
+            delete(m.pending, id)
+        }
+    }
 }
 
 // AddSession registers a session.
 func (m *AuthMiddleware) AddSession(sessionID, phoneNumber string) {
-    m.sessionStore[sessionID] = phoneNumber
+    m.mu.Lock()
+    delete(m.pending, sessionID)
+    m.mu.Unlock()
+    m.store.Add(sessionID, Session{PhoneNumber: phoneNumber, ExpiresAt: m.now().Add(m.ttl)})
+}
+
+// RemoveSession forgets a session, pending or not; unknown IDs are a no-op.
+func (m *AuthMiddleware) RemoveSession(sessionID string) {
+    m.mu.Lock()
+    delete(m.pending, sessionID)
+    m.mu.Unlock()
+    m.store.Remove(sessionID)
+}
+
+// AddPendingSession registers a session that only becomes valid once
+// VerifySession accepts it.
+func (m *AuthMiddleware) AddPendingSession(sessionID, phoneNumber string) {
+    m.store.Remove(sessionID)
+    m.mu.Lock()
+    defer m.mu.Unlock()
+    m.pending[sessionID] = &pendingSession{phoneNumber: phoneNumber, expiresAt: m.now().Add(m.ttl)}
 }
 
-// GetPhoneNumber looks up the phone for a sessionID (or "" if none).
+// IsPending reports whether sessionID is waiting on MFA.
+func (m *AuthMiddleware) IsPending(sessionID string) bool {
+    m.mu.Lock()
+    defer m.mu.Unlock()
+    p := m.pending[sessionID]
+    return p != nil && m.now().Before(p.expiresAt)
+}
+
+// VerifySession promotes a pending session when ok, returning its phone. A
+// failed attempt counts against the session, which is dropped after
+// MaxVerifyAttempts; found is false once there is nothing to verify.
+func (m *AuthMiddleware) VerifySession(sessionID string, ok bool) (phoneNumber string, found bool) {
+    m.mu.Lock()
+    p := m.pending[sessionID]
+    if p == nil || !m.now().Before(p.expiresAt) {
+        m.mu.Unlock()
+        return "", false
+    }
+    if !ok {
+        if p.attempts++; p.attempts >= MaxVerifyAttempts {
+            delete(m.pending, sessionID)
+        }
+        m.mu.Unlock()
+        return "", true
+    }
+    delete(m.pending, sessionID)
+    m.mu.Unlock()
+    m.store.Add(sessionID, Session{PhoneNumber: p.phoneNumber, ExpiresAt: m.now().Add(m.ttl)})
+    return p.phoneNumber, true
+}
+
+// GetPhoneNumber looks up the phone for a sessionID (or "" if none or
+// expired), renewing the session when Sliding is on.
 func (m *AuthMiddleware) GetPhoneNumber(sessionID string) string {
-    return m.sessionStore[sessionID]
+    s, ok := m.store.Get(sessionID)
+    now := m.now()
+    if !ok || !now.Before(s.ExpiresAt) {
+        return ""
+    }
+    if m.Sliding && s.ExpiresAt.Sub(now) < m.ttl/2 {
+        m.store.Refresh(sessionID, now.Add(m.ttl))
+    }
+    return s.PhoneNumber
+}
+
+// SessionCount reports how many sessions are live. Expired ones waiting for
+// the janitor are left out, as GetPhoneNumber already treats them as gone.
+func (m *AuthMiddleware) SessionCount() int {
+    return m.store.Len(m.now())
 }
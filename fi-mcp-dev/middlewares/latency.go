@@ -0,0 +1,77 @@
+package middlewares
+
+import (
+    "bufio"
+    "net"
+    "net/http"
+    "strconv"
+    "time"
+)
+
+// Latency answers requests carrying X-Latency-Budget-Ms with
+// X-Latency-Used-Ms and X-Latency-Budget-Met. Headers can't change once the
+// body starts, so the time is measured up to the handler's first write (for
+// SSE, until the stream opens).
+func Latency(next http.Handler) http.Handler {
+    return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+        h := r.Header.Get("X-Latency-Budget-Ms")
+        if h == "" {
+            next.ServeHTTP(w, r)
+            return
+        }
+        budget, err := strconv.ParseFloat(h, 64)
+        if err != nil || budget < 0 {
+            http.Error(w, "invalid X-Latency-Budget-Ms", http.StatusBadRequest)
+            return
+        }
+        lw := &latencyWriter{ResponseWriter: w, start: time.Now(), budget: budget}
+        next.ServeHTTP(lw, r)
+        lw.stamp() // handler wrote nothing; net/http sends the headers now
+    })
+}
+
+type latencyWriter struct {
+    http.ResponseWriter
+    start   time.Time
+    budget  float64
+    stamped bool
+}
+
+func (w *latencyWriter) stamp() {
+    if w.stamped {
+        return
+    }
+    w.stamped = true
+    used := float64(time.Since(w.start).Microseconds()) / 1000
+    w.Header().Set("X-Latency-Used-Ms", strconv.FormatFloat(used, 'f', 3, 64))
+    w.Header().Set("X-Latency-Budget-Met", strconv.FormatBool(used <= w.budget))
+}
+
+func (w *latencyWriter) WriteHeader(code int) {
+    w.stamp()
+    w.ResponseWriter.WriteHeader(code)
+}
+
+func (w *latencyWriter) Write(b []byte) (int, error) {
+    w.stamp()
+    return w.ResponseWriter.Write(b)
+}
+
+// FlushError stamps before a flush sends the headers; ResponseController
+// prefers it over unwrapping.
+func (w *latencyWriter) FlushError() error {
+    w.stamp()
+    return http.NewResponseController(w.ResponseWriter).Flush()
+}
+
+// Unwrap lets http.ResponseController reach Flush and deadlines underneath.
+func (w *latencyWriter) Unwrap() http.ResponseWriter {
+    return w.ResponseWriter
+}
+
+// Hijack lets WebSocket upgrades through; the budget is measured up to the
+// upgrade.
+func (w *latencyWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+    w.stamp()
+    return http.NewResponseController(w.ResponseWriter).Hijack()
+}
@@ -0,0 +1,230 @@
+package middlewares
+
+import (
+    "fmt"
+    "io"
+    "net/http"
+    "sort"
+    "strconv"
+    "strings"
+    "sync"
+    "time"
+)
+
+// Stream kinds for StreamStarted.
+const (
+    StreamSSE       = "sse"
+    StreamWebSocket = "ws"
+)
+
+// durationBuckets are the Prometheus client defaults, in seconds.
+var durationBuckets = []float64{0.005, 0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10}
+
+// Metrics keeps the process-wide counters shown by the stats endpoints and
+// /metrics.
+type Metrics struct {
+    mu            sync.Mutex
+    requests      map[string]uint64
+    responses     map[responseKey]uint64
+    durations     map[string]*histogram
+    personaHits   map[string]uint64
+    activeStreams map[string]int64
+    draining      bool // shutting down: open streams are being drained
+    phoneLabel    func(string) string
+}
+
+type responseKey struct{ pattern, method, code string }
+
+type histogram struct {
+    counts []uint64 // per bucket, not cumulative; the last is +Inf
+    sum    float64
+    count  uint64
+}
+
+// NewMetrics labels persona hits with phoneLabel(phone), so metrics follow
+// the same phone hygiene as the logs.
+func NewMetrics(phoneLabel func(string) string) *Metrics {
+    return &Metrics{
+        requests:      make(map[string]uint64),
+        responses:     make(map[responseKey]uint64),
+        durations:     make(map[string]*histogram),
+        personaHits:   make(map[string]uint64),
+        activeStreams: make(map[string]int64),
+        phoneLabel:    phoneLabel,
+    }
+}
+
+// codeAborted is the status label of a request whose handler panicked, e.g.
+// with http.ErrAbortHandler to cut the connection.
+const codeAborted = "aborted"
+
+// Wrap counts every request by its ServeMux pattern (not the raw path, so
+// arbitrary URLs can't grow the maps), status and duration, and by persona
+// once auth has named one. A stream's duration is how long it stayed open.
+// The pattern comes from RecordPattern. Counting is deferred so aborted
+// requests are counted too, under the code "aborted".
+func (m *Metrics) Wrap(next http.Handler) http.Handler {
+    return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+        start := time.Now()
+        rec := recordResponse(w)
+        aborted := true
+        defer func() {
+            code := strconv.Itoa(rec.statusCode())
+            if aborted {
+                code = codeAborted
+            }
+            m.record(r, code, time.Since(start).Seconds())
+        }()
+        next.ServeHTTP(rec, r)
+        aborted = false
+    })
+}
+
+func (m *Metrics) record(r *http.Request, code string, elapsed float64) {
+    pattern := requestPattern(r)
+    if pattern == "" {
+        pattern = "unmatched"
+    }
+    phone := requestPhone(r.Context())
+
+    m.mu.Lock()
+    defer m.mu.Unlock()
+    m.requests[pattern]++
+    m.responses[responseKey{pattern, r.Method, code}]++
+    h := m.durations[pattern]
+    if h == nil {
+        h = &histogram{counts: make([]uint64, len(durationBuckets)+1)}
+        m.durations[pattern] = h
+    }
+    h.counts[sort.SearchFloat64s(durationBuckets, elapsed)]++
+    h.sum += elapsed
+    h.count++
+    if phone != "" {
+        m.personaHits[m.phoneLabel(phone)]++
+    }
+}
+
+// StreamStarted records an open stream of the given kind; call the returned
+// func when it ends.
+func (m *Metrics) StreamStarted(kind string) func() {
+    m.mu.Lock()
+    m.activeStreams[kind]++
+    m.mu.Unlock()
+    return func() {
+        m.mu.Lock()
+        m.activeStreams[kind]--
+        m.mu.Unlock()
+    }
+}
+
+// ActiveStreams returns the number of open streams of every kind.
+func (m *Metrics) ActiveStreams() int64 {
+    m.mu.Lock()
+    defer m.mu.Unlock()
+    return m.activeStreamsLocked()
+}
+
+func (m *Metrics) activeStreamsLocked() int64 {
+    var n int64
+    for _, v := range m.activeStreams {
+        n += v
+    }
+    return n
+}
+
+// StartDraining marks the server as shutting down; from then on /metrics
+// reports the streams still open as draining.
+func (m *Metrics) StartDraining() {
+    m.mu.Lock()
+    m.draining = true
+    m.mu.Unlock()
+}
+
+// Requests returns a copy of the per-pattern request counts.
+func (m *Metrics) Requests() map[string]uint64 {
+    m.mu.Lock()
+    defer m.mu.Unlock()
+    out := make(map[string]uint64, len(m.requests))
+    for k, v := range m.requests {
+        out[k] = v
+    }
+    return out
+}
+
+// ServeHTTP writes the metrics in the Prometheus text format.
+func (m *Metrics) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+    w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+    m.mu.Lock()
+    defer m.mu.Unlock()
+
+    writeFamily(w, "fi_mcp_http_requests_total", "counter", "HTTP requests by route pattern, method and status code.")
+    keys := make([]responseKey, 0, len(m.responses))
+    for k := range m.responses {
+        keys = append(keys, k)
+    }
+    sort.Slice(keys, func(i, j int) bool {
+        a, b := keys[i], keys[j]
+        if a.pattern != b.pattern {
+            return a.pattern < b.pattern
+        }
+        if a.method != b.method {
+            return a.method < b.method
+        }
+        return a.code < b.code
+    })
+    for _, k := range keys {
+        fmt.Fprintf(w, "fi_mcp_http_requests_total{pattern=%s,method=%s,code=%s} %d\n",
+            quoteLabel(k.pattern), quoteLabel(k.method), quoteLabel(k.code), m.responses[k])
+    }
+
+    writeFamily(w, "fi_mcp_http_request_duration_seconds", "histogram", "HTTP request durations by route pattern; for streams, how long they stayed open.")
+    for _, pattern := range sortedKeys(m.durations) {
+        h := m.durations[pattern]
+        label := quoteLabel(pattern)
+        var cum uint64
+        for i, le := range durationBuckets {
+            cum += h.counts[i]
+            fmt.Fprintf(w, "fi_mcp_http_request_duration_seconds_bucket{pattern=%s,le=\"%s\"} %d\n",
+                label, strconv.FormatFloat(le, 'g', -1, 64), cum)
+        }
+        fmt.Fprintf(w, "fi_mcp_http_request_duration_seconds_bucket{pattern=%s,le=\"+Inf\"} %d\n", label, h.count)
+        fmt.Fprintf(w, "fi_mcp_http_request_duration_seconds_sum{pattern=%s} %s\n", label, strconv.FormatFloat(h.sum, 'g', -1, 64))
+        fmt.Fprintf(w, "fi_mcp_http_request_duration_seconds_count{pattern=%s} %d\n", label, h.count)
+    }
+
+    writeFamily(w, "fi_mcp_active_streams", "gauge", "Open SSE and WebSocket streams.")
+    for _, kind := range []string{StreamSSE, StreamWebSocket} {
+        fmt.Fprintf(w, "fi_mcp_active_streams{transport=%s} %d\n", quoteLabel(kind), m.activeStreams[kind])
+    }
+
+    writeFamily(w, "fi_mcp_draining_streams", "gauge", "Streams still open during graceful shutdown; 0 while serving.")
+    var draining int64
+    if m.draining {
+        draining = m.activeStreamsLocked()
+    }
+    fmt.Fprintf(w, "fi_mcp_draining_streams %d\n", draining)
+
+    writeFamily(w, "fi_mcp_persona_hits_total", "counter", "Authenticated requests by persona phone.")
+    for _, phone := range sortedKeys(m.personaHits) {
+        fmt.Fprintf(w, "fi_mcp_persona_hits_total{phone=%s} %d\n", quoteLabel(phone), m.personaHits[phone])
+    }
+}
+
+func writeFamily(w io.Writer, name, kind, help string) {
+    fmt.Fprintf(w, "# HELP %s %s\n# TYPE %s %s\n", name, help, name, kind)
+}
+
+var labelEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+
+func quoteLabel(v string) string {
+    return `"` + labelEscaper.Replace(v) + `"`
+}
+
+func sortedKeys[V any](m map[string]V) []string {
+    keys := make([]string, 0, len(m))
+    for k := range m {
+        keys = append(keys, k)
+    }
+    sort.Strings(keys)
+    return keys
+}
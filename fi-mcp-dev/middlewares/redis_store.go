@@ -0,0 +1,112 @@
+package middlewares
+
+import (
+    "context"
+    "encoding/json"
+    "errors"
+    "fmt"
+    "log"
+    "time"
+
+    "github.com/redis/go-redis/v9"
+)
+
+// ————— Redis store —————
+
+const (
+    redisKeyPrefix = "fi-mcp:session:"
+    redisTimeout   = 2 * time.Second
+)
+
+// RedisSessionStore shares sessions between replicas. Each key expires with
+// its session, so DeleteExpired has nothing to do. Redis errors are logged
+// and the session treated as missing.
+type RedisSessionStore struct {
+    client *redis.Client
+}
+
+// NewRedisSessionStore connects to url ("redis://host:6379/0") and checks
+// the server answers.
+func NewRedisSessionStore(url string) (*RedisSessionStore, error) {
+    opts, err := redis.ParseURL(url)
+    if err != nil {
+        return nil, err
+    }
+    client := redis.NewClient(opts)
+    ctx, cancel := context.WithTimeout(context.Background(), redisTimeout)
+    defer cancel()
+    if err := client.Ping(ctx).Err(); err != nil {
+        client.Close()
+        return nil, fmt.Errorf("redis %s: %w", opts.Addr, err)
+    }
+    return &RedisSessionStore{client: client}, nil
+}
+
+func (s *RedisSessionStore) Add(sessionID string, sess Session) {
+    ctx, cancel := context.WithTimeout(context.Background(), redisTimeout)
+    defer cancel()
+    data, _ := json.Marshal(sess)
+    if err := s.client.SetArgs(ctx, redisKeyPrefix+sessionID, data, redis.SetArgs{ExpireAt: sess.ExpiresAt}).Err(); err != nil {
+        log.Printf("session store redis: add: %v", err)
+    }
+}
+
+func (s *RedisSessionStore) Get(sessionID string) (Session, bool) {
+    ctx, cancel := context.WithTimeout(context.Background(), redisTimeout)
+    defer cancel()
+    data, err := s.client.Get(ctx, redisKeyPrefix+sessionID).Bytes()
+    if err != nil {
+        if !errors.Is(err, redis.Nil) {
+            log.Printf("session store redis: get: %v", err)
+        }
+        return Session{}, false
+    }
+    var sess Session
+    if err := json.Unmarshal(data, &sess); err != nil {
+        return Session{}, false
+    }
+    return sess, true
+}
+
+func (s *RedisSessionStore) Remove(sessionID string) {
+    ctx, cancel := context.WithTimeout(context.Background(), redisTimeout)
+    defer cancel()
+    if err := s.client.Del(ctx, redisKeyPrefix+sessionID).Err(); err != nil {
+        log.Printf("session store redis: remove: %v", err)
+    }
+}
+
+// Refresh rewrites the session only if its key still exists (SET XX), so a
+// logout on another replica is not undone.
+func (s *RedisSessionStore) Refresh(sessionID string, expiresAt time.Time) {
+    sess, ok := s.Get(sessionID)
+    if !ok {
+        return
+    }
+    sess.ExpiresAt = expiresAt
+    ctx, cancel := context.WithTimeout(context.Background(), redisTimeout)
+    defer cancel()
+    data, _ := json.Marshal(sess)
+    err := s.client.SetArgs(ctx, redisKeyPrefix+sessionID, data, redis.SetArgs{Mode: "XX", ExpireAt: expiresAt}).Err()
+    if err != nil && !errors.Is(err, redis.Nil) {
+        log.Printf("session store redis: refresh: %v", err)
+    }
+}
+
+// Len scans the session keys; it is only used for stats. Redis expires the
+// keys itself, so every key found is live.
+func (s *RedisSessionStore) Len(time.Time) int {
+    ctx, cancel := context.WithTimeout(context.Background(), redisTimeout)
+    defer cancel()
+    n := 0
+    iter := s.client.Scan(ctx, 0, redisKeyPrefix+"*", 100).Iterator()
+    for iter.Next(ctx) {
+        n++
+    }
+    if err := iter.Err(); err != nil {
+        log.Printf("session store redis: len: %v", err)
+    }
+    return n
+}
+
+func (s *RedisSessionStore) DeleteExpired(time.Time) {}
@@ -0,0 +1,194 @@
+package middlewares
+
+import (
+    "encoding/json"
+    "errors"
+    "log"
+    "os"
+    "path/filepath"
+    "sync"
+    "time"
+)
+
+// Session is what a SessionStore keeps per session ID.
+type Session struct {
+    PhoneNumber string    `json:"phoneNumber"`
+    ExpiresAt   time.Time `json:"expiresAt"`
+}
+
+// SessionStore holds AuthMiddleware's sessions. Implementations must be safe
+// for concurrent use. Expiry is checked by AuthMiddleware; DeleteExpired only
+// reclaims space.
+type SessionStore interface {
+    Add(sessionID string, s Session)
+    Get(sessionID string) (Session, bool)
+    Remove(sessionID string)
+    // Refresh moves an existing session's expiry; unknown IDs are a no-op,
+    // so a session removed meanwhile is not brought back.
+    Refresh(sessionID string, expiresAt time.Time)
+    // Len counts the sessions still live at now.
+    Len(now time.Time) int
+    DeleteExpired(now time.Time)
+}
+
+// ————— in-memory store —————
+
+// MemorySessionStore is the default store; sessions are lost on restart.
+type MemorySessionStore struct {
+    mu       sync.RWMutex
+    sessions map[string]Session
+}
+
+func NewMemorySessionStore() *MemorySessionStore {
+    return &MemorySessionStore{sessions: make(map[string]Session)}
+}
+
+func (m *MemorySessionStore) Add(sessionID string, s Session) {
+    m.mu.Lock()
+    defer m.mu.Unlock()
+    m.sessions[sessionID] = s
+}
+
+func (m *MemorySessionStore) Get(sessionID string) (Session, bool) {
+    m.mu.RLock()
+    defer m.mu.RUnlock()
+    s, ok := m.sessions[sessionID]
+    return s, ok
+}
+
+func (m *MemorySessionStore) Remove(sessionID string) {
+    m.mu.Lock()
+    defer m.mu.Unlock()
+    delete(m.sessions, sessionID)
+}
+
+func (m *MemorySessionStore) Refresh(sessionID string, expiresAt time.Time) {
+    m.mu.Lock()
+    defer m.mu.Unlock()
+    if s, ok := m.sessions[sessionID]; ok {
+        s.ExpiresAt = expiresAt
+        m.sessions[sessionID] = s
+    }
+}
+
+func (m *MemorySessionStore) Len(now time.Time) int {
+    m.mu.RLock()
+    defer m.mu.RUnlock()
+    return liveSessions(m.sessions, now)
+}
+
+func (m *MemorySessionStore) DeleteExpired(now time.Time) {
+    m.mu.Lock()
+    defer m.mu.Unlock()
+    for id, s := range m.sessions {
+        if !now.Before(s.ExpiresAt) {
+            delete(m.sessions, id)
+        }
+    }
+}
+
+func liveSessions(sessions map[string]Session, now time.Time) int {
+    n := 0
+    for _, s := range sessions {
+        if now.Before(s.ExpiresAt) {
+            n++
+        }
+    }
+    return n
+}
+
+// ————— file store —————
+
+// FileSessionStore keeps sessions in memory and rewrites them to a JSON file
+// on every change, so they survive a restart. Write errors are logged; the
+// in-memory copy stays authoritative.
+type FileSessionStore struct {
+    mu       sync.Mutex
+    path     string
+    sessions map[string]Session
+}
+
+// NewFileSessionStore loads path if it exists; a missing file starts empty.
+func NewFileSessionStore(path string) (*FileSessionStore, error) {
+    f := &FileSessionStore{path: path, sessions: make(map[string]Session)}
+    data, err := os.ReadFile(path)
+    if errors.Is(err, os.ErrNotExist) {
+        return f, nil
+    }
+    if err != nil {
+        return nil, err
+    }
+    if err := json.Unmarshal(data, &f.sessions); err != nil {
+        return nil, err
+    }
+    return f, nil
+}
+
+func (f *FileSessionStore) Add(sessionID string, s Session) {
+    f.mu.Lock()
+    defer f.mu.Unlock()
+    f.sessions[sessionID] = s
+    f.save()
+}
+
+func (f *FileSessionStore) Get(sessionID string) (Session, bool) {
+    f.mu.Lock()
+    defer f.mu.Unlock()
+    s, ok := f.sessions[sessionID]
+    return s, ok
+}
+
+func (f *FileSessionStore) Remove(sessionID string) {
+    f.mu.Lock()
+    defer f.mu.Unlock()
+    if _, ok := f.sessions[sessionID]; ok {
+        delete(f.sessions, sessionID)
+        f.save()
+    }
+}
+
+func (f *FileSessionStore) Refresh(sessionID string, expiresAt time.Time) {
+    f.mu.Lock()
+    defer f.mu.Unlock()
+    if s, ok := f.sessions[sessionID]; ok {
+        s.ExpiresAt = expiresAt
+        f.sessions[sessionID] = s
+        f.save()
+    }
+}
+
+func (f *FileSessionStore) Len(now time.Time) int {
+    f.mu.Lock()
+    defer f.mu.Unlock()
+    return liveSessions(f.sessions, now)
+}
+
+func (f *FileSessionStore) DeleteExpired(now time.Time) {
+    f.mu.Lock()
+    defer f.mu.Unlock()
+    changed := false
+    for id, s := range f.sessions {
+        if !now.Before(s.ExpiresAt) {
+            delete(f.sessions, id)
+            changed = true
+        }
+    }
+    if changed {
+        f.save()
+    }
+}
+
+// save writes through a temp file and rename so a crash never leaves a
+// half-written file. Callers hold f.mu.
+func (f *FileSessionStore) save() {
+    data, err := json.Marshal(f.sessions)
+    if err == nil {
+        tmp := f.path + ".tmp"
+        if err = os.WriteFile(tmp, data, 0o600); err == nil {
+            err = os.Rename(tmp, f.path)
+        }
+    }
+    if err != nil {
+        log.Printf("session store %s: %v", filepath.Base(f.path), err)
+    }
+}
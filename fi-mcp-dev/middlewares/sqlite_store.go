@@ -0,0 +1,86 @@
+package middlewares
+
+import (
+    "database/sql"
+    "log"
+    "time"
+
+    _ "modernc.org/sqlite"
+)
+
+// ————— SQLite store —————
+
+// SQLiteSessionStore keeps sessions in a SQLite database file, which
+// survives restarts and can be shared by replicas on the same host or
+// volume. Query errors are logged and the session treated as missing.
+type SQLiteSessionStore struct {
+    db *sql.DB
+}
+
+// NewSQLiteSessionStore opens (creating if needed) the database at path.
+func NewSQLiteSessionStore(path string) (*SQLiteSessionStore, error) {
+    db, err := sql.Open("sqlite", "file:"+path+"?_pragma=busy_timeout(5000)&_pragma=journal_mode(WAL)")
+    if err != nil {
+        return nil, err
+    }
+    db.SetMaxOpenConns(1) // one writer at a time; SQLite locks the file anyway
+    _, err = db.Exec(`CREATE TABLE IF NOT EXISTS sessions (
+        id         TEXT PRIMARY KEY,
+        phone      TEXT NOT NULL,
+        expires_at INTEGER NOT NULL
+    )`)
+    if err != nil {
+        db.Close()
+        return nil, err
+    }
+    return &SQLiteSessionStore{db: db}, nil
+}
+
+func (s *SQLiteSessionStore) Add(sessionID string, sess Session) {
+    _, err := s.db.Exec(`INSERT INTO sessions (id, phone, expires_at) VALUES (?, ?, ?)
+        ON CONFLICT(id) DO UPDATE SET phone = excluded.phone, expires_at = excluded.expires_at`,
+        sessionID, sess.PhoneNumber, sess.ExpiresAt.UnixNano())
+    if err != nil {
+        log.Printf("session store sqlite: add: %v", err)
+    }
+}
+
+func (s *SQLiteSessionStore) Get(sessionID string) (Session, bool) {
+    var sess Session
+    var exp int64
+    err := s.db.QueryRow(`SELECT phone, expires_at FROM sessions WHERE id = ?`, sessionID).Scan(&sess.PhoneNumber, &exp)
+    if err != nil {
+        if err != sql.ErrNoRows {
+            log.Printf("session store sqlite: get: %v", err)
+        }
+        return Session{}, false
+    }
+    sess.ExpiresAt = time.Unix(0, exp)
+    return sess, true
+}
+
+func (s *SQLiteSessionStore) Remove(sessionID string) {
+    if _, err := s.db.Exec(`DELETE FROM sessions WHERE id = ?`, sessionID); err != nil {
+        log.Printf("session store sqlite: remove: %v", err)
+    }
+}
+
+func (s *SQLiteSessionStore) Refresh(sessionID string, expiresAt time.Time) {
+    if _, err := s.db.Exec(`UPDATE sessions SET expires_at = ? WHERE id = ?`, expiresAt.UnixNano(), sessionID); err != nil {
+        log.Printf("session store sqlite: refresh: %v", err)
+    }
+}
+
+func (s *SQLiteSessionStore) Len(now time.Time) int {
+    var n int
+    if err := s.db.QueryRow(`SELECT COUNT(*) FROM sessions WHERE expires_at > ?`, now.UnixNano()).Scan(&n); err != nil {
+        log.Printf("session store sqlite: len: %v", err)
+    }
+    return n
+}
+
+func (s *SQLiteSessionStore) DeleteExpired(now time.Time) {
+    if _, err := s.db.Exec(`DELETE FROM sessions WHERE expires_at <= ?`, now.UnixNano()); err != nil {
+        log.Printf("session store sqlite: delete expired: %v", err)
+    }
+}
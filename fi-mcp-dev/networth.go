@@ -0,0 +1,222 @@
+package main
+
+import (
+    "context"
+    "encoding/json"
+    "log"
+    "math"
+    "net/http"
+    "time"
+
+    "github.com/epifi/fi-mcp-lite/middlewares"
+)
+
+// ————— net worth analytics —————
+
+// netWorthPercentileHandler ranks the caller's net worth against every
+// allowed phone that has a net worth fixture.
+func netWorthPercentileHandler(w http.ResponseWriter, r *http.Request) {
+    phone := r.Context().Value("phone").(string)
+    own, err := loadNetWorth(r.Context(), phone)
+    if err != nil {
+        http.Error(w, "data not found", http.StatusInternalServerError)
+        return
+    }
+    value := own.NetWorthResponse.TotalNetWorthValue.Float()
+
+    var cohort []float64
+    for _, p := range allowedMobileNumbers() {
+        if p == phone {
+            continue
+        }
+        nw, err := loadNetWorth(r.Context(), p)
+        if err != nil {
+            continue
+        }
+        cohort = append(cohort, nw.NetWorthResponse.TotalNetWorthValue.Float())
+    }
+
+    writeJSON(w, map[string]interface{}{
+        "netWorth":   value,
+        "percentile": percentileRank(value, cohort),
+        "cohortSize": len(cohort) + 1,
+    })
+}
+
+// percentileRank returns the share of others below value, counting ties as
+// half. With nobody to compare against the caller is at the 100th percentile.
+func percentileRank(value float64, others []float64) float64 {
+    if len(others) == 0 {
+        return 100
+    }
+    var below, ties float64
+    for _, o := range others {
+        switch {
+        case o < value:
+            below++
+        case o == value:
+            ties++
+        }
+    }
+    return round2(100 * (below + ties/2) / float64(len(others)))
+}
+
+// netWorthTicker streams just the net worth total as {value, delta, ts} for
+// small live widgets, every defaultInterval or ?interval=.
+func netWorthTicker(defaultInterval time.Duration) http.Handler {
+    return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+        phone := r.Context().Value("phone").(string)
+        interval, err := streamInterval(r.URL.Query(), defaultInterval)
+        if err != nil {
+            http.Error(w, err.Error(), http.StatusBadRequest)
+            return
+        }
+        rc, ok := startSSE(w, r)
+        if !ok {
+            return
+        }
+        defer metrics.StreamStarted(middlewares.StreamSSE)()
+        ticker := time.NewTicker(interval)
+        defer ticker.Stop()
+        ka := newKeepAlive()
+        defer ka.stop()
+
+        var last float64
+        first := true
+        for {
+            select {
+            case <-r.Context().Done():
+                return
+            case <-ka.C():
+                if !ka.send(w, rc) {
+                    return
+                }
+            case now := <-ticker.C:
+                nw, err := loadNetWorth(r.Context(), phone)
+                if err != nil {
+                    log.Printf("read error: phone=%s file=fetch_net_worth.json: %v", logPhone(phone), readErr(err))
+                    continue
+                }
+                value := nw.NetWorthResponse.TotalNetWorthValue.Float()
+                delta := 0.0
+                if !first {
+                    delta = value - last
+                }
+                last, first = value, false
+                data, _ := json.Marshal(map[string]interface{}{
+                    "value": value,
+                    "delta": round2(delta),
+                    "ts":    now.UTC().Format(time.RFC3339),
+                })
+                writeEvent(w, data)
+                if err := rc.Flush(); err != nil {
+                    return
+                }
+                ka.reset()
+            }
+        }
+    })
+}
+
+// ————— net worth by account type —————
+// Each account type is rebuilt from its most detailed source and compared
+// with the headline figure in fetch_net_worth.json. A difference above
+// ₹1 or 0.5% (whichever is larger) is flagged.
+
+type accountTypeTotal struct {
+    AccountType string   `json:"accountType"`
+    Source      string   `json:"source"`
+    Computed    *float64 `json:"computed"` // nil when the source is missing or empty
+    Reported    float64  `json:"reported"`
+    Difference  float64  `json:"difference"`
+    Discrepancy bool     `json:"discrepancy"`
+}
+
+func netWorthByAccountTypeHandler(w http.ResponseWriter, r *http.Request) {
+    phone := r.Context().Value("phone").(string)
+    nw, err := loadNetWorth(r.Context(), phone)
+    if err != nil {
+        http.Error(w, "data not found", http.StatusInternalServerError)
+        return
+    }
+
+    rows := []accountTypeTotal{
+        {AccountType: "ASSET_TYPE_SAVINGS_ACCOUNTS", Source: "fetch_bank_transactions.json", Computed: savingsFromBank(r.Context(), phone)},
+        {AccountType: "ASSET_TYPE_MUTUAL_FUND", Source: "fetch_net_worth.json#mfSchemeAnalytics", Computed: mutualFundsFromSchemes(nw)},
+        {AccountType: "ASSET_TYPE_INDIAN_SECURITIES", Source: "fetch_net_worth.json#accountDetailsBulkResponse", Computed: equitiesFromAccounts(nw)},
+        {AccountType: "ASSET_TYPE_EPF", Source: "fetch_epf_details.json", Computed: epfFromDetails(r.Context(), phone)},
+    }
+    var total, reported float64
+    consistent := true
+    for i := range rows {
+        row := &rows[i]
+        row.Reported = nw.attribute(row.AccountType)
+        reported += row.Reported
+        if row.Computed != nil {
+            // without a source the reported value can't be checked, which
+            // is not the same as it being wrong
+            total += *row.Computed
+            row.Difference = round2(*row.Computed - row.Reported)
+            row.Discrepancy = math.Abs(row.Difference) > math.Max(1, 0.005*math.Abs(row.Reported))
+        }
+        consistent = consistent && !row.Discrepancy
+    }
+    writeJSON(w, map[string]interface{}{
+        "breakdown":     rows,
+        "total":         round2(total),
+        "reportedTotal": round2(reported),
+        "consistent":    consistent,
+    })
+}
+
+func savingsFromBank(ctx context.Context, phone string) *float64 {
+    bt, err := loadBankTransactions(ctx, phone)
+    if err != nil || len(bt.BankTransactions) == 0 {
+        return nil
+    }
+    var sum float64
+    for _, b := range bt.latestBalances() {
+        sum += b
+    }
+    return &sum
+}
+
+func mutualFundsFromSchemes(nw *netWorthFile) *float64 {
+    schemes := nw.MfSchemeAnalytics.SchemeAnalytics
+    if len(schemes) == 0 {
+        return nil
+    }
+    var sum float64
+    for _, s := range schemes {
+        sum += s.EnrichedAnalytics.Analytics.SchemeDetails.CurrentValue.Float()
+    }
+    sum = round2(sum)
+    return &sum
+}
+
+func equitiesFromAccounts(nw *netWorthFile) *float64 {
+    var sum float64
+    found := false
+    for _, acc := range nw.AccountDetailsBulkResponse.AccountDetailsMap {
+        if acc.AccountDetails.AccInstrumentType == "ACC_INSTRUMENT_TYPE_EQUITIES" && acc.EquitySummary != nil {
+            sum += acc.EquitySummary.CurrentValue.Float()
+            found = true
+        }
+    }
+    if !found {
+        return nil
+    }
+    return &sum
+}
+
+func epfFromDetails(ctx context.Context, phone string) *float64 {
+    epf, err := loadEPFDetails(ctx, phone)
+    if err != nil || len(epf.UanAccounts) == 0 {
+        return nil
+    }
+    var sum float64
+    for _, u := range epf.UanAccounts {
+        sum += toFloat(u.RawDetails.OverallPfBalance.CurrentPfBalance)
+    }
+    return &sum
+}
@@ -0,0 +1,325 @@
+package main
+
+import (
+    "crypto/rand"
+    "crypto/sha256"
+    "crypto/subtle"
+    "encoding/base64"
+    "encoding/hex"
+    "html/template"
+    "log"
+    "net/http"
+    "net/url"
+    "strings"
+    "sync"
+    "time"
+
+    "github.com/epifi/fi-mcp-lite/pkg"
+)
+
+// ————— mock OAuth2 / OIDC —————
+// A minimal authorization-code grant over the same personas as /login, so
+// clients can run their real OAuth code against the mock. /oauth/authorize
+// shows a phone picker and redirects back with a one-time code;
+// /oauth/token trades it for a bearer token (the same JWT as /auth/token)
+// and, for scope "openid", an ID token. Any client_id is accepted and client
+// secrets are not checked. PKCE is enforced when the authorize request had a
+// code_challenge. Everything is signed with FI_MCP_JWT_SECRET, so without it
+// these endpoints return 404.
+
+const oauthCodeTTL = 10 * time.Minute
+
+type oauthCode struct {
+    phone       string
+    clientID    string
+    redirectURI string
+    scope       string
+    nonce       string
+    challenge   string
+    method      string
+    expires     time.Time
+}
+
+var oauthCodes = struct {
+    sync.Mutex
+    byCode map[string]oauthCode
+}{byCode: map[string]oauthCode{}}
+
+// oauthRequest is an /oauth/authorize request, from the query string on GET
+// and the form on POST.
+type oauthRequest struct {
+    ResponseType        string
+    ClientID            string
+    RedirectURI         string
+    Scope               string
+    State               string
+    Nonce               string
+    CodeChallenge       string
+    CodeChallengeMethod string
+}
+
+func parseOAuthRequest(r *http.Request) oauthRequest {
+    return oauthRequest{
+        ResponseType:        r.FormValue("response_type"),
+        ClientID:            r.FormValue("client_id"),
+        RedirectURI:         r.FormValue("redirect_uri"),
+        Scope:               r.FormValue("scope"),
+        State:               r.FormValue("state"),
+        Nonce:               r.FormValue("nonce"),
+        CodeChallenge:       r.FormValue("code_challenge"),
+        CodeChallengeMethod: r.FormValue("code_challenge_method"),
+    }
+}
+
+// validRedirect accepts absolute http(s) URLs without a fragment, limited to
+// FI_MCP_OAUTH_REDIRECT_URIS when that is set. Until it passes, errors are
+// shown to the user rather than redirected.
+func validRedirect(uri string) bool {
+    u, err := url.Parse(uri)
+    if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" || u.Fragment != "" {
+        return false
+    }
+    allowed := pkg.GetOAuthRedirectURIs()
+    return len(allowed) == 0 || contains(allowed, uri)
+}
+
+// redirect sends the user agent back to the client with params added to the
+// redirect URI's own query.
+func (req oauthRequest) redirect(w http.ResponseWriter, r *http.Request, params url.Values) {
+    u, _ := url.Parse(req.RedirectURI)
+    q := u.Query()
+    for k, v := range params {
+        q[k] = v
+    }
+    if req.State != "" {
+        q.Set("state", req.State)
+    }
+    u.RawQuery = q.Encode()
+    http.Redirect(w, r, u.String(), http.StatusFound)
+}
+
+func (req oauthRequest) redirectError(w http.ResponseWriter, r *http.Request, code, desc string) {
+    req.redirect(w, r, url.Values{"error": {code}, "error_description": {desc}})
+}
+
+// check reports whether req can be served, replying itself when it can't.
+func (req *oauthRequest) check(w http.ResponseWriter, r *http.Request) bool {
+    if pkg.GetJWTSecret() == "" {
+        http.Error(w, "oauth disabled", http.StatusNotFound)
+        return false
+    }
+    if req.ClientID == "" {
+        http.Error(w, "client_id required", http.StatusBadRequest)
+        return false
+    }
+    if !validRedirect(req.RedirectURI) {
+        http.Error(w, "invalid redirect_uri", http.StatusBadRequest)
+        return false
+    }
+    if req.ResponseType != "code" {
+        req.redirectError(w, r, "unsupported_response_type", "only response_type=code is supported")
+        return false
+    }
+    if req.CodeChallenge != "" && req.CodeChallengeMethod == "" {
+        req.CodeChallengeMethod = "plain"
+    }
+    if req.CodeChallengeMethod != "" && (req.CodeChallenge == "" || (req.CodeChallengeMethod != "S256" && req.CodeChallengeMethod != "plain")) {
+        req.redirectError(w, r, "invalid_request", "code_challenge_method must be S256 or plain, with a code_challenge")
+        return false
+    }
+    return true
+}
+
+func renderOAuthPage(w http.ResponseWriter, status int, req oauthRequest, errMsg string) {
+    tmpl, err := template.ParseFiles("static/oauth_authorize.html")
+    if err != nil {
+        log.Printf("oauth page: %v", err)
+        http.Error(w, "login page unavailable", http.StatusInternalServerError)
+        return
+    }
+    setDefaultContentType(w, "text/html; charset=utf-8")
+    w.WriteHeader(status)
+    tmpl.Execute(w, struct {
+        oauthRequest
+        Allowed []string
+        MFA     bool
+        Error   string
+    }{req, allowedMobileNumbers(), pkg.GetMFA(), errMsg})
+}
+
+// oauthAuthorizeHandler serves GET /oauth/authorize with the login form and
+// POST /oauth/authorize with its answer.
+func oauthAuthorizeHandler(w http.ResponseWriter, r *http.Request) {
+    if r.Method != http.MethodGet && r.Method != http.MethodPost {
+        http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+        return
+    }
+    req := parseOAuthRequest(r)
+    if !req.check(w, r) {
+        return
+    }
+    if r.Method == http.MethodGet {
+        renderOAuthPage(w, http.StatusOK, req, "")
+        return
+    }
+    if r.PostFormValue("deny") != "" {
+        req.redirectError(w, r, "access_denied", "the user denied access")
+        return
+    }
+    ph := r.PostFormValue("phoneNumber")
+    if !allowedPhone(ph) {
+        renderOAuthPage(w, http.StatusBadRequest, req, "This phone number is not allowed.")
+        return
+    }
+    if pkg.GetMFA() && subtle.ConstantTimeCompare([]byte(r.PostFormValue("otp")), []byte(pkg.GetMFACode())) != 1 {
+        renderOAuthPage(w, http.StatusUnauthorized, req, "Wrong OTP.")
+        return
+    }
+    code := randomToken()
+    now := time.Now()
+    oauthCodes.Lock()
+    for c, v := range oauthCodes.byCode {
+        if now.After(v.expires) {
+            delete(oauthCodes.byCode, c)
+        }
+    }
+    oauthCodes.byCode[code] = oauthCode{
+        phone: ph, clientID: req.ClientID, redirectURI: req.RedirectURI, scope: req.Scope, nonce: req.Nonce,
+        challenge: req.CodeChallenge, method: req.CodeChallengeMethod, expires: now.Add(oauthCodeTTL),
+    }
+    oauthCodes.Unlock()
+    log.Printf("oauth: phone=%s authorized client %q", logPhone(ph), req.ClientID)
+    req.redirect(w, r, url.Values{"code": {code}})
+}
+
+// tokenError is an RFC 6749 error response.
+func tokenError(w http.ResponseWriter, status int, code, desc string) {
+    w.Header().Set("Cache-Control", "no-store")
+    setDefaultContentType(w, "application/json")
+    w.WriteHeader(status)
+    writeJSON(w, map[string]string{"error": code, "error_description": desc})
+}
+
+// oauthTokenHandler serves POST /oauth/token for grant_type=authorization_code.
+func oauthTokenHandler(w http.ResponseWriter, r *http.Request) {
+    if r.Method != http.MethodPost {
+        http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+        return
+    }
+    secret := pkg.GetJWTSecret()
+    if secret == "" {
+        http.Error(w, "oauth disabled", http.StatusNotFound)
+        return
+    }
+    if gt := r.PostFormValue("grant_type"); gt != "authorization_code" {
+        tokenError(w, http.StatusBadRequest, "unsupported_grant_type", "only authorization_code is supported")
+        return
+    }
+    clientID := r.PostFormValue("client_id")
+    if id, _, ok := r.BasicAuth(); ok && clientID == "" {
+        clientID, _ = url.QueryUnescape(id)
+    }
+
+    // a code is used at most once, even when the exchange fails
+    oauthCodes.Lock()
+    c, ok := oauthCodes.byCode[r.PostFormValue("code")]
+    delete(oauthCodes.byCode, r.PostFormValue("code"))
+    oauthCodes.Unlock()
+    switch {
+    case !ok || time.Now().After(c.expires):
+        tokenError(w, http.StatusBadRequest, "invalid_grant", "unknown or expired code")
+        return
+    case c.clientID != clientID:
+        tokenError(w, http.StatusBadRequest, "invalid_grant", "code was issued to another client")
+        return
+    case c.redirectURI != r.PostFormValue("redirect_uri"):
+        tokenError(w, http.StatusBadRequest, "invalid_grant", "redirect_uri does not match the authorize request")
+        return
+    case !pkceMatches(c, r.PostFormValue("code_verifier")):
+        tokenError(w, http.StatusBadRequest, "invalid_grant", "code_verifier does not match the code_challenge")
+        return
+    case !allowedPhone(c.phone):
+        tokenError(w, http.StatusBadRequest, "invalid_grant", "persona no longer exists")
+        return
+    }
+
+    ttl := pkg.GetJWTTTL()
+    now := time.Now()
+    resp := map[string]interface{}{
+        "access_token": signJWT(secret, jwtClaims{Subject: c.phone, IssuedAt: now.Unix(), Expires: now.Add(ttl).Unix()}),
+        "token_type":   "Bearer",
+        "expires_in":   int64(ttl.Seconds()),
+    }
+    if c.scope != "" {
+        resp["scope"] = c.scope
+    }
+    if contains(strings.Fields(c.scope), "openid") {
+        resp["id_token"] = signJWT(secret, jwtClaims{
+            Subject: c.phone, IssuedAt: now.Unix(), Expires: now.Add(ttl).Unix(),
+            Issuer: requestBaseURL(r), Audience: c.clientID, Nonce: c.nonce, PhoneNumber: c.phone,
+        })
+    }
+    w.Header().Set("Cache-Control", "no-store")
+    writeJSON(w, resp)
+}
+
+func pkceMatches(c oauthCode, verifier string) bool {
+    switch c.method {
+    case "":
+        return true
+    case "S256":
+        sum := sha256.Sum256([]byte(verifier))
+        return verifier != "" && base64.RawURLEncoding.EncodeToString(sum[:]) == c.challenge
+    default: // plain
+        return verifier != "" && verifier == c.challenge
+    }
+}
+
+// oauthUserinfoHandler serves GET /oauth/userinfo for a bearer token.
+func oauthUserinfoHandler(w http.ResponseWriter, r *http.Request) {
+    if pkg.GetJWTSecret() == "" {
+        http.Error(w, "oauth disabled", http.StatusNotFound)
+        return
+    }
+    token, _ := bearerToken(r)
+    phone, ok := bearerPhone(token)
+    if !ok {
+        w.Header().Set("WWW-Authenticate", `Bearer error="invalid_token"`)
+        http.Error(w, "invalid or expired token", http.StatusUnauthorized)
+        return
+    }
+    writeJSON(w, map[string]interface{}{
+        "sub":                   phone,
+        "phone_number":          phone,
+        "phone_number_verified": true,
+    })
+}
+
+// oidcDiscoveryHandler serves /.well-known/openid-configuration.
+func oidcDiscoveryHandler(w http.ResponseWriter, r *http.Request) {
+    if pkg.GetJWTSecret() == "" {
+        http.Error(w, "oauth disabled", http.StatusNotFound)
+        return
+    }
+    base := requestBaseURL(r)
+    writeJSON(w, map[string]interface{}{
+        "issuer":                                base,
+        "authorization_endpoint":                base + "/oauth/authorize",
+        "token_endpoint":                        base + "/oauth/token",
+        "userinfo_endpoint":                     base + "/oauth/userinfo",
+        "response_types_supported":              []string{"code"},
+        "grant_types_supported":                 []string{"authorization_code"},
+        "subject_types_supported":               []string{"public"},
+        "id_token_signing_alg_values_supported": []string{"HS256"},
+        "scopes_supported":                      []string{"openid", "phone"},
+        "claims_supported":                      []string{"sub", "phone_number", "phone_number_verified"},
+        "code_challenge_methods_supported":      []string{"S256", "plain"},
+        "token_endpoint_auth_methods_supported": []string{"none", "client_secret_post", "client_secret_basic"},
+    })
+}
+
+func randomToken() string {
+    b := make([]byte, 24)
+    rand.Read(b)
+    return hex.EncodeToString(b)
+}
@@ -0,0 +1,607 @@
+package main
+
+import (
+    "context"
+    "encoding/json"
+    "html/template"
+    "log"
+    "net/http"
+    "os"
+    "path/filepath"
+    "regexp"
+    "sort"
+    "strings"
+    "sync"
+
+    "github.com/epifi/fi-mcp-lite/pkg"
+)
+
+// ————— OpenAPI —————
+// /openapi.json describes every route: the data endpoints come from
+// dataEndpoints, everything else from apiOps. Response schemas aren't written
+// by hand. A fixture's schema is inferred from that fixture across all
+// phones, and an op with a Sample handler is run once per phone and its JSON
+// answers inferred the same way. A field present in every sample is
+// required. The spec is built on first request and again after the admin
+// API changes fixtures. /docs is Swagger UI over it.
+
+type apiAuth int
+
+const (
+    authNone    apiAuth = iota
+    authSession         // session cookie or bearer token
+    authStream          // as authSession, or ?token=
+    authBearer          // bearer token only
+    authAdmin           // X-Admin-Token
+)
+
+type apiOp struct {
+    Method, Path, Summary string
+    Auth                  apiAuth
+    // Params are query params, described in paramDocs; {path} params are
+    // taken from Path.
+    Params []string
+    // Form lists the urlencoded body fields; Body is a JSON body's schema.
+    Form []string
+    Body map[string]interface{}
+    // Produces is the 200 response's content type, JSON when empty.
+    Produces string
+    // Sample, when set, infers the 200 response from a run per phone with
+    // SampleQuery. Only handlers without side effects qualify.
+    Sample      http.HandlerFunc
+    SampleQuery string
+}
+
+var paramDocs = map[string]struct{ Type, Description string }{
+    "accountId":   {"string", "only this account, an id from /api/accounts"},
+    "datasets":    {"string", "data type names, comma-separated (required)"},
+    "days":        {"integer", "days in the window, 1-366 (default 90)"},
+    "explain":     {"boolean", "add where each headline figure came from"},
+    "format":      {"string", "json, csv or ndjson; overrides the Accept header"},
+    "from":        {"string", "earliest date, RFC3339 or YYYY-MM-DD, inclusive"},
+    "to":          {"string", "latest date, RFC3339 or YYYY-MM-DD, inclusive"},
+    "type":        {"string", "type codes or names, comma-separated"},
+    "sort":        {"string", "date, -date, amount or -amount"},
+    "limit":       {"integer", "rows per page"},
+    "offset":      {"integer", "rows to skip"},
+    "goal":        {"number", "target amount in rupees (required)"},
+    "interval":    {"string", "time between polls such as 10s, 500ms to 10m; overrides the stream's default"},
+    "lastEventId": {"string", "resume after this event id"},
+    "minAmount":   {"number", "only transactions of at least this amount"},
+    "months":      {"integer", "months to project, 1-600"},
+    "period":      {"string", "day, week or month between points (default week)"},
+    "phone":       {"string", "the persona's phone number"},
+    "rate":        {"number", "yearly rate in percent"},
+    "seed":        {"integer", "random seed; the same seed gives the same series"},
+    "sessionId":   {"string", "the MCP session id"},
+    "simulations": {"integer", "Monte Carlo runs, 1-10000"},
+    "startDelay":  {"string", "wait this long (at most 30s) before the first event"},
+    "token":       {"string", "a token from /api/stream_token, instead of the cookie"},
+    "ttl":         {"string", "token lifetime such as 15m, at most 24h"},
+    "types":       {"string", "data type names, comma-separated"},
+    "years":       {"integer", "years to project, 1-50 (default 10)"},
+    "z":           {"number", "z-score threshold"},
+}
+
+var oauthParams = []string{"response_type", "client_id", "redirect_uri", "scope", "state", "nonce", "code_challenge", "code_challenge_method"}
+
+var apiOps = []apiOp{
+    // login UI
+    {Method: "GET", Path: "/mockWebPage", Summary: "Login page that binds a session to a phone", Params: []string{"sessionId"}, Produces: "text/html"},
+    {Method: "POST", Path: "/login", Summary: "Log a session in as a phone", Form: []string{"sessionId", "phoneNumber"}, Produces: "text/html"},
+    {Method: "POST", Path: "/login/verify", Summary: "Finish an MFA login with the OTP", Form: []string{"sessionId", "otp"}, Produces: "text/html"},
+    {Method: "POST", Path: "/logout", Summary: "End the cookie's session", Produces: "text/plain"},
+    {Method: "POST", Path: "/auth/token", Summary: "Log in and get a bearer JWT", Form: []string{"sessionId", "phoneNumber"}},
+
+    // mock OAuth2 / OIDC
+    {Method: "GET", Path: "/oauth/authorize", Summary: "Authorization-code login page", Params: oauthParams, Produces: "text/html"},
+    {Method: "POST", Path: "/oauth/authorize", Summary: "Answer the login page; redirects back with a code", Form: append(append([]string(nil), oauthParams...), "phoneNumber", "otp", "deny"), Produces: "text/html"},
+    {Method: "POST", Path: "/oauth/token", Summary: "Exchange an authorization code for tokens", Form: []string{"grant_type", "code", "redirect_uri", "client_id", "code_verifier"}},
+    {Method: "GET", Path: "/oauth/userinfo", Summary: "Claims of a bearer token's phone", Auth: authBearer},
+    {Method: "GET", Path: "/.well-known/openid-configuration", Summary: "OIDC discovery document"},
+
+    // MCP
+    {Method: "POST", Path: "/mcp/stream", Summary: "MCP over streamable HTTP (JSON-RPC 2.0)", Params: []string{"sessionId"},
+        Body: map[string]interface{}{"type": "object", "required": []string{"jsonrpc", "method"}}},
+    {Method: "GET", Path: "/ws", Summary: "WebSocket carrying any dataset; subscribe with {\"type\":\"subscribe\",\"dataset\":...}", Auth: authStream, Params: []string{"token"}},
+
+    // derived JSON
+    {Method: "GET", Path: "/api/net_worth/percentile", Summary: "Net worth percentile among all phones", Auth: authSession, Sample: netWorthPercentileHandler},
+    {Method: "GET", Path: "/api/net_worth/forecast", Summary: "Monte Carlo net worth forecast", Auth: authSession, Params: []string{"years", "simulations", "seed"}, Sample: netWorthForecastHandler, SampleQuery: "simulations=50"},
+    {Method: "GET", Path: "/api/net_worth/by_account_type", Summary: "Net worth by account type, checked against its sources", Auth: authSession, Sample: netWorthByAccountTypeHandler},
+    {Method: "GET", Path: "/api/net_worth/drift", Summary: "Each asset type's share of assets over time", Auth: authSession, Params: []string{"days", "period", "seed"}, Sample: netWorthDriftHandler},
+    {Method: "GET", Path: "/api/accounts", Summary: "Bank accounts", Auth: authSession, Sample: accountsHandler},
+    {Method: "GET", Path: "/api/health_score", Summary: "0-100 financial health score", Auth: authSession, Sample: healthScoreHandler},
+    {Method: "GET", Path: "/api/kyc_status", Summary: "Simulated KYC state; each poll advances it", Auth: authSession},
+    {Method: "GET", Path: "/api/anomalies", Summary: "Unusual bank transactions", Auth: authSession, Params: []string{"z"}, Sample: anomaliesHandler},
+    {Method: "GET", Path: "/api/bank_transactions/by_merchant", Summary: "Bank transactions grouped by merchant", Auth: authSession, Sample: bankTransactionsByMerchantHandler},
+    {Method: "GET", Path: "/api/benchmarks", Summary: "Cohort averages across all phones", Auth: authSession, Sample: benchmarksHandler},
+    {Method: "GET", Path: "/api/sip_recommendation", Summary: "Monthly SIP needed to reach a goal", Auth: authSession, Params: []string{"goal", "years", "rate"}, Sample: sipRecommendationHandler, SampleQuery: "goal=1000000"},
+    {Method: "GET", Path: "/api/spending_calendar", Summary: "Daily spend for a heatmap", Auth: authSession, Params: []string{"days"}, Sample: spendingCalendarHandler},
+    {Method: "GET", Path: "/api/insights", Summary: "Plain-English insights and savings suggestions", Auth: authSession},
+    {Method: "GET", Path: "/api/interest_projection", Summary: "Monthly interest on the savings balance", Auth: authSession, Params: []string{"rate", "months"}, Sample: interestProjectionHandler},
+    {Method: "GET", Path: "/api/reconcile", Summary: "Credit report tradelines matched to bank accounts", Auth: authSession, Sample: reconcileHandler},
+    {Method: "GET", Path: "/api/analytics/cashflow", Summary: "Monthly income against expense", Auth: authSession, Sample: cashflowHandler},
+    {Method: "GET", Path: "/api/analytics/categories", Summary: "Spend by category", Auth: authSession, Sample: categoriesHandler},
+    {Method: "GET", Path: "/api/analytics/networth_projection", Summary: "Holdings grown at fixed rates", Auth: authSession, Params: []string{"years"}, Sample: netWorthProjectionHandler},
+    {Method: "GET", Path: "/api/simulation", Summary: "The phone's virtual clock; 404 without a simulation", Auth: authSession, Sample: simulationHandler},
+    {Method: "GET", Path: "/api/summary", Summary: "Every data type and headline figures", Auth: authSession, Params: []string{"explain"}, Sample: summaryHandler},
+    {Method: "GET", Path: "/api/batch", Summary: "Several raw fixtures in one response", Auth: authSession, Params: []string{"types"}},
+    {Method: "GET", Path: "/api/export/ofx", Summary: "Bank transactions as an OFX statement", Auth: authSession, Produces: "application/x-ofx"},
+    {Method: "GET", Path: "/api/stream_token", Summary: "Short-lived token for /stream and /ws", Auth: authSession, Params: []string{"ttl"}},
+    {Method: "GET", Path: "/api/webhooks", Summary: "The phone's webhooks", Auth: authSession},
+    {Method: "POST", Path: "/api/webhooks", Summary: "Register a webhook", Auth: authSession, Body: webhookBodySchema},
+    {Method: "GET", Path: "/api/webhooks/{id}", Summary: "One webhook", Auth: authSession},
+    {Method: "PUT", Path: "/api/webhooks/{id}", Summary: "Change a webhook", Auth: authSession, Body: webhookBodySchema},
+    {Method: "DELETE", Path: "/api/webhooks/{id}", Summary: "Remove a webhook", Auth: authSession},
+
+    // derived SSE
+    {Method: "GET", Path: "/stream/net_worth/ticker", Summary: "Net worth total, every 2s by default", Auth: authStream, Params: []string{"token", "startDelay", "interval"}, Produces: "text/event-stream"},
+    {Method: "GET", Path: "/stream/portfolio_value", Summary: "Equity holdings revalued, every 2s by default", Auth: authStream, Params: []string{"token", "startDelay", "interval", "seed"}, Produces: "text/event-stream"},
+    {Method: "GET", Path: "/stream/all", Summary: "Several datasets over one stream; each event is named after its dataset", Auth: authStream, Params: []string{"datasets", "token", "startDelay", "lastEventId", "interval", "minAmount"}, Produces: "text/event-stream"},
+
+    // admin and monitoring
+    {Method: "POST", Path: "/admin/kyc_status/reset", Summary: "Reset a phone's KYC state", Auth: authAdmin, Params: []string{"phone"}},
+    {Method: "GET", Path: "/stats.json", Summary: "Server counters", Auth: authAdmin},
+    {Method: "GET", Path: "/metrics", Summary: "Prometheus metrics", Produces: "text/plain"},
+    {Method: "GET", Path: "/healthz", Summary: "Liveness probe", Sample: healthzHandler},
+    {Method: "GET", Path: "/readyz", Summary: "Readiness probe and fixture status; 503 when not ready", Sample: readyzHandler},
+    {Method: "POST", Path: "/admin/simulation/reset", Summary: "Restart every virtual clock", Auth: authAdmin},
+    {Method: "GET", Path: "/admin/personas", Summary: "Phones and their datasets", Auth: authAdmin},
+    {Method: "POST", Path: "/admin/personas", Summary: "Create a phone with fixtures", Auth: authAdmin, Body: map[string]interface{}{
+        "type": "object", "required": []string{"phone"},
+        "properties": map[string]interface{}{
+            "phone":    map[string]interface{}{"type": "string"},
+            "datasets": map[string]interface{}{"type": "object", "description": "fixtures keyed by data type name"},
+        },
+    }},
+    {Method: "POST", Path: "/admin/personas/generate", Summary: "Create personas from generated data", Auth: authAdmin, Body: map[string]interface{}{
+        "type": "object",
+        "properties": map[string]interface{}{
+            "profile": map[string]interface{}{"type": "string"},
+            "count":   map[string]interface{}{"type": "integer", "maximum": 500},
+            "seed":    map[string]interface{}{"type": "integer"},
+            "months":  map[string]interface{}{"type": "integer"},
+            "end":     map[string]interface{}{"type": "string", "format": "date"},
+            "phone":   map[string]interface{}{"type": "string"},
+        },
+    }},
+    {Method: "PUT", Path: "/admin/personas/{phone}/{dataset}", Summary: "Upload or replace one fixture", Auth: authAdmin, Body: map[string]interface{}{"type": "object", "description": "the fixture"}},
+    {Method: "DELETE", Path: "/admin/personas/{phone}/{dataset}", Summary: "Remove one fixture", Auth: authAdmin},
+    {Method: "DELETE", Path: "/admin/personas/{phone}", Summary: "Remove a phone created through the API", Auth: authAdmin},
+    {Method: "GET", Path: "/admin/audit", Summary: "Recorded fixture changes, oldest first", Auth: authAdmin, Params: []string{"phone"}},
+}
+
+var webhookBodySchema = map[string]interface{}{
+    "type": "object", "required": []string{"dataset", "url"},
+    "properties": map[string]interface{}{
+        "dataset": map[string]interface{}{"type": "string"},
+        "url":     map[string]interface{}{"type": "string", "format": "uri"},
+        "secret":  map[string]interface{}{"type": "string", "description": "generated when left out"},
+    },
+}
+
+// openAPICache is built without its lock held, since building takes
+// personas' lock and fixturesChanged takes this one under it. gen stops a
+// build that raced a change from being stored.
+var openAPICache struct {
+    sync.Mutex
+    value []byte
+    gen   int
+}
+
+// openAPIHandler serves /openapi.json. The server URL is the request's, so
+// that part is not cached.
+func openAPIHandler(w http.ResponseWriter, r *http.Request) {
+    openAPICache.Lock()
+    spec, gen := openAPICache.value, openAPICache.gen
+    openAPICache.Unlock()
+    if spec == nil {
+        spec, _ = json.Marshal(buildOpenAPI())
+        openAPICache.Lock()
+        if openAPICache.gen == gen {
+            openAPICache.value = spec
+        }
+        openAPICache.Unlock()
+    }
+
+    var doc map[string]json.RawMessage
+    json.Unmarshal(spec, &doc)
+    doc["servers"], _ = json.Marshal([]map[string]string{{"url": requestBaseURL(r)}})
+    writeJSON(w, doc)
+}
+
+func forgetOpenAPI() {
+    openAPICache.Lock()
+    openAPICache.value = nil
+    openAPICache.gen++
+    openAPICache.Unlock()
+}
+
+// Swagger UI's files are vendored into static/swagger-ui by go generate, so
+// /docs works offline and embedfixtures builds carry them with the rest of
+// static. Without that copy /docs falls back to the CDN.
+//go:generate sh -c "mkdir -p static/swagger-ui && curl -sSfL https://registry.npmjs.org/swagger-ui-dist/-/swagger-ui-dist-5.17.14.tgz | tar -xzf - -C static/swagger-ui --strip-components=1 package/swagger-ui.css package/swagger-ui-bundle.js package/LICENSE"
+const (
+    localSwaggerUI = "static/swagger-ui"
+    cdnSwaggerUI   = "https://unpkg.com/swagger-ui-dist@5"
+)
+
+// swaggerUIBase is where /docs loads Swagger UI from: FI_MCP_SWAGGER_UI_URL
+// if set, else the vendored copy when it is there, else the CDN.
+func swaggerUIBase() string {
+    if ui := pkg.GetSwaggerUIURL(); ui != "" {
+        return strings.TrimRight(ui, "/")
+    }
+    if _, err := os.Stat(filepath.Join(localSwaggerUI, "swagger-ui-bundle.js")); err == nil {
+        return "/" + localSwaggerUI
+    }
+    return cdnSwaggerUI
+}
+
+// docsHandler serves /docs, Swagger UI from swaggerUIBase.
+func docsHandler(w http.ResponseWriter, r *http.Request) {
+    tmpl, err := template.ParseFiles("static/docs.html")
+    if err != nil {
+        log.Printf("docs page: %v", err)
+        http.Error(w, "docs page unavailable", http.StatusInternalServerError)
+        return
+    }
+    setDefaultContentType(w, "text/html; charset=utf-8")
+    tmpl.Execute(w, struct{ UI string }{swaggerUIBase()})
+}
+
+var pathParamPattern = regexp.MustCompile(`\{(\w+)\}`)
+
+func buildOpenAPI() map[string]interface{} {
+    paths := map[string]map[string]interface{}{}
+    add := func(op apiOp, response map[string]interface{}) {
+        if paths[op.Path] == nil {
+            paths[op.Path] = map[string]interface{}{}
+        }
+        paths[op.Path][strings.ToLower(op.Method)] = operation(op, response)
+    }
+    for _, op := range apiOps {
+        var schema map[string]interface{}
+        if s := sampleResponses(op); s != nil {
+            schema = s.render()
+        }
+        add(op, schema)
+    }
+
+    schemas := map[string]interface{}{}
+    skipEmpty := pkg.GetSkipEmptyRoutes()
+    for _, e := range dataEndpoints {
+        if skipEmpty && !anyPhoneHas(e.File) {
+            continue // not registered either
+        }
+        s := inferFixtureSchema(e)
+        rendered := s.render()
+        rendered["description"] = e.Description
+        schemas[e.Name] = rendered
+        ref := map[string]interface{}{"$ref": "#/components/schemas/" + e.Name}
+
+        params := e.APIParams
+        if e.Txns != nil {
+            params = append(append(append([]string(nil), params...), txnPageParams...), "format")
+        }
+        add(apiOp{Method: "GET", Path: "/api/" + e.Name, Summary: e.Description, Auth: authSession, Params: params}, ref)
+        add(apiOp{Method: "GET", Path: "/stream/" + e.Name, Summary: "Server-sent events; each event's data is the " + e.Name + " fixture",
+            Auth: authStream, Params: append([]string{"token", "startDelay", "lastEventId", "interval"}, e.StreamParams...), Produces: "text/event-stream"}, nil)
+        add(apiOp{Method: "GET", Path: "/ws/" + e.Name, Summary: "WebSocket; each message carries the " + e.Name + " fixture",
+            Auth: authStream, Params: append([]string{"token", "lastEventId", "interval"}, e.StreamParams...)}, nil)
+        if e.Txns != nil {
+            content := paths["/api/"+e.Name]["get"].(map[string]interface{})["responses"].(map[string]interface{})["200"].(map[string]interface{})["content"].(map[string]interface{})
+            for _, ct := range formatContentTypes {
+                content[strings.Split(ct, ";")[0]] = map[string]interface{}{"schema": map[string]interface{}{"type": "string", "description": "one row per transaction: " + strings.Join(txnColumns(*e.Txns), ", ")}}
+            }
+        }
+    }
+
+    return map[string]interface{}{
+        "openapi": "3.1.0",
+        "info": map[string]interface{}{
+            "title":       "Fi MCP mock",
+            "version":     "dev",
+            "description": "Mock Fi Money APIs over test personas. Response schemas are inferred from the fixtures on this server.",
+        },
+        "paths": paths,
+        "components": map[string]interface{}{
+            "schemas": schemas,
+            "securitySchemes": map[string]interface{}{
+                "sessionCookie": map[string]interface{}{"type": "apiKey", "in": "cookie", "name": pkg.GetCookieName()},
+                "bearerAuth":    map[string]interface{}{"type": "http", "scheme": "bearer", "bearerFormat": "JWT"},
+                "streamToken":   map[string]interface{}{"type": "apiKey", "in": "query", "name": "token"},
+                "adminToken":    map[string]interface{}{"type": "apiKey", "in": "header", "name": "X-Admin-Token"},
+            },
+        },
+    }
+}
+
+// operation renders op, with response as its 200 JSON schema when known.
+func operation(op apiOp, response map[string]interface{}) map[string]interface{} {
+    var params []map[string]interface{}
+    for _, m := range pathParamPattern.FindAllStringSubmatch(op.Path, -1) {
+        params = append(params, map[string]interface{}{"name": m[1], "in": "path", "required": true, "schema": map[string]string{"type": "string"}})
+    }
+    for _, name := range op.Params {
+        d, ok := paramDocs[name]
+        if !ok {
+            d.Type = "string"
+        }
+        p := map[string]interface{}{"name": name, "in": "query", "schema": map[string]string{"type": d.Type}}
+        if d.Description != "" {
+            p["description"] = d.Description
+        }
+        params = append(params, p)
+    }
+
+    ct := op.Produces
+    if ct == "" {
+        ct = "application/json"
+    }
+    if response == nil {
+        response = map[string]interface{}{}
+        if ct != "application/json" {
+            response["type"] = "string"
+        }
+    }
+    out := map[string]interface{}{
+        "summary": op.Summary,
+        "responses": map[string]interface{}{
+            "200": map[string]interface{}{
+                "description": "OK",
+                "content":     map[string]interface{}{ct: map[string]interface{}{"schema": response}},
+            },
+        },
+    }
+    if strings.HasPrefix(op.Path, "/ws") {
+        out["responses"] = map[string]interface{}{"101": map[string]interface{}{"description": "Switching to the WebSocket protocol"}}
+    }
+    if len(params) > 0 {
+        out["parameters"] = params
+    }
+    if len(op.Form) > 0 {
+        props := map[string]interface{}{}
+        for _, f := range op.Form {
+            props[f] = map[string]string{"type": "string"}
+        }
+        out["requestBody"] = map[string]interface{}{"content": map[string]interface{}{
+            "application/x-www-form-urlencoded": map[string]interface{}{"schema": map[string]interface{}{"type": "object", "properties": props}},
+        }}
+    }
+    if op.Body != nil {
+        out["requestBody"] = map[string]interface{}{"required": true, "content": map[string]interface{}{
+            "application/json": map[string]interface{}{"schema": op.Body},
+        }}
+    }
+
+    responses := out["responses"].(map[string]interface{})
+    switch op.Auth {
+    case authSession:
+        out["security"] = []map[string][]string{{"sessionCookie": {}}, {"bearerAuth": {}}}
+        responses["401"] = map[string]string{"description": "Not logged in"}
+    case authStream:
+        out["security"] = []map[string][]string{{"sessionCookie": {}}, {"bearerAuth": {}}, {"streamToken": {}}}
+        responses["401"] = map[string]string{"description": "Not logged in"}
+    case authBearer:
+        out["security"] = []map[string][]string{{"bearerAuth": {}}}
+        responses["401"] = map[string]string{"description": "Invalid or expired token"}
+    case authAdmin:
+        out["security"] = []map[string][]string{{"adminToken": {}}}
+        responses["403"] = map[string]string{"description": "Missing or wrong X-Admin-Token"}
+        responses["404"] = map[string]string{"description": "FI_MCP_ADMIN_TOKEN is not set"}
+    }
+    return out
+}
+
+// inferFixtureSchema infers e's fixture from every phone that has one,
+// naming transaction row columns after Txns.
+func inferFixtureSchema(e dataEndpoint) *inferredSchema {
+    s := &inferredSchema{}
+    for _, phone := range allowedMobileNumbers() {
+        data, err := cachedReadFile(dataFilePath(phone, e.File))
+        if err != nil {
+            continue
+        }
+        if v, ok := decodeSample(data); ok {
+            s.add(v, "", false)
+        }
+    }
+    if e.Txns != nil {
+        if rows := s.prop(e.Txns.ListKey).itemSchema().prop("txns").itemSchema(); rows != nil {
+            for i, col := range e.Txns.Cols {
+                if i < len(rows.tuple) {
+                    rows.tuple[i].title = col
+                }
+            }
+        }
+    }
+    return s
+}
+
+// sampleResponses runs op.Sample as every phone and infers the schema of its
+// 200 answers; nil when op has no Sample or nothing answered.
+func sampleResponses(op apiOp) *inferredSchema {
+    if op.Sample == nil {
+        return nil
+    }
+    var s *inferredSchema
+    for _, phone := range allowedMobileNumbers() {
+        // not the /openapi.json request's context: a client hanging up must
+        // not leave half a spec in the cache
+        ctx := context.WithValue(context.Background(), "phone", phone)
+        req, _ := http.NewRequestWithContext(ctx, http.MethodGet, op.Path+"?"+op.SampleQuery, nil)
+        rec := &recorder{header: http.Header{}, status: http.StatusOK}
+        op.Sample(rec, req)
+        if rec.status != http.StatusOK {
+            continue
+        }
+        if v, ok := decodeSample(rec.body.Bytes()); ok {
+            if s == nil {
+                s = &inferredSchema{}
+            }
+            s.add(v, "", false)
+        }
+    }
+    return s
+}
+
+func decodeSample(data []byte) (interface{}, bool) {
+    var v interface{}
+    return v, json.Unmarshal(data, &v) == nil
+}
+
+// ————— schema inference —————
+
+// schemaMapKeys are fields whose object value is keyed by data (account ids,
+// dates) rather than by field names.
+var schemaMapKeys = map[string]bool{"accountDetailsMap": true, "days": true, "byPattern": true, "personas": true}
+
+// inferredSchema merges JSON values into one JSON Schema. Arrays nested
+// directly in arrays are rows, typed position by position.
+type inferredSchema struct {
+    types    map[string]bool
+    props    map[string]*inferredSchema
+    seen     int            // objects merged
+    propSeen map[string]int // objects that had the field
+    items    *inferredSchema
+    tuple    []*inferredSchema
+    isMap    bool
+    title    string
+}
+
+// prop and itemSchema walk down a schema, nil when it has no such part.
+func (s *inferredSchema) prop(name string) *inferredSchema {
+    if s == nil {
+        return nil
+    }
+    return s.props[name]
+}
+
+func (s *inferredSchema) itemSchema() *inferredSchema {
+    if s == nil {
+        return nil
+    }
+    return s.items
+}
+
+func (s *inferredSchema) add(v interface{}, key string, row bool) {
+    if s.types == nil {
+        s.types = map[string]bool{}
+    }
+    switch v := v.(type) {
+    case nil:
+        s.types["null"] = true
+    case bool:
+        s.types["boolean"] = true
+    case string:
+        s.types["string"] = true
+    case float64:
+        // never "integer": a whole rupee amount in one sample says nothing
+        // about the next
+        s.types["number"] = true
+    case map[string]interface{}:
+        s.types["object"] = true
+        if schemaMapKeys[key] {
+            s.isMap = true
+            for _, x := range v {
+                s.child(&s.items).add(x, "", isArray(x))
+            }
+            return
+        }
+        if s.props == nil {
+            s.props, s.propSeen = map[string]*inferredSchema{}, map[string]int{}
+        }
+        s.seen++
+        for k, x := range v {
+            s.propSeen[k]++
+            if s.props[k] == nil {
+                s.props[k] = &inferredSchema{}
+            }
+            s.props[k].add(x, k, false)
+        }
+    case []interface{}:
+        s.types["array"] = true
+        for i, x := range v {
+            if row {
+                for len(s.tuple) <= i {
+                    s.tuple = append(s.tuple, &inferredSchema{})
+                }
+                s.tuple[i].add(x, "", isArray(x))
+            } else {
+                s.child(&s.items).add(x, key, isArray(x))
+            }
+        }
+    }
+}
+
+func (s *inferredSchema) child(p **inferredSchema) *inferredSchema {
+    if *p == nil {
+        *p = &inferredSchema{}
+    }
+    return *p
+}
+
+func isArray(v interface{}) bool {
+    _, ok := v.([]interface{})
+    return ok
+}
+
+func (s *inferredSchema) render() map[string]interface{} {
+    out := map[string]interface{}{}
+    if s.title != "" {
+        out["title"] = s.title
+    }
+    var types []string
+    for t := range s.types {
+        types = append(types, t)
+    }
+    sort.Strings(types)
+    switch len(types) {
+    case 0:
+        return out // never seen a value: anything
+    case 1:
+        out["type"] = types[0]
+    default:
+        out["type"] = types
+    }
+    if s.types["object"] {
+        if s.isMap {
+            out["additionalProperties"] = s.items.renderOrAny()
+        } else if len(s.props) > 0 {
+            props := map[string]interface{}{}
+            var required []string
+            for k, p := range s.props {
+                props[k] = p.render()
+                if s.propSeen[k] == s.seen {
+                    required = append(required, k)
+                }
+            }
+            sort.Strings(required)
+            out["properties"] = props
+            if len(required) > 0 {
+                out["required"] = required
+            }
+        }
+    }
+    if s.types["array"] {
+        if len(s.tuple) > 0 {
+            prefix := make([]map[string]interface{}, len(s.tuple))
+            for i, t := range s.tuple {
+                prefix[i] = t.render()
+            }
+            out["prefixItems"] = prefix
+        } else {
+            out["items"] = s.items.renderOrAny()
+        }
+    }
+    return out
+}
+
+func (s *inferredSchema) renderOrAny() map[string]interface{} {
+    if s == nil {
+        return map[string]interface{}{}
+    }
+    return s.render()
+}
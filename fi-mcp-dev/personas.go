@@ -0,0 +1,446 @@
+package main
+
+import (
+    "encoding/json"
+    "errors"
+    "fmt"
+    "io"
+    "io/fs"
+    "log"
+    "net/http"
+    "os"
+    "path/filepath"
+    "sort"
+    "strconv"
+    "sync"
+    "time"
+
+    "github.com/epifi/fi-mcp-lite/pkg"
+)
+
+// ————— test personas (admin) —————
+// /admin/personas creates, edits and deletes test phones at runtime. Each
+// upload is checked against its data type's Schema and then written to
+// test_data_dir/<phone>/<file>. Phones created here join the allowed numbers
+// and are listed in test_data_dir/.personas.json, so they are still allowed
+// after a restart.
+
+const (
+    personasFile     = ".personas.json"
+    maxFixtureUpload = 10 << 20
+)
+
+var personas = struct {
+    sync.RWMutex
+    phones map[string]bool
+}{phones: map[string]bool{}}
+
+// loadPersonas reads the phones created by earlier runs; main calls it once
+// at startup.
+func loadPersonas() {
+    data, err := os.ReadFile(filepath.Join(dataDir, personasFile))
+    if errors.Is(err, fs.ErrNotExist) {
+        return
+    }
+    var phones []string
+    if err == nil {
+        err = json.Unmarshal(data, &phones)
+    }
+    if err != nil {
+        log.Printf("%s: %v", personasFile, err)
+        return
+    }
+    personas.Lock()
+    defer personas.Unlock()
+    for _, ph := range phones {
+        if phonePattern.MatchString(ph) {
+            personas.phones[ph] = true
+        }
+    }
+}
+
+// savePersonasLocked rewrites the persona list; the caller holds the lock.
+func savePersonasLocked() error {
+    phones := make([]string, 0, len(personas.phones))
+    for ph := range personas.phones {
+        phones = append(phones, ph)
+    }
+    sort.Strings(phones)
+    data, _ := json.Marshal(phones)
+    return writeFileAtomic(filepath.Join(dataDir, personasFile), data)
+}
+
+// allowedMobileNumbers is pkg.GetAllowedMobileNumbers plus the personas
+// created through the admin API.
+func allowedMobileNumbers() []string {
+    phones := pkg.GetAllowedMobileNumbers()
+    personas.RLock()
+    defer personas.RUnlock()
+    var extra []string
+    for ph := range personas.phones {
+        if !contains(phones, ph) {
+            extra = append(extra, ph)
+        }
+    }
+    sort.Strings(extra)
+    // copy: the pkg slice may be shared
+    return append(append([]string(nil), phones...), extra...)
+}
+
+func writeFileAtomic(path string, data []byte) error {
+    tmp, err := os.CreateTemp(filepath.Dir(path), ".upload-*")
+    if err != nil {
+        return err
+    }
+    defer os.Remove(tmp.Name())
+    if _, err := tmp.Write(data); err != nil {
+        tmp.Close()
+        return err
+    }
+    if err := tmp.Close(); err != nil {
+        return err
+    }
+    if err := os.Chmod(tmp.Name(), 0o644); err != nil {
+        return err
+    }
+    return os.Rename(tmp.Name(), path)
+}
+
+// fixturesChanged drops the cached fixtures and everything built from them
+// and tells the phone's webhooks. Callers may hold personas' lock, which the
+// webhook check needs, hence the goroutine.
+func fixturesChanged(phone string) {
+    forgetFixtures(phone)
+    purgeCachedResponses(phone)
+    benchmarkCache.Lock()
+    benchmarkCache.value = nil
+    benchmarkCache.Unlock()
+    forgetOpenAPI()
+    go checkWebhooks(phone)
+}
+
+// ————— schema checks —————
+
+// fixtureSchema is what an uploaded fixture must look like. {} is always
+// accepted: it is how a phone has no data of a type.
+type fixtureSchema struct {
+    Required map[string]string // top-level key → "object" or "array"
+    // Decode, if set, decodes into the type the handlers read, catching
+    // fields of the wrong type.
+    Decode func([]byte) error
+}
+
+func decodeAs[T any](data []byte) error {
+    var v T
+    return json.Unmarshal(data, &v)
+}
+
+func validateFixture(e dataEndpoint, data []byte) error {
+    var doc map[string]json.RawMessage
+    if err := json.Unmarshal(data, &doc); err != nil || doc == nil {
+        return errors.New("fixture must be a JSON object")
+    }
+    if len(doc) == 0 {
+        return nil
+    }
+    for key, kind := range e.Schema.Required {
+        raw, ok := doc[key]
+        if !ok {
+            return fmt.Errorf("missing %q", key)
+        }
+        if jsonKind(raw) != kind {
+            return fmt.Errorf("%q must be an %s", key, kind)
+        }
+    }
+    if e.Txns != nil {
+        if err := checkTxnRows(doc, *e.Txns); err != nil {
+            return err
+        }
+    }
+    if e.Schema.Decode != nil {
+        return e.Schema.Decode(data)
+    }
+    return nil
+}
+
+func jsonKind(raw json.RawMessage) string {
+    for _, c := range raw {
+        switch c {
+        case ' ', '\t', '\r', '\n':
+            continue
+        case '{':
+            return "object"
+        case '[':
+            return "array"
+        default:
+            return "value"
+        }
+    }
+    return "value"
+}
+
+// checkTxnRows makes sure every txns row has the date, type and amount the
+// paging and filters read.
+func checkTxnRows(doc map[string]json.RawMessage, rows txnRows) error {
+    var groups []struct {
+        Txns [][]interface{} `json:"txns"`
+    }
+    if err := json.Unmarshal(doc[rows.ListKey], &groups); err != nil {
+        return fmt.Errorf("%s: each entry must be an object whose txns is an array of rows", rows.ListKey)
+    }
+    for gi, g := range groups {
+        for ti, row := range g.Txns {
+            where := fmt.Sprintf("%s[%d].txns[%d]", rows.ListKey, gi, ti)
+            if len(row) <= max(rows.DateIdx, rows.TypeIdx, rows.AmountIdx) {
+                return fmt.Errorf("%s: too few columns", where)
+            }
+            if s, _ := row[rows.DateIdx].(string); !validDate(s) {
+                return fmt.Errorf("%s: column %d must be a YYYY-MM-DD date", where, rows.DateIdx)
+            }
+            code, ok := row[rows.TypeIdx].(float64)
+            if _, known := rows.TypeNames[int(code)]; !ok || !known {
+                return fmt.Errorf("%s: column %d is not a known transaction type", where, rows.TypeIdx)
+            }
+            if rows.AmountIdx >= 0 && !isNumber(row[rows.AmountIdx]) {
+                return fmt.Errorf("%s: column %d must be a number", where, rows.AmountIdx)
+            }
+        }
+    }
+    return nil
+}
+
+func validDate(s string) bool {
+    _, err := time.Parse(time.DateOnly, s)
+    return err == nil
+}
+
+// isNumber accepts numbers and numeric strings, as the fixtures use both.
+func isNumber(v interface{}) bool {
+    switch n := v.(type) {
+    case float64:
+        return true
+    case string:
+        _, err := strconv.ParseFloat(n, 64)
+        return err == nil
+    }
+    return false
+}
+
+// ————— handlers —————
+
+type personaInfo struct {
+    Phone    string   `json:"phone"`
+    BuiltIn  bool     `json:"builtIn"`
+    Datasets []string `json:"datasets"`
+}
+
+func describePersona(phone string) personaInfo {
+    personas.RLock()
+    p := personaInfo{Phone: phone, BuiltIn: !personas.phones[phone], Datasets: []string{}}
+    personas.RUnlock()
+    for _, e := range dataEndpoints {
+        if _, err := os.Stat(dataFilePath(phone, e.File)); err == nil {
+            p.Datasets = append(p.Datasets, e.Name)
+        }
+    }
+    return p
+}
+
+// listPersonasHandler serves GET /admin/personas.
+func listPersonasHandler(w http.ResponseWriter, r *http.Request) {
+    out := []personaInfo{}
+    for _, phone := range allowedMobileNumbers() {
+        out = append(out, describePersona(phone))
+    }
+    writeJSON(w, out)
+}
+
+// createPersonaHandler serves POST /admin/personas with
+// {"phone": "...", "datasets": {"<type>": <fixture>, ...}}. Every fixture is
+// checked before anything is written.
+func createPersonaHandler(w http.ResponseWriter, r *http.Request) {
+    var req struct {
+        Phone    string                     `json:"phone"`
+        Datasets map[string]json.RawMessage `json:"datasets"`
+    }
+    if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxFixtureUpload)).Decode(&req); err != nil {
+        http.Error(w, "invalid JSON body", http.StatusBadRequest)
+        return
+    }
+    if !phonePattern.MatchString(req.Phone) {
+        http.Error(w, "invalid phone", http.StatusBadRequest)
+        return
+    }
+    for name, data := range req.Datasets {
+        e, ok := dataEndpointByName(name)
+        if !ok {
+            http.Error(w, fmt.Sprintf("unknown data type: %s", name), http.StatusBadRequest)
+            return
+        }
+        if err := validateFixture(e, data); err != nil {
+            http.Error(w, fmt.Sprintf("%s: %v", name, err), http.StatusBadRequest)
+            return
+        }
+    }
+
+    datasets := make(map[string][]byte, len(req.Datasets))
+    for name, data := range req.Datasets {
+        datasets[name] = data
+    }
+    if err := createPersona(req.Phone, datasets, r.Pattern); err != nil {
+        if errors.Is(err, errPersonaExists) {
+            http.Error(w, err.Error(), http.StatusConflict)
+            return
+        }
+        log.Printf("create persona %s: %v", logPhone(req.Phone), err)
+        http.Error(w, "could not create persona", http.StatusInternalServerError)
+        return
+    }
+
+    p := personaInfo{Phone: req.Phone, Datasets: []string{}}
+    for _, e := range dataEndpoints {
+        if _, ok := req.Datasets[e.Name]; ok {
+            p.Datasets = append(p.Datasets, e.Name)
+        }
+    }
+    setDefaultContentType(w, defaultContentType)
+    w.WriteHeader(http.StatusCreated)
+    writeJSON(w, p)
+}
+
+var errPersonaExists = errors.New("phone already exists")
+
+// createPersona writes a new phone's fixtures, keyed by data type name and
+// already validated, and adds it to the allowed numbers. endpoint names the
+// caller in the audit trail.
+func createPersona(phone string, datasets map[string][]byte, endpoint string) error {
+    personas.Lock()
+    defer personas.Unlock()
+    dir := filepath.Join(dataDir, phone)
+    if _, err := os.Stat(dir); err == nil || contains(pkg.GetAllowedMobileNumbers(), phone) || personas.phones[phone] {
+        return errPersonaExists
+    }
+    if err := os.Mkdir(dir, 0o755); err != nil {
+        return err
+    }
+    for name, data := range datasets {
+        e, _ := dataEndpointByName(name)
+        if err := writeFileAtomic(dataFilePath(phone, e.File), data); err != nil {
+            os.RemoveAll(dir)
+            return err
+        }
+    }
+    personas.phones[phone] = true
+    if err := savePersonasLocked(); err != nil {
+        log.Printf("%s: %v", personasFile, err)
+    }
+    log.Printf("admin: created persona %s with %d dataset(s)", logPhone(phone), len(datasets))
+    recordAudit(phone, endpoint, nil, datasets)
+    fixturesChanged(phone)
+    return nil
+}
+
+// personaDataset resolves {phone} and {dataset}, replying 404 when either
+// is unknown.
+func personaDataset(w http.ResponseWriter, r *http.Request) (string, dataEndpoint, bool) {
+    phone := r.PathValue("phone")
+    if !allowedPhone(phone) {
+        http.Error(w, "unknown persona", http.StatusNotFound)
+        return "", dataEndpoint{}, false
+    }
+    e, ok := dataEndpointByName(r.PathValue("dataset"))
+    if !ok {
+        http.Error(w, "unknown data type", http.StatusNotFound)
+        return "", dataEndpoint{}, false
+    }
+    return phone, e, true
+}
+
+// putDatasetHandler serves PUT /admin/personas/{phone}/{dataset}: the body
+// replaces (or adds) that fixture.
+func putDatasetHandler(w http.ResponseWriter, r *http.Request) {
+    phone, e, ok := personaDataset(w, r)
+    if !ok {
+        return
+    }
+    data, err := io.ReadAll(http.MaxBytesReader(w, r.Body, maxFixtureUpload))
+    if err != nil {
+        http.Error(w, "body too large", http.StatusRequestEntityTooLarge)
+        return
+    }
+    if err := validateFixture(e, data); err != nil {
+        http.Error(w, fmt.Sprintf("%s: %v", e.Name, err), http.StatusBadRequest)
+        return
+    }
+    path := dataFilePath(phone, e.File)
+    before := phoneDatasets(phone)
+    _, statErr := os.Stat(path)
+    if err := os.MkdirAll(filepath.Dir(path), 0o755); err == nil {
+        err = writeFileAtomic(path, data)
+    }
+    if err != nil {
+        log.Printf("put %s for %s: %v", e.File, logPhone(phone), err)
+        http.Error(w, "could not write fixture", http.StatusInternalServerError)
+        return
+    }
+    log.Printf("admin: wrote %s for %s", e.File, logPhone(phone))
+    recordAudit(phone, r.Pattern, before, phoneDatasets(phone))
+    fixturesChanged(phone)
+    if statErr != nil {
+        setDefaultContentType(w, defaultContentType)
+        w.WriteHeader(http.StatusCreated)
+    }
+    writeJSON(w, describePersona(phone))
+}
+
+// deleteDatasetHandler serves DELETE /admin/personas/{phone}/{dataset}.
+func deleteDatasetHandler(w http.ResponseWriter, r *http.Request) {
+    phone, e, ok := personaDataset(w, r)
+    if !ok {
+        return
+    }
+    before := phoneDatasets(phone)
+    if err := os.Remove(dataFilePath(phone, e.File)); err != nil {
+        if errors.Is(err, fs.ErrNotExist) {
+            http.Error(w, "no such fixture", http.StatusNotFound)
+            return
+        }
+        log.Printf("delete %s for %s: %v", e.File, logPhone(phone), err)
+        http.Error(w, "could not delete fixture", http.StatusInternalServerError)
+        return
+    }
+    log.Printf("admin: deleted %s for %s", e.File, logPhone(phone))
+    recordAudit(phone, r.Pattern, before, phoneDatasets(phone))
+    fixturesChanged(phone)
+    w.WriteHeader(http.StatusNoContent)
+}
+
+// deletePersonaHandler serves DELETE /admin/personas/{phone}. Only personas
+// created through the API can be deleted; built-in ones keep their data.
+func deletePersonaHandler(w http.ResponseWriter, r *http.Request) {
+    phone := r.PathValue("phone")
+    personas.Lock()
+    defer personas.Unlock()
+    if !personas.phones[phone] {
+        if contains(pkg.GetAllowedMobileNumbers(), phone) {
+            http.Error(w, "built-in personas can't be deleted", http.StatusForbidden)
+            return
+        }
+        http.Error(w, "unknown persona", http.StatusNotFound)
+        return
+    }
+    before := phoneDatasets(phone)
+    if err := os.RemoveAll(filepath.Join(dataDir, phone)); err != nil {
+        log.Printf("delete persona %s: %v", logPhone(phone), err)
+        http.Error(w, "could not delete persona", http.StatusInternalServerError)
+        return
+    }
+    delete(personas.phones, phone)
+    if err := savePersonasLocked(); err != nil {
+        log.Printf("%s: %v", personasFile, err)
+    }
+    log.Printf("admin: deleted persona %s", logPhone(phone))
+    recordAudit(phone, r.Pattern, before, nil)
+    fixturesChanged(phone)
+    w.WriteHeader(http.StatusNoContent)
+}
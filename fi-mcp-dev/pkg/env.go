@@ -0,0 +1,642 @@
+package pkg
+
+import (
+    "errors"
+    "log"
+    "math"
+    "os"
+    "strconv"
+    "strings"
+    "time"
+)
+
+// ————— env driven settings —————
+// Every setting has a default so the server runs with no environment at all.
+
+// GetCookieName returns FI_MCP_COOKIE_NAME, the session cookie name
+// (default "sessionid").
+func GetCookieName() string {
+    return getString("FI_MCP_COOKIE_NAME", "sessionid")
+}
+
+// GetCookiePath returns FI_MCP_COOKIE_PATH, the session cookie path
+// (default "/").
+func GetCookiePath() string {
+    return getString("FI_MCP_COOKIE_PATH", "/")
+}
+
+// GetGoogleAPIKey returns GOOGLE_API_KEY, the Gemini key shared with the
+// Python agent. Empty keeps model-backed features on their offline fallback.
+func GetGoogleAPIKey() string {
+    return strings.TrimSpace(os.Getenv("GOOGLE_API_KEY"))
+}
+
+// GetAdminToken returns FI_MCP_ADMIN_TOKEN, the X-Admin-Token value required
+// on /admin endpoints. Empty disables them.
+func GetAdminToken() string {
+    return os.Getenv("FI_MCP_ADMIN_TOKEN")
+}
+
+// GetGeminiModel reads FI_MCP_GEMINI_MODEL, the model /api/insights calls
+// (default "gemini-1.5-flash").
+func GetGeminiModel() string {
+    return getString("FI_MCP_GEMINI_MODEL", "gemini-1.5-flash")
+}
+
+// GetGeminiTimeout reads FI_MCP_GEMINI_TIMEOUT, how long /api/insights waits
+// for the model before falling back to the rules (default 10s).
+func GetGeminiTimeout() time.Duration {
+    return getDuration("FI_MCP_GEMINI_TIMEOUT", 10*time.Second)
+}
+
+// GetRateLimit reads FI_MCP_RATE_LIMIT_RPS, the sustained requests per
+// second allowed per caller (0, the default, disables limiting), and
+// FI_MCP_RATE_LIMIT_BURST, how many may come at once (default 10).
+func GetRateLimit() (rps float64, burst int) {
+    rps, err := strconv.ParseFloat(strings.TrimSpace(os.Getenv("FI_MCP_RATE_LIMIT_RPS")), 64)
+    if err != nil || !(rps > 0) {
+        rps = 0
+    }
+    return rps, max(1, getInt("FI_MCP_RATE_LIMIT_BURST", 10))
+}
+
+// GetCORSOrigins reads FI_MCP_CORS_ORIGINS, a comma-separated list of exact
+// origins ("http://localhost:3000") allowed to make browser requests. Empty
+// disables CORS.
+func GetCORSOrigins() []string {
+    var out []string
+    for _, o := range strings.Split(os.Getenv("FI_MCP_CORS_ORIGINS"), ",") {
+        if o = strings.TrimSpace(o); o != "" {
+            out = append(out, o)
+        }
+    }
+    return out
+}
+
+// GetCORSHeaders reads FI_MCP_CORS_HEADERS, a comma-separated list of extra
+// request headers preflights allow on top of the built-in ones.
+func GetCORSHeaders() []string {
+    var out []string
+    for _, h := range strings.Split(os.Getenv("FI_MCP_CORS_HEADERS"), ",") {
+        if h = strings.TrimSpace(h); h != "" {
+            out = append(out, h)
+        }
+    }
+    return out
+}
+
+// GetCORSCredentials reads FI_MCP_CORS_CREDENTIALS, whether allowed origins
+// may send the session cookie. On by default; "0", "off", "false" or "no"
+// leaves Access-Control-Allow-Credentials out, for frontends that only use
+// stream tokens.
+func GetCORSCredentials() bool {
+    switch strings.ToLower(strings.TrimSpace(os.Getenv("FI_MCP_CORS_CREDENTIALS"))) {
+    case "0", "off", "false", "no":
+        return false
+    }
+    return true
+}
+
+// GetSwaggerUIURL reads FI_MCP_SWAGGER_UI_URL, where /docs loads the
+// swagger-ui-dist files from. Empty (the default) means the vendored copy
+// in static/swagger-ui, or the unpkg CDN without one.
+func GetSwaggerUIURL() string {
+    return getString("FI_MCP_SWAGGER_UI_URL", "")
+}
+
+// GetSessionStore reads FI_MCP_SESSION_STORE, the session backend: memory,
+// file, redis or sqlite. Unset means file when FI_MCP_SESSION_FILE is set and
+// memory otherwise.
+func GetSessionStore() string {
+    def := "memory"
+    if os.Getenv("FI_MCP_SESSION_FILE") != "" {
+        def = "file"
+    }
+    return strings.ToLower(getString("FI_MCP_SESSION_STORE", def))
+}
+
+// GetSessionFile reads FI_MCP_SESSION_FILE, the JSON file the file backend
+// saves sessions to and reloads them from (default "sessions.json").
+func GetSessionFile() string {
+    return getString("FI_MCP_SESSION_FILE", "sessions.json")
+}
+
+// GetRedisURL reads FI_MCP_REDIS_URL for the redis session backend (default
+// "redis://localhost:6379/0").
+func GetRedisURL() string {
+    return getString("FI_MCP_REDIS_URL", "redis://localhost:6379/0")
+}
+
+// GetSessionDB reads FI_MCP_SESSION_DB, the SQLite file of the sqlite
+// session backend (default "sessions.db").
+func GetSessionDB() string {
+    return getString("FI_MCP_SESSION_DB", "sessions.db")
+}
+
+// GetSessionSliding reports whether FI_MCP_SESSION_SLIDING is set, making
+// sessions renew their TTL while in use.
+func GetSessionSliding() bool {
+    return getBool("FI_MCP_SESSION_SLIDING")
+}
+
+// GetShutdownTimeout reads FI_MCP_SHUTDOWN_TIMEOUT, how long in-flight
+// requests get to finish after SIGINT/SIGTERM (default 10s).
+func GetShutdownTimeout() time.Duration {
+    return getDuration("FI_MCP_SHUTDOWN_TIMEOUT", 10*time.Second)
+}
+
+// GetSessionTTL reads FI_MCP_SESSION_TTL, how long a login stays valid
+// (default 24h).
+func GetSessionTTL() time.Duration {
+    return getDuration("FI_MCP_SESSION_TTL", 24*time.Hour)
+}
+
+// GetMFA reports whether FI_MCP_MFA is set, making /login issue an OTP
+// challenge that /login/verify must answer before the session is valid.
+func GetMFA() bool {
+    return getBool("FI_MCP_MFA")
+}
+
+// GetMFACode is the mock OTP /login/verify accepts (FI_MCP_MFA_CODE,
+// default "123456").
+func GetMFACode() string {
+    return getString("FI_MCP_MFA_CODE", "123456")
+}
+
+// GetStrictParams reports whether FI_MCP_STRICT_PARAMS is set, making data
+// endpoints reject query params they don't declare.
+func GetStrictParams() bool {
+    return getBool("FI_MCP_STRICT_PARAMS")
+}
+
+// GetMinifyJSON reports whether FI_MCP_MINIFY_JSON is set, compacting
+// fixtures before they are served.
+func GetMinifyJSON() bool {
+    return getBool("FI_MCP_MINIFY_JSON")
+}
+
+// GetSSEReplaySize reads FI_MCP_SSE_REPLAY, how many recent events each stream
+// replays to a client on connect. Defaults to 0 (no replay).
+func GetSSEReplaySize() int {
+    return getInt("FI_MCP_SSE_REPLAY", 0)
+}
+
+// GetSimulation reads FI_MCP_SIMULATION; "off" stops phones' simulation.yaml
+// files from being applied. On by default.
+func GetSimulation() bool {
+    switch strings.ToLower(strings.TrimSpace(os.Getenv("FI_MCP_SIMULATION"))) {
+    case "0", "false", "no", "off":
+        return false
+    }
+    return true
+}
+
+// GetSSEKeepAlive reads FI_MCP_SSE_KEEPALIVE, how long a stream may go
+// without an event before a ": keep-alive" comment is sent. Defaults to 15s;
+// "0" or "off" disables it.
+func GetSSEKeepAlive() time.Duration {
+    switch strings.ToLower(strings.TrimSpace(os.Getenv("FI_MCP_SSE_KEEPALIVE"))) {
+    case "0", "off":
+        return 0
+    }
+    return getDuration("FI_MCP_SSE_KEEPALIVE", 15*time.Second)
+}
+
+// GetWSPing reads FI_MCP_WS_PING, how often WebSocket streams are pinged; a
+// socket that doesn't pong within twice that is closed. Defaults to 20s;
+// "0" or "off" disables pings.
+func GetWSPing() time.Duration {
+    switch strings.ToLower(strings.TrimSpace(os.Getenv("FI_MCP_WS_PING"))) {
+    case "0", "off":
+        return 0
+    }
+    return getDuration("FI_MCP_WS_PING", 20*time.Second)
+}
+
+// GetMaxStreamsPerSession reads FI_MCP_MAX_STREAMS_PER_SESSION, how many SSE
+// streams one session may hold open at once. Defaults to 0 (unlimited).
+func GetMaxStreamsPerSession() int {
+    return getInt("FI_MCP_MAX_STREAMS_PER_SESSION", 0)
+}
+
+// GetStreamTokenSecret returns FI_MCP_STREAM_TOKEN_SECRET, the HMAC key for
+// shareable stream tokens. Empty disables them.
+func GetStreamTokenSecret() string {
+    return os.Getenv("FI_MCP_STREAM_TOKEN_SECRET")
+}
+
+// GetJWTSecret returns FI_MCP_JWT_SECRET, the HS256 key for /auth/token
+// bearer tokens. Empty disables them.
+func GetJWTSecret() string {
+    return os.Getenv("FI_MCP_JWT_SECRET")
+}
+
+// GetJWTTTL reads FI_MCP_JWT_TTL, how long a bearer token lasts. Defaults to 1h.
+func GetJWTTTL() time.Duration {
+    return getDuration("FI_MCP_JWT_TTL", time.Hour)
+}
+
+// GetOAuthRedirectURIs reads FI_MCP_OAUTH_REDIRECT_URIS, a comma-separated
+// list of exact redirect URIs /oauth/authorize accepts. Empty accepts any
+// http(s) URL.
+func GetOAuthRedirectURIs() []string {
+    var out []string
+    for _, u := range strings.Split(os.Getenv("FI_MCP_OAUTH_REDIRECT_URIS"), ",") {
+        if u = strings.TrimSpace(u); u != "" {
+            out = append(out, u)
+        }
+    }
+    return out
+}
+
+// GetWebhookPoll reads FI_MCP_WEBHOOK_POLL, how often webhook datasets are
+// checked for changes. Defaults to 5s.
+func GetWebhookPoll() time.Duration {
+    return getDuration("FI_MCP_WEBHOOK_POLL", 5*time.Second)
+}
+
+// GetWebhookRetries reads FI_MCP_WEBHOOK_RETRIES, how many times a failed
+// webhook delivery is retried. Defaults to 5.
+func GetWebhookRetries() int {
+    return getInt("FI_MCP_WEBHOOK_RETRIES", 5)
+}
+
+// GetAnomalyZScore reads FI_MCP_ANOMALY_Z, the default z-score beyond which a
+// transaction counts as an anomaly. Defaults to 2.
+func GetAnomalyZScore() float64 {
+    z, err := strconv.ParseFloat(strings.TrimSpace(os.Getenv("FI_MCP_ANOMALY_Z")), 64)
+    if err != nil || z <= 0 {
+        return 2
+    }
+    return z
+}
+
+// GetSkipEmptyRoutes reports whether FI_MCP_SKIP_EMPTY_ROUTES is set, leaving
+// out the /api and /stream routes of data types no phone has a fixture for.
+// Routes are decided at startup, so fixtures added later need a restart.
+func GetSkipEmptyRoutes() bool {
+    return getBool("FI_MCP_SKIP_EMPTY_ROUTES")
+}
+
+// SSERamp is the slow-start schedule for SSE streams.
+type SSERamp struct {
+    Enabled bool
+    Start   time.Duration
+    Factor  float64
+}
+
+// GetSSERamp reads FI_MCP_SSE_RAMP (on/off), FI_MCP_SSE_RAMP_START (first gap,
+// default 250ms) and FI_MCP_SSE_RAMP_FACTOR (growth per event, default 2).
+// A factor of 1 or less would never ramp, so it is logged and ignored.
+func GetSSERamp() SSERamp {
+    ramp := SSERamp{
+        Enabled: getBool("FI_MCP_SSE_RAMP"),
+        Start:   getDuration("FI_MCP_SSE_RAMP_START", 250*time.Millisecond),
+        Factor:  2,
+    }
+    if v := strings.TrimSpace(os.Getenv("FI_MCP_SSE_RAMP_FACTOR")); v != "" {
+        f, err := strconv.ParseFloat(v, 64)
+        if err != nil || !(f > 1) || math.IsInf(f, 0) {
+            log.Printf("FI_MCP_SSE_RAMP_FACTOR: ignoring %q, want a number above 1", v)
+        } else {
+            ramp.Factor = f
+        }
+    }
+    return ramp
+}
+
+// ServerLimits are the http.Server hardening settings.
+type ServerLimits struct {
+    ReadHeaderTimeout time.Duration
+    ReadTimeout       time.Duration
+    IdleTimeout       time.Duration
+    MaxHeaderBytes    int
+}
+
+// GetServerLimits reads FI_MCP_READ_HEADER_TIMEOUT (default 10s),
+// FI_MCP_READ_TIMEOUT (default 30s), FI_MCP_IDLE_TIMEOUT (default 2m) and
+// FI_MCP_MAX_HEADER_BYTES (default 64 KiB). http.Server treats a header limit
+// of 0 as its own 1 MiB default, so values below 1 are logged and ignored.
+func GetServerLimits() ServerLimits {
+    limits := ServerLimits{
+        ReadHeaderTimeout: getDuration("FI_MCP_READ_HEADER_TIMEOUT", 10*time.Second),
+        ReadTimeout:       getDuration("FI_MCP_READ_TIMEOUT", 30*time.Second),
+        IdleTimeout:       getDuration("FI_MCP_IDLE_TIMEOUT", 2*time.Minute),
+        MaxHeaderBytes:    64 << 10,
+    }
+    if v := strings.TrimSpace(os.Getenv("FI_MCP_MAX_HEADER_BYTES")); v != "" {
+        if n, err := strconv.Atoi(v); err != nil || n < 1 {
+            log.Printf("FI_MCP_MAX_HEADER_BYTES: ignoring %q, want a byte count of at least 1", v)
+        } else {
+            limits.MaxHeaderBytes = n
+        }
+    }
+    return limits
+}
+
+// GetTLSFiles returns FI_MCP_TLS_CERT and FI_MCP_TLS_KEY, PEM files for
+// serving HTTPS. The server refuses to start with only one of them set.
+func GetTLSFiles() (cert, key string) {
+    return strings.TrimSpace(os.Getenv("FI_MCP_TLS_CERT")), strings.TrimSpace(os.Getenv("FI_MCP_TLS_KEY"))
+}
+
+// GetForecastSimulations reads FI_MCP_FORECAST_SIMULATIONS, how many Monte
+// Carlo runs /api/net_worth/forecast does when ?simulations= is not given.
+// Defaults to 1000, at most 10000.
+func GetForecastSimulations() int {
+    n := getInt("FI_MCP_FORECAST_SIMULATIONS", 1000)
+    if n < 1 || n > 10000 {
+        return 1000
+    }
+    return n
+}
+
+// GetScenarioDir returns FI_MCP_SCENARIO_DIR, the root of the per-scenario
+// fixture overrides selected with the X-Scenario header (default "scenarios").
+func GetScenarioDir() string {
+    return getString("FI_MCP_SCENARIO_DIR", "scenarios")
+}
+
+// GetProgressiveNetWorth reports whether FI_MCP_PROGRESSIVE_NET_WORTH is set,
+// making /api/net_worth fill in one more section per poll of a session.
+func GetProgressiveNetWorth() bool {
+    return getBool("FI_MCP_PROGRESSIVE_NET_WORTH")
+}
+
+// GetBenchmarkTTL reads FI_MCP_BENCHMARK_TTL, how long /api/benchmarks reuses
+// its cohort averages (default 5m).
+func GetBenchmarkTTL() time.Duration {
+    return getDuration("FI_MCP_BENCHMARK_TTL", 5*time.Minute)
+}
+
+// CacheTTL is one endpoint's response cache lifetime; Grace is how long past
+// TTL a stale copy may still be served while it refreshes.
+type CacheTTL struct {
+    TTL   time.Duration
+    Grace time.Duration
+}
+
+// GetCacheTTLs reads FI_MCP_CACHE, name=ttl[/grace] pairs such as
+// "forecast=1m/5m,benchmarks=5m". Defaults to "forecast=1m/5m"; set it to
+// "off" to disable caching. Bad entries are logged and ignored.
+func GetCacheTTLs() map[string]CacheTTL {
+    v := getString("FI_MCP_CACHE", "forecast=1m/5m")
+    out := map[string]CacheTTL{}
+    if v == "off" {
+        return out
+    }
+    for name, spec := range parsePairs(v) {
+        ttlSpec, graceSpec, _ := strings.Cut(spec, "/")
+        ttl, err := time.ParseDuration(ttlSpec)
+        if err != nil || ttl <= 0 {
+            log.Printf("FI_MCP_CACHE: ignoring invalid TTL %q for %s", spec, name)
+            continue
+        }
+        var grace time.Duration
+        if graceSpec != "" {
+            if grace, err = time.ParseDuration(graceSpec); err != nil || grace < 0 {
+                log.Printf("FI_MCP_CACHE: ignoring invalid grace %q for %s", spec, name)
+                continue
+            }
+        }
+        out[name] = CacheTTL{TTL: ttl, Grace: grace}
+    }
+    return out
+}
+
+// Chaos is the fault injection applied to requests under Paths. X-Chaos-*
+// headers override each setting per request.
+type Chaos struct {
+    Enabled      bool
+    Paths        []string
+    Latency      DurationRange
+    ErrorRate    float64
+    ErrorStatus  []int
+    TruncateRate float64
+    DropAfter    DurationRange // SSE streams only; zero never drops
+}
+
+// DurationRange is a fixed duration when Min == Max.
+type DurationRange struct {
+    Min, Max time.Duration
+}
+
+// GetChaos reads FI_MCP_CHAOS (on/off), FI_MCP_CHAOS_PATHS (path prefixes,
+// default "/api/,/stream/"), FI_MCP_CHAOS_LATENCY ("200ms" or "100ms-2s"),
+// FI_MCP_CHAOS_ERROR_RATE (0–1), FI_MCP_CHAOS_ERROR_STATUS (e.g. "500,503",
+// default 500), FI_MCP_CHAOS_TRUNCATE_RATE (0–1) and FI_MCP_CHAOS_DROP_AFTER
+// (a duration or range). Bad values are logged and left at zero.
+func GetChaos() Chaos {
+    c := Chaos{Enabled: getBool("FI_MCP_CHAOS"), ErrorStatus: []int{500}}
+    for _, p := range strings.Split(getString("FI_MCP_CHAOS_PATHS", "/api/,/stream/"), ",") {
+        if p = strings.TrimSpace(p); p != "" {
+            c.Paths = append(c.Paths, p)
+        }
+    }
+    var err error
+    for key, parse := range map[string]func(string) error{
+        "FI_MCP_CHAOS_LATENCY":       func(v string) error { c.Latency, err = ParseDurationRange(v); return err },
+        "FI_MCP_CHAOS_ERROR_RATE":    func(v string) error { c.ErrorRate, err = ParseRate(v); return err },
+        "FI_MCP_CHAOS_ERROR_STATUS":  func(v string) error { c.ErrorStatus, err = ParseStatuses(v); return err },
+        "FI_MCP_CHAOS_TRUNCATE_RATE": func(v string) error { c.TruncateRate, err = ParseRate(v); return err },
+        "FI_MCP_CHAOS_DROP_AFTER":    func(v string) error { c.DropAfter, err = ParseDurationRange(v); return err },
+    } {
+        if v := strings.TrimSpace(os.Getenv(key)); v != "" {
+            if err := parse(v); err != nil {
+                log.Printf("%s: ignoring %q: %v", key, v, err)
+            }
+        }
+    }
+    if len(c.ErrorStatus) == 0 {
+        c.ErrorStatus = []int{500}
+    }
+    return c
+}
+
+// ParseDurationRange reads "500ms" or "100ms-2s".
+func ParseDurationRange(s string) (DurationRange, error) {
+    from, to, isRange := strings.Cut(strings.TrimSpace(s), "-")
+    var d DurationRange
+    var err error
+    if d.Min, err = time.ParseDuration(strings.TrimSpace(from)); err != nil || d.Min < 0 {
+        return DurationRange{}, errors.New("want a duration like 500ms or a range like 100ms-2s")
+    }
+    d.Max = d.Min
+    if isRange {
+        if d.Max, err = time.ParseDuration(strings.TrimSpace(to)); err != nil || d.Max < d.Min {
+            return DurationRange{}, errors.New("want a duration like 500ms or a range like 100ms-2s")
+        }
+    }
+    return d, nil
+}
+
+// ParseRate reads a probability between 0 and 1.
+func ParseRate(s string) (float64, error) {
+    f, err := strconv.ParseFloat(strings.TrimSpace(s), 64)
+    if err != nil || f < 0 || f > 1 {
+        return 0, errors.New("want a rate between 0 and 1")
+    }
+    return f, nil
+}
+
+// ParseStatuses reads a comma separated list of 4xx/5xx codes.
+func ParseStatuses(s string) ([]int, error) {
+    var out []int
+    for _, part := range strings.Split(s, ",") {
+        code, err := strconv.Atoi(strings.TrimSpace(part))
+        if err != nil || code < 400 || code > 599 {
+            return nil, errors.New("want 4xx or 5xx status codes, e.g. 500,503")
+        }
+        out = append(out, code)
+    }
+    return out, nil
+}
+
+// GetHealthScoreWeights reads FI_MCP_HEALTH_WEIGHTS, a comma separated list of
+// name=weight pairs, e.g. "savings_rate=0.3,credit_score=0.2". Pairs that are
+// not given keep their default weight; unknown names and bad values are
+// logged and ignored.
+func GetHealthScoreWeights() map[string]float64 {
+    weights := map[string]float64{
+        "savings_rate":   0.3,
+        "dti":            0.2,
+        "emergency_fund": 0.25,
+        "credit_score":   0.25,
+    }
+    for name, v := range parsePairs(os.Getenv("FI_MCP_HEALTH_WEIGHTS")) {
+        if _, known := weights[name]; !known {
+            log.Printf("FI_MCP_HEALTH_WEIGHTS: ignoring unknown weight %q", name)
+            continue
+        }
+        f, err := strconv.ParseFloat(v, 64)
+        if err != nil || f < 0 {
+            log.Printf("FI_MCP_HEALTH_WEIGHTS: ignoring invalid value %q for %s", v, name)
+            continue
+        }
+        weights[name] = f
+    }
+    return weights
+}
+
+// GetThrottle reads FI_MCP_THROTTLE, endpoint=N pairs such as
+// "net_worth=3,bank_transactions=1". The first N /api requests of each
+// session to that endpoint get a 429. Bad counts are logged and ignored.
+func GetThrottle() map[string]int {
+    out := map[string]int{}
+    for name, v := range parsePairs(os.Getenv("FI_MCP_THROTTLE")) {
+        n, err := strconv.Atoi(v)
+        if err != nil || n < 0 {
+            log.Printf("FI_MCP_THROTTLE: ignoring invalid count %q for %s", v, name)
+            continue
+        }
+        out[name] = n
+    }
+    return out
+}
+
+// GetContentTypes reads FI_MCP_CONTENT_TYPES, endpoint=type pairs overriding
+// the /api/<endpoint> Content-Type, e.g. "net_worth=application/vnd.api+json".
+// A type may carry parameters ("application/x.fi+json; charset=utf-8").
+func GetContentTypes() map[string]string {
+    return parsePairs(os.Getenv("FI_MCP_CONTENT_TYPES"))
+}
+
+// GetThrottleRetryAfter reads FI_MCP_THROTTLE_RETRY_AFTER, the Retry-After
+// seconds sent with simulated 429s. Defaults to 1.
+func GetThrottleRetryAfter() int {
+    return getInt("FI_MCP_THROTTLE_RETRY_AFTER", 1)
+}
+
+// GetHashPhoneLogs reports whether FI_MCP_HASH_PHONE_LOGS is set, logging an
+// HMAC of each phone (keyed by FI_MCP_LOG_PHONE_SECRET) instead of the number.
+func GetHashPhoneLogs() bool {
+    return getBool("FI_MCP_HASH_PHONE_LOGS")
+}
+
+// GetAccessLog reads FI_MCP_ACCESS_LOG: JSON access log lines on stdout,
+// on by default; "0", "off", "false" or "no" turns them off.
+func GetAccessLog() bool {
+    switch strings.ToLower(strings.TrimSpace(os.Getenv("FI_MCP_ACCESS_LOG"))) {
+    case "0", "off", "false", "no":
+        return false
+    }
+    return true
+}
+
+// GetLogPhoneSecret returns FI_MCP_LOG_PHONE_SECRET, the HMAC key for hashed
+// phones in logs.
+func GetLogPhoneSecret() string {
+    return os.Getenv("FI_MCP_LOG_PHONE_SECRET")
+}
+
+// GetEmptyFixture reads FI_MCP_EMPTY_FIXTURE, what to serve for a 0-byte (or
+// whitespace only) fixture: "error" (default), "null" or "{}".
+func GetEmptyFixture() string {
+    switch v := getString("FI_MCP_EMPTY_FIXTURE", "error"); v {
+    case "null", "{}":
+        return v
+    default:
+        return "error"
+    }
+}
+
+// GetAuditLog reads FI_MCP_AUDIT_LOG, the file admin fixture changes are
+// appended to. Empty (the default) means .audit.jsonl in test_data_dir;
+// "off" records nothing.
+func GetAuditLog() string {
+    return strings.TrimSpace(os.Getenv("FI_MCP_AUDIT_LOG"))
+}
+
+// GetPreload reads FI_MCP_PRELOAD, whether every fixture is read into the
+// fixture cache at startup so no first request finds it cold. Off by
+// default, as it holds all of test_data_dir in memory from the start.
+func GetPreload() bool {
+    return getBool("FI_MCP_PRELOAD")
+}
+
+// parsePairs splits "a=1,b=2" into a map, skipping malformed entries.
+func parsePairs(s string) map[string]string {
+    out := map[string]string{}
+    for _, part := range strings.Split(s, ",") {
+        k, v, ok := strings.Cut(strings.TrimSpace(part), "=")
+        if !ok || k == "" {
+            continue
+        }
+        out[strings.TrimSpace(k)] = strings.TrimSpace(v)
+    }
+    return out
+}
+
+// getBool treats "1", "true", "yes" and "on" (any case) as true.
+func getBool(key string) bool {
+    switch strings.ToLower(strings.TrimSpace(os.Getenv(key))) {
+    case "1", "true", "yes", "on":
+        return true
+    }
+    return false
+}
+
+// getInt returns the env value as a non-negative int, or def when unset or invalid.
+func getInt(key string, def int) int {
+    n, err := strconv.Atoi(strings.TrimSpace(os.Getenv(key)))
+    if err != nil || n < 0 {
+        return def
+    }
+    return n
+}
+
+func getString(key, def string) string {
+    if v := strings.TrimSpace(os.Getenv(key)); v != "" {
+        return v
+    }
+    return def
+}
+
+// getDuration parses a positive Go duration ("250ms"), or returns def.
+func getDuration(key string, def time.Duration) time.Duration {
+    d, err := time.ParseDuration(strings.TrimSpace(os.Getenv(key)))
+    if err != nil || d <= 0 {
+        return def
+    }
+    return d
+}
@@ -0,0 +1,160 @@
+package main
+
+import (
+    "context"
+    "encoding/binary"
+    "encoding/json"
+    "errors"
+    "hash/fnv"
+    "net/http"
+    "sort"
+    "strconv"
+    "time"
+
+    "github.com/epifi/fi-mcp-lite/middlewares"
+)
+
+// ————— live portfolio value —————
+// Each tick every Indian equity holding's price takes a small step derived
+// from a hash of (seed, isin, tick), so a given seed always produces the same
+// series and an ISIN held in two demat accounts moves the same way in both.
+
+// maxPriceStep bounds each tick's move to ±1%.
+const maxPriceStep = 0.01
+
+type holding struct {
+    Isin  string
+    Units float64
+    Price float64
+}
+
+// loadHoldings returns the phone's equity holdings, with Isin-sorted order so
+// the float sum is the same on every run.
+func loadHoldings(ctx context.Context, phone string) ([]holding, error) {
+    nw, err := loadNetWorth(ctx, phone)
+    if err != nil {
+        return nil, err
+    }
+    var out []holding
+    for _, acc := range nw.AccountDetailsBulkResponse.AccountDetailsMap {
+        if acc.EquitySummary == nil {
+            continue
+        }
+        for _, h := range acc.EquitySummary.HoldingsInfo {
+            out = append(out, holding{Isin: h.Isin, Units: h.Units, Price: h.LastTradedPrice.Float()})
+        }
+    }
+    if len(out) == 0 {
+        return nil, errors.New("no equity holdings")
+    }
+    sort.SliceStable(out, func(i, j int) bool {
+        if out[i].Isin != out[j].Isin {
+            return out[i].Isin < out[j].Isin
+        }
+        return out[i].Units < out[j].Units
+    })
+    return out, nil
+}
+
+// priceStep is the deterministic relative move of isin at tick, in
+// [-maxPriceStep, maxPriceStep].
+func priceStep(seed int64, isin string, tick int) float64 {
+    return hashUnit(seed, isin, tick) * maxPriceStep
+}
+
+// hashUnit is a deterministic pseudo-random value in [-1, 1) for seed, key
+// and n.
+func hashUnit(seed int64, key string, n int) float64 {
+    h := fnv.New64a()
+    var buf [16]byte
+    binary.LittleEndian.PutUint64(buf[:8], uint64(seed))
+    binary.LittleEndian.PutUint64(buf[8:], uint64(n))
+    h.Write(buf[:])
+    h.Write([]byte(key))
+    u := float64(h.Sum64()>>11) / (1 << 53) // uniform in [0, 1)
+    return 2*u - 1
+}
+
+// portfolioValueStream serves /stream/portfolio_value?seed=N as
+// {value, delta, tick, ts} events, every defaultInterval or ?interval=. The
+// first event is the unmoved value.
+func portfolioValueStream(defaultInterval time.Duration) http.Handler {
+    return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+        phone := r.Context().Value("phone").(string)
+        interval, err := streamInterval(r.URL.Query(), defaultInterval)
+        if err != nil {
+            http.Error(w, err.Error(), http.StatusBadRequest)
+            return
+        }
+        var seed int64
+        if s := r.URL.Query().Get("seed"); s != "" {
+            n, err := strconv.ParseInt(s, 10, 64)
+            if err != nil {
+                http.Error(w, "seed must be an integer", http.StatusBadRequest)
+                return
+            }
+            seed = n
+        }
+        holdings, err := loadHoldings(r.Context(), phone)
+        if err != nil {
+            http.Error(w, "data not found", http.StatusNotFound)
+            return
+        }
+
+        rc, ok := startSSE(w, r)
+        if !ok {
+            return
+        }
+        defer metrics.StreamStarted(middlewares.StreamSSE)()
+
+        // prices carries the walk forward so each tick is O(holdings)
+        prices := make([]float64, len(holdings))
+        for i, h := range holdings {
+            prices[i] = h.Price
+        }
+        next := tickSchedule(interval)
+        timer := time.NewTimer(0)
+        defer timer.Stop()
+        ka := newKeepAlive()
+        defer ka.stop()
+
+        last, tick := 0.0, 0
+        for {
+            select {
+            case <-r.Context().Done():
+                return
+            case <-ka.C():
+                if !ka.send(w, rc) {
+                    return
+                }
+            case now := <-timer.C:
+                timer.Reset(next())
+                var value float64
+                for i, h := range holdings {
+                    if tick > 0 {
+                        prices[i] *= 1 + priceStep(seed, h.Isin, tick)
+                    }
+                    value += h.Units * prices[i]
+                }
+                value = round2(value)
+                delta := 0.0
+                if tick > 0 {
+                    delta = round2(value - last)
+                }
+                last = value
+                data, _ := json.Marshal(map[string]interface{}{
+                    "value": value,
+                    "delta": delta,
+                    "tick":  tick,
+                    "ts":    now.UTC().Format(time.RFC3339),
+                })
+                writeEvent(w, data)
+                if err := rc.Flush(); err != nil {
+                    return
+                }
+                ka.reset()
+                tick++
+            }
+        }
+    })
+}
@@ -0,0 +1,152 @@
+package main
+
+import (
+    "bytes"
+    "encoding/json"
+    "errors"
+    "io/fs"
+    "log"
+    "net/http"
+    "os"
+    "strings"
+    "time"
+
+    "github.com/epifi/fi-mcp-lite/pkg"
+)
+
+// ————— probes —————
+// /healthz is liveness: the process is up and serving, nothing more.
+// /readyz is readiness: test_data_dir is there and every allowed phone's
+// fixtures can be served. A fixture that isn't a JSON object fails it, as
+// every handler decoding it would fail with 500. One that parses but misses
+// the admin upload checks is only a warning: it is served as it is, and a
+// few shipped fixtures are like that. The fixtures are checked again on
+// every /readyz call, through the fixture cache so unchanged files aren't
+// reread, which picks up edits made straight on disk. main runs the same
+// check once at startup and logs what it finds; unless FI_MCP_PRELOAD has
+// filled the cache, that one reads the files without caching them.
+
+const (
+    fixtureOK        = "ok"
+    fixtureMissing   = "missing" // the phone has no data of this type
+    fixtureEmpty     = "empty"
+    fixtureMalformed = "malformed" // unreadable, or not a JSON object
+    fixtureInvalid   = "invalid"   // fails the upload schema checks
+)
+
+type fixtureStatus struct {
+    Status string `json:"status"`
+    Error  string `json:"error,omitempty"`
+    // problem is whether this fixture keeps the server from being ready
+    problem bool
+}
+
+type readiness struct {
+    Ready    bool                                `json:"ready"`
+    DataDir  bool                                `json:"dataDir"`
+    Problems int                                 `json:"problems"`
+    Warnings int                                 `json:"warnings"`
+    Personas map[string]map[string]fixtureStatus `json:"personas"` // phone → data type → status
+    // Unlisted are directories in test_data_dir that aren't an allowed phone,
+    // so nothing serves their fixtures.
+    Unlisted []string `json:"unlisted"`
+}
+
+// checkFixture reports on one phone's fixture of one data type. A missing
+// file is fine: it is how a phone has no data. An empty file is only a
+// problem with FI_MCP_EMPTY_FIXTURE=error, which makes it a 500.
+// Malformed fixtures are problems; invalid ones are not.
+func checkFixture(read func(string) ([]byte, error), phone string, e dataEndpoint) fixtureStatus {
+    data, err := read(dataFilePath(phone, e.File))
+    switch {
+    case errors.Is(err, fs.ErrNotExist):
+        return fixtureStatus{Status: fixtureMissing}
+    case err != nil:
+        return fixtureStatus{Status: fixtureMalformed, Error: readErr(err).Error(), problem: true}
+    case len(bytes.TrimSpace(data)) == 0:
+        return fixtureStatus{Status: fixtureEmpty, problem: pkg.GetEmptyFixture() == "error"}
+    }
+    var doc map[string]json.RawMessage
+    if err := json.Unmarshal(data, &doc); err != nil || doc == nil {
+        return fixtureStatus{Status: fixtureMalformed, Error: "not a JSON object", problem: true}
+    }
+    if err := validateFixture(e, data); err != nil {
+        return fixtureStatus{Status: fixtureInvalid, Error: err.Error()}
+    }
+    return fixtureStatus{Status: fixtureOK}
+}
+
+// checkReadiness checks every allowed phone's fixtures, reading them with
+// read.
+func checkReadiness(read func(string) ([]byte, error)) readiness {
+    rd := readiness{DataDir: dataDirAvailable(), Personas: map[string]map[string]fixtureStatus{}, Unlisted: []string{}}
+    if !rd.DataDir {
+        return rd
+    }
+    allowed := map[string]bool{}
+    for _, phone := range allowedMobileNumbers() {
+        allowed[phone] = true
+        statuses := map[string]fixtureStatus{}
+        for _, e := range dataEndpoints {
+            st := checkFixture(read, phone, e)
+            if st.problem {
+                rd.Problems++
+            } else if st.Status == fixtureInvalid {
+                rd.Warnings++
+            }
+            statuses[e.Name] = st
+        }
+        rd.Personas[phone] = statuses
+    }
+    if entries, err := os.ReadDir(dataDir); err == nil {
+        for _, d := range entries {
+            if d.IsDir() && !allowed[d.Name()] {
+                rd.Unlisted = append(rd.Unlisted, d.Name())
+            }
+        }
+    }
+    rd.Ready = rd.Problems == 0
+    return rd
+}
+
+// validateFixturesAtStartup logs every fixture problem and warning. The
+// server starts regardless; /readyz fails until the problems are fixed.
+func validateFixturesAtStartup() {
+    start := time.Now()
+    read := os.ReadFile
+    if pkg.GetPreload() {
+        read = cachedReadFile
+    }
+    rd := checkReadiness(read)
+    if !rd.DataDir {
+        log.Printf("%s: not found; /readyz will fail until it exists", dataDir)
+        return
+    }
+    for _, phone := range allowedMobileNumbers() {
+        for _, e := range dataEndpoints {
+            if st := rd.Personas[phone][e.Name]; st.problem || st.Status == fixtureInvalid {
+                log.Printf("fixture check: phone=%s file=%s: %s", logPhone(phone), e.File, strings.TrimSpace(st.Status+" "+st.Error))
+            }
+        }
+    }
+    for _, name := range rd.Unlisted {
+        log.Printf("%s: directory %s is not an allowed phone, ignored", dataDir, logPhone(name))
+    }
+    log.Printf("checked fixtures of %d phone(s) in %s: %d problem(s), %d warning(s)", len(rd.Personas), time.Since(start).Round(time.Millisecond), rd.Problems, rd.Warnings)
+}
+
+// healthzHandler serves /healthz.
+func healthzHandler(w http.ResponseWriter, r *http.Request) {
+    writeJSON(w, map[string]string{"status": "ok"})
+}
+
+// readyzHandler serves /readyz: 200 when ready, else 503, with the same
+// report either way.
+func readyzHandler(w http.ResponseWriter, r *http.Request) {
+    rd := checkReadiness(cachedReadFile)
+    if !rd.Ready {
+        setDefaultContentType(w, "application/json")
+        w.WriteHeader(http.StatusServiceUnavailable)
+    }
+    writeJSON(w, rd)
+}
@@ -0,0 +1,78 @@
+package main
+
+import (
+    "encoding/json"
+    "net/http"
+    "sort"
+    "sync"
+
+    "github.com/epifi/fi-mcp-lite/pkg"
+)
+
+// ————— progressive net worth —————
+// With FI_MCP_PROGRESSIVE_NET_WORTH on, /api/net_worth behaves like a provider
+// still fetching: the first poll of a session has only the headline
+// netWorthResponse filled in and every other section is {"status":"loading"},
+// each later poll fills in one more section, and once all are in the fixture
+// is served as is.
+
+// netWorthSections is the reveal order; sections not listed follow in
+// alphabetical order.
+var netWorthSections = []string{"netWorthResponse", "accountDetailsBulkResponse", "mfSchemeAnalytics"}
+
+var progressive = struct {
+    sync.Mutex
+    polls map[string]int
+}{polls: map[string]int{}}
+
+func netWorthHandler() http.Handler {
+    raw := apiHandler("fetch_net_worth.json")
+    return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+        if !pkg.GetProgressiveNetWorth() {
+            raw.ServeHTTP(w, r)
+            return
+        }
+        phone := r.Context().Value("phone").(string)
+        data, err := readDataFile(r.Context(), phone, "fetch_net_worth.json")
+        if err != nil {
+            http.Error(w, "data not found", http.StatusInternalServerError)
+            return
+        }
+        var doc map[string]json.RawMessage
+        if err := json.Unmarshal(data, &doc); err != nil {
+            http.Error(w, "data not found", http.StatusInternalServerError)
+            return
+        }
+
+        order := sectionOrder(doc)
+        key := sessionKey(r)
+        progressive.Lock()
+        revealed := progressive.polls[key] + 1
+        if revealed < len(order) {
+            progressive.polls[key] = revealed
+        }
+        progressive.Unlock()
+
+        loading := json.RawMessage(`{"status":"loading"}`)
+        for _, section := range order[min(revealed, len(order)):] {
+            doc[section] = loading
+        }
+        writeJSON(w, doc)
+    })
+}
+
+func sectionOrder(doc map[string]json.RawMessage) []string {
+    var order, rest []string
+    for _, s := range netWorthSections {
+        if _, ok := doc[s]; ok {
+            order = append(order, s)
+        }
+    }
+    for s := range doc {
+        if !contains(netWorthSections, s) {
+            rest = append(rest, s)
+        }
+    }
+    sort.Strings(rest)
+    return append(order, rest...)
+}
@@ -0,0 +1,116 @@
+package main
+
+import (
+    "math"
+    "net"
+    "net/http"
+    "strconv"
+    "sync"
+    "time"
+
+    "github.com/epifi/fi-mcp-lite/pkg"
+)
+
+// ————— rate limiting —————
+// FI_MCP_RATE_LIMIT_RPS gives every caller a token bucket of
+// FI_MCP_RATE_LIMIT_BURST requests refilled at that rate; past it requests
+// get 429 with Retry-After. Callers are keyed by the phone of their session
+// cookie or stream token, or by remote IP when they have neither.
+
+type tokenBucket struct {
+    tokens float64
+    last   time.Time
+}
+
+type rateLimiter struct {
+    mu      sync.Mutex
+    rps     float64
+    burst   float64
+    buckets map[string]*tokenBucket
+}
+
+func newRateLimiter(rps float64, burst int) *rateLimiter {
+    l := &rateLimiter{rps: rps, burst: float64(burst), buckets: map[string]*tokenBucket{}}
+    go func() {
+        for range time.Tick(time.Minute) {
+            l.evictIdle(time.Now())
+        }
+    }()
+    return l
+}
+
+// allow takes a token for key, or reports how long until one is available.
+func (l *rateLimiter) allow(key string, now time.Time) (bool, time.Duration) {
+    l.mu.Lock()
+    defer l.mu.Unlock()
+    b := l.buckets[key]
+    if b == nil {
+        b = &tokenBucket{tokens: l.burst, last: now}
+        l.buckets[key] = b
+    }
+    b.tokens = math.Min(l.burst, b.tokens+now.Sub(b.last).Seconds()*l.rps)
+    b.last = now
+    if b.tokens >= 1 {
+        b.tokens--
+        return true, 0
+    }
+    return false, time.Duration((1 - b.tokens) / l.rps * float64(time.Second))
+}
+
+// evictIdle drops buckets that have been idle long enough to refill; a new
+// bucket starts full, so nothing is lost.
+func (l *rateLimiter) evictIdle(now time.Time) {
+    refill := time.Duration(l.burst / l.rps * float64(time.Second))
+    l.mu.Lock()
+    defer l.mu.Unlock()
+    for key, b := range l.buckets {
+        if now.Sub(b.last) >= refill {
+            delete(l.buckets, key)
+        }
+    }
+}
+
+func withRateLimit(next http.Handler) http.Handler {
+    rps, burst := pkg.GetRateLimit()
+    if rps <= 0 {
+        return next
+    }
+    limiter := newRateLimiter(rps, burst)
+    return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+        if r.URL.Path == "/healthz" || r.URL.Path == "/readyz" {
+            // probes come from one address and must not be throttled
+            next.ServeHTTP(w, r)
+            return
+        }
+        ok, wait := limiter.allow(rateLimitKey(r), time.Now())
+        if !ok {
+            w.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(wait.Seconds()))))
+            http.Error(w, "rate limit exceeded", http.StatusTooManyRequests)
+            return
+        }
+        next.ServeHTTP(w, r)
+    })
+}
+
+func rateLimitKey(r *http.Request) string {
+    if c, err := r.Cookie(pkg.GetCookieName()); err == nil {
+        if phone := authMW.GetPhoneNumber(c.Value); phone != "" {
+            return "phone:" + phone
+        }
+    }
+    if token, ok := bearerToken(r); ok {
+        if phone, ok := bearerPhone(token); ok {
+            return "phone:" + phone
+        }
+    }
+    if token := r.URL.Query().Get("token"); token != "" {
+        if phone, ok := verifyStreamToken(pkg.GetStreamTokenSecret(), token, time.Now()); ok {
+            return "phone:" + phone
+        }
+    }
+    host, _, err := net.SplitHostPort(r.RemoteAddr)
+    if err != nil {
+        host = r.RemoteAddr
+    }
+    return "ip:" + host
+}
@@ -0,0 +1,75 @@
+package main
+
+import (
+    "net/http"
+    "sort"
+)
+
+// ————— credit report ↔ bank reconciliation —————
+// Tradelines have no account numbers, so they are matched to bank accounts
+// by lender name (compared as slugs, so case and punctuation don't matter).
+// A lender with several accounts matches all of them.
+
+type reconciledTradeline struct {
+    SubscriberName string   `json:"subscriberName"`
+    AccountType    string   `json:"accountType"`
+    CurrentBalance float64  `json:"currentBalance"`
+    AccountIDs     []string `json:"accountIds,omitempty"`
+}
+
+func reconcileHandler(w http.ResponseWriter, r *http.Request) {
+    phone := r.Context().Value("phone").(string)
+    cr, err := loadCreditReport(r.Context(), phone)
+    if err != nil {
+        http.Error(w, "data not found", http.StatusInternalServerError)
+        return
+    }
+    bt, err := loadBankTransactions(r.Context(), phone)
+    if err != nil {
+        http.Error(w, "data not found", http.StatusInternalServerError)
+        return
+    }
+
+    byBank := map[string][]string{}
+    for i, id := range bankAccountIDs(bt) {
+        slug := slugify(bt.BankTransactions[i].Bank)
+        byBank[slug] = append(byBank[slug], id)
+    }
+
+    matched := []reconciledTradeline{}
+    unmatched := []reconciledTradeline{}
+    used := map[string]bool{}
+    for _, rep := range cr.CreditReports {
+        for _, t := range rep.CreditReportData.CreditAccount.CreditAccountDetails {
+            row := reconciledTradeline{
+                SubscriberName: t.SubscriberName,
+                AccountType:    t.AccountType,
+                CurrentBalance: toFloat(t.CurrentBalance),
+            }
+            if ids := byBank[slugify(t.SubscriberName)]; len(ids) > 0 {
+                row.AccountIDs = ids
+                matched = append(matched, row)
+                for _, id := range ids {
+                    used[id] = true
+                }
+            } else {
+                unmatched = append(unmatched, row)
+            }
+        }
+    }
+    unmatchedAccounts := []string{}
+    for _, ids := range byBank {
+        for _, id := range ids {
+            if !used[id] {
+                unmatchedAccounts = append(unmatchedAccounts, id)
+            }
+        }
+    }
+    sort.Strings(unmatchedAccounts)
+
+    writeJSON(w, map[string]interface{}{
+        "matched":             matched,
+        "unmatchedTradelines": unmatched,
+        "unmatchedAccounts":   unmatchedAccounts,
+    })
+}
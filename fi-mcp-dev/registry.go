@@ -0,0 +1,185 @@
+package main
+
+import (
+    "fmt"
+    "log"
+    "net/http"
+    "net/url"
+    "os"
+    "sort"
+    "strings"
+    "time"
+
+    "github.com/epifi/fi-mcp-lite/pkg"
+)
+
+// ————— endpoint registry —————
+// One entry per fixture type. main registers /api/<Name>, /stream/<Name> and
+// /ws/<Name> from this table, plus /stream/all over all of them, and anything
+// that needs to know the data types (param allowlists, validation) reads it
+// from here.
+type dataEndpoint struct {
+    Name     string
+    File     string
+    Interval time.Duration // SSE tick unless ?interval= sets another
+    // Description is shown to agents for the fetch_<Name> MCP tool.
+    Description string
+    // APIParams and StreamParams are the query params each route honours.
+    APIParams    []string
+    StreamParams []string
+    // API overrides the plain file server for /api/<Name>.
+    API func() http.Handler
+    // Txns locates transaction rows, which makes /api/<Name> take
+    // txnPageParams and ?format=.
+    Txns *txnRows
+    // StreamFilter rewrites each /stream/<Name> payload from its params.
+    StreamFilter streamFilter
+    // Schema is what /admin/personas uploads of this type are checked against.
+    Schema fixtureSchema
+    // ContentType of /api/<Name> responses; defaultContentType when empty.
+    // FI_MCP_CONTENT_TYPES can override it without a rebuild.
+    ContentType string
+}
+
+const defaultContentType = "application/json; charset=utf-8"
+
+var dataEndpoints = []dataEndpoint{
+    {Name: "net_worth", File: "fetch_net_worth.json", Interval: 2 * time.Second, API: netWorthHandler,
+        Description: "Net worth of the user's connected accounts: assets and liabilities by type, the total, and per-account details.",
+        Schema: fixtureSchema{Required: map[string]string{"netWorthResponse": "object"}, Decode: decodeAs[netWorthFile]}},
+    {Name: "credit_report", File: "fetch_credit_report.json", Interval: 5 * time.Second,
+        Description: "Credit bureau report: credit score, active loans and cards with balances, and account history.",
+        Schema: fixtureSchema{Required: map[string]string{"creditReports": "array"}, Decode: decodeAs[creditReportFile]}},
+    {Name: "epf_details", File: "fetch_epf_details.json", Interval: 2 * time.Second,
+        Description: "Employees' Provident Fund accounts: employers, balances and contributions.",
+        Schema: fixtureSchema{Required: map[string]string{"uanAccounts": "array"}, Decode: decodeAs[epfDetailsFile]}},
+    {Name: "mf_transactions", File: "fetch_mf_transactions.json", Interval: 2 * time.Second,
+        Description: "Mutual fund transactions per scheme and folio: type, date, NAV, units and amount.",
+        Schema: fixtureSchema{Required: map[string]string{"mfTransactions": "array"}},
+        Txns: &mfTxnRows,
+        StreamParams: []string{"minAmount"}, StreamFilter: minAmountFilter("mfTransactions", 4)},
+    {Name: "bank_transactions", File: "fetch_bank_transactions.json", Interval: 2 * time.Second,
+        Description: "Bank transactions per account: amount, narration, date, type, mode and running balance.",
+        Schema: fixtureSchema{Required: map[string]string{"bankTransactions": "array"}, Decode: decodeAs[bankTransactionsFile]},
+        APIParams: []string{"accountId"}, API: bankTransactionsHandler,
+        Txns: &bankTxnRows,
+        StreamParams: []string{"minAmount"}, StreamFilter: minAmountFilter("bankTransactions", 0)},
+    {Name: "stock_transactions", File: "fetch_stock_transactions.json", Interval: 2 * time.Second,
+        Description: "Indian stock transactions per ISIN: buy, sell, bonus and split events with date and quantity.",
+        Schema: fixtureSchema{Required: map[string]string{"stockTransactions": "array"}},
+        Txns: &stockTxnRows},
+}
+
+func registerDataEndpoints(mux *http.ServeMux) {
+    skipEmpty := pkg.GetSkipEmptyRoutes()
+    throttle := pkg.GetThrottle()
+    for name := range throttle {
+        if _, ok := dataEndpointByName(name); !ok {
+            log.Printf("FI_MCP_THROTTLE: no data endpoint %q", name)
+        }
+    }
+    contentTypes := pkg.GetContentTypes()
+    for name := range contentTypes {
+        if _, ok := dataEndpointByName(name); !ok {
+            log.Printf("FI_MCP_CONTENT_TYPES: no data endpoint %q", name)
+        }
+    }
+    var registered []dataEndpoint
+    allParams := []string{"token", "startDelay", "lastEventId", "interval", "datasets"}
+    for _, e := range dataEndpoints {
+        if skipEmpty && !anyPhoneHas(e.File) {
+            log.Printf("no phone has %s, not registering /api/%s, /stream/%s and /ws/%s", e.File, e.Name, e.Name, e.Name)
+            continue
+        }
+        registered = append(registered, e)
+        for _, p := range e.StreamParams {
+            if !contains(allParams, p) {
+                allParams = append(allParams, p)
+            }
+        }
+        api := apiHandler(e.File)
+        if e.API != nil {
+            api = e.API()
+        }
+        ct := e.ContentType
+        if ct == "" {
+            ct = defaultContentType
+        }
+        if override, ok := contentTypes[e.Name]; ok {
+            ct = override
+        }
+        params := e.APIParams
+        if e.Txns != nil {
+            api = withTxnFormat(*e.Txns, withTxnPage(*e.Txns, api))
+            params = append(append([]string(nil), params...), txnPageParams...)
+            params = append(params, "format")
+        }
+        api = withContentType(ct, api)
+        mux.Handle("/api/"+e.Name, allowParams(params, withAuth(withThrottle(e.Name, throttle[e.Name], api))))
+        mux.Handle("/stream/"+e.Name, allowParams(append([]string{"token", "startDelay", "lastEventId", "interval"}, e.StreamParams...), withStreamAuth(sseStream(e.File, e.Interval, e.StreamFilter))))
+        mux.Handle("/ws/"+e.Name, allowParams(append([]string{"token", "lastEventId", "interval"}, e.StreamParams...), withStreamAuth(wsHandler(e.Name))))
+    }
+    if len(registered) > 0 {
+        mux.Handle("/stream/all", allowParams(allParams, withStreamAuth(multiStream(registered))))
+    }
+}
+
+// withContentType presets the response Content-Type; handlers only fill it in
+// when unset, and http.Error still replaces it with text/plain.
+func withContentType(ct string, next http.Handler) http.Handler {
+    return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+        w.Header().Set("Content-Type", ct)
+        next.ServeHTTP(w, r)
+    })
+}
+
+func dataEndpointByName(name string) (dataEndpoint, bool) {
+    for _, e := range dataEndpoints {
+        if e.Name == name {
+            return e, true
+        }
+    }
+    return dataEndpoint{}, false
+}
+
+// anyPhoneHas reports whether any allowed phone has the fixture on disk.
+func anyPhoneHas(fileName string) bool {
+    for _, phone := range allowedMobileNumbers() {
+        if _, err := os.Stat(dataFilePath(phone, fileName)); err == nil {
+            return true
+        }
+    }
+    return false
+}
+
+// allowParams rejects query params outside allowed with 400 when
+// FI_MCP_STRICT_PARAMS is on; otherwise unknown params are ignored as before.
+func allowParams(allowed []string, next http.Handler) http.Handler {
+    return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+        if pkg.GetStrictParams() {
+            if unknown := unknownParams(r.URL.Query(), allowed); len(unknown) > 0 {
+                http.Error(w, fmt.Sprintf("unsupported query params: %s", strings.Join(unknown, ", ")), http.StatusBadRequest)
+                return
+            }
+        }
+        next.ServeHTTP(w, r)
+    })
+}
+
+func unknownParams(q url.Values, allowed []string) []string {
+    var unknown []string
+    for k := range q {
+        ok := false
+        for _, a := range allowed {
+            if k == a {
+                ok = true
+                break
+            }
+        }
+        if !ok {
+            unknown = append(unknown, k)
+        }
+    }
+    sort.Strings(unknown)
+    return unknown
+}
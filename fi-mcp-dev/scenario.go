@@ -0,0 +1,55 @@
+package main
+
+import (
+    "context"
+    "net/http"
+    "os"
+    "path/filepath"
+    "regexp"
+
+    "github.com/epifi/fi-mcp-lite/pkg"
+)
+
+// ————— scenarios —————
+// An X-Scenario header switches the request to an alternative dataset:
+// <FI_MCP_SCENARIO_DIR>/<scenario>/<phone>/<file> is served when it exists,
+// and the phone's regular fixture otherwise. "default" (or no header) is the
+// regular data.
+
+var scenarioName = regexp.MustCompile(`^[A-Za-z0-9_-]{1,64}$`)
+
+// withScenario puts a valid X-Scenario value on the request context and
+// rejects names that could escape the scenario directory.
+func withScenario(next http.Handler) http.Handler {
+    return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+        name := r.Header.Get("X-Scenario")
+        if name == "" || name == "default" {
+            next.ServeHTTP(w, r)
+            return
+        }
+        if !scenarioName.MatchString(name) {
+            http.Error(w, "invalid X-Scenario", http.StatusBadRequest)
+            return
+        }
+        w.Header().Set("X-Scenario", name)
+        ctx := context.WithValue(r.Context(), "scenario", name)
+        next.ServeHTTP(w, r.WithContext(ctx))
+    })
+}
+
+func scenarioFrom(ctx context.Context) string {
+    name, _ := ctx.Value("scenario").(string)
+    return name
+}
+
+// scenarioFilePath resolves a fixture for the request's scenario, falling
+// back to the phone's regular file.
+func scenarioFilePath(ctx context.Context, phone, fileName string) string {
+    if name := scenarioFrom(ctx); name != "" {
+        path := filepath.Join(pkg.GetScenarioDir(), name, phone, fileName)
+        if _, err := os.Stat(path); err == nil {
+            return path
+        }
+    }
+    return dataFilePath(phone, fileName)
+}
@@ -0,0 +1,544 @@
+package main
+
+import (
+    "bytes"
+    "context"
+    "encoding/json"
+    "errors"
+    "fmt"
+    "log"
+    "math"
+    "net/http"
+    "os"
+    "strconv"
+    "sync"
+    "time"
+
+    "github.com/epifi/fi-mcp-lite/pkg"
+    "gopkg.in/yaml.v3"
+)
+
+// ————— simulation —————
+// A phone with a simulation.yaml next to its fixtures (or in its X-Scenario
+// directory) gets data that moves on a virtual clock: monthly bank credits
+// and debits, SIP purchases, and a daily random walk on mutual fund and stock
+// values. readDataFile applies it to every fixture it returns, so the raw
+// endpoints, the derived ones and the streams all see the same data.
+//
+// The clock is at the config's start date when the server starts (or after
+// POST /admin/simulation/reset) and runs speed virtual seconds per real one.
+
+const (
+    simConfigFile = "simulation.yaml"
+    maxSimDays    = 3650 // the clock stops ten virtual years after start
+)
+
+type simConfig struct {
+    // Start is the virtual date at clock zero. It defaults to the latest
+    // bank transaction, so generated rows carry on from the fixture.
+    Start string `yaml:"start"`
+    // Speed is virtual seconds per real second; default 86400, a day a second.
+    Speed  float64 `yaml:"speed"`
+    Seed   int64   `yaml:"seed"`
+    Market struct {
+        MF    simWalk `yaml:"mf"`
+        Stock simWalk `yaml:"stock"`
+    } `yaml:"market"`
+    Events []simEvent `yaml:"events"`
+
+    start time.Time
+}
+
+// simWalk moves a value by drift ± volatility (uniformly) each virtual day,
+// e.g. drift 0.0005 and volatility 0.01.
+type simWalk struct {
+    Drift      float64 `yaml:"drift"`
+    Volatility float64 `yaml:"volatility"`
+}
+
+// simEvent recurs on Day of every month, or the month's last day when it is
+// shorter.
+type simEvent struct {
+    Kind      string  `yaml:"kind"` // salary, credit, debit or sip
+    Day       int     `yaml:"day"`
+    Amount    float64 `yaml:"amount"`
+    Narration string  `yaml:"narration"`
+    Mode      string  `yaml:"mode"`
+    Account   int     `yaml:"account"` // index into bankTransactions
+    ISIN      string  `yaml:"isin"`    // sip: the scheme bought
+}
+
+func (e simEvent) credits() bool {
+    return e.Kind == "salary" || e.Kind == "credit"
+}
+
+func (e simEvent) firesOn(d time.Time) bool {
+    last := time.Date(d.Year(), d.Month()+1, 0, 0, 0, 0, 0, time.UTC).Day()
+    return d.Day() == min(e.Day, last)
+}
+
+// bankRow fills in the narration and mode the config left out.
+func (e simEvent) bankRow() (narration, mode string) {
+    narration, mode = e.Narration, e.Mode
+    defaults := map[string][2]string{
+        "salary": {"SALARY CREDIT", "NEFT"},
+        "credit": {"CREDIT", "OTHERS"},
+        "debit":  {"DEBIT", "OTHERS"},
+        "sip":    {"SIP " + e.ISIN, "ACH"},
+    }[e.Kind]
+    if narration == "" {
+        narration = defaults[0]
+    }
+    if mode == "" {
+        mode = defaults[1]
+    }
+    return narration, mode
+}
+
+func (c *simConfig) check() error {
+    if c.Speed < 0 {
+        return errors.New("speed must not be negative")
+    }
+    for i, e := range c.Events {
+        switch {
+        case e.Kind != "salary" && e.Kind != "credit" && e.Kind != "debit" && e.Kind != "sip":
+            return fmt.Errorf("events[%d]: kind must be salary, credit, debit or sip", i)
+        case e.Day < 1 || e.Day > 31:
+            return fmt.Errorf("events[%d]: day must be 1-31", i)
+        case e.Amount <= 0:
+            return fmt.Errorf("events[%d]: amount must be positive", i)
+        case e.Account < 0:
+            return fmt.Errorf("events[%d]: account must not be negative", i)
+        case e.Kind == "sip" && e.ISIN == "":
+            return fmt.Errorf("events[%d]: sip needs an isin", i)
+        }
+    }
+    return nil
+}
+
+// ————— config loading —————
+
+var simConfigs = struct {
+    sync.Mutex
+    byPath map[string]simConfigEntry
+}{byPath: map[string]simConfigEntry{}}
+
+type simConfigEntry struct {
+    mtime time.Time
+    cfg   *simConfig // nil when the file is invalid
+}
+
+// simulationFor returns the simulation for the request's scenario and
+// phone, or nil. The file is parsed again when it changes; an invalid one is
+// logged once and ignored.
+func simulationFor(ctx context.Context, phone string) *simConfig {
+    if !pkg.GetSimulation() {
+        return nil
+    }
+    path := scenarioFilePath(ctx, phone, simConfigFile)
+    fi, err := os.Stat(path)
+    if err != nil {
+        return nil
+    }
+    simConfigs.Lock()
+    defer simConfigs.Unlock()
+    if e, ok := simConfigs.byPath[path]; ok && e.mtime.Equal(fi.ModTime()) {
+        return e.cfg
+    }
+    cfg, err := loadSimConfig(ctx, phone, path)
+    if err != nil {
+        log.Printf("simulation config: phone=%s file=%s: %v", logPhone(phone), simConfigFile, readErr(err))
+    }
+    simConfigs.byPath[path] = simConfigEntry{mtime: fi.ModTime(), cfg: cfg}
+    return cfg
+}
+
+func loadSimConfig(ctx context.Context, phone, path string) (*simConfig, error) {
+    data, err := os.ReadFile(path)
+    if err != nil {
+        return nil, err
+    }
+    var cfg simConfig
+    dec := yaml.NewDecoder(bytes.NewReader(data))
+    dec.KnownFields(true)
+    if err := dec.Decode(&cfg); err != nil {
+        return nil, err
+    }
+    if err := cfg.check(); err != nil {
+        return nil, err
+    }
+    if cfg.Speed == 0 {
+        cfg.Speed = 86400
+    }
+    if cfg.Start != "" {
+        if cfg.start, err = time.Parse(time.DateOnly, cfg.Start); err != nil {
+            return nil, errors.New("start must be a YYYY-MM-DD date")
+        }
+    } else {
+        cfg.start = latestRawBankDate(ctx, phone)
+    }
+    return &cfg, nil
+}
+
+// latestRawBankDate reads the unsimulated bank fixture from the cache
+// (readDataFile would simulate it), falling back to today.
+func latestRawBankDate(ctx context.Context, phone string) time.Time {
+    var bt bankTransactionsFile
+    if data, err := cachedReadFile(scenarioFilePath(ctx, phone, "fetch_bank_transactions.json")); err == nil && json.Unmarshal(data, &bt) == nil {
+        if d := bt.latestTxnDate(); !d.IsZero() {
+            return d
+        }
+    }
+    return time.Now().UTC().Truncate(24 * time.Hour)
+}
+
+// ————— virtual clock —————
+
+var simClock = struct {
+    sync.Mutex
+    epoch time.Time
+}{epoch: time.Now()}
+
+// simRun is everything that happened between start and the virtual now.
+type simRun struct {
+    now       time.Time
+    days      int       // whole virtual days since start
+    mf, stock []float64 // value factor after each day; [0] is 1
+    fired     []simFired
+}
+
+type simFired struct {
+    simEvent
+    date time.Time
+    day  int
+}
+
+func (c *simConfig) run(now time.Time) simRun {
+    simClock.Lock()
+    elapsed := now.Sub(simClock.epoch).Seconds() * c.Speed
+    simClock.Unlock()
+    elapsed = math.Min(elapsed, maxSimDays*24*3600)
+    r := simRun{now: c.start.Add(time.Duration(elapsed * float64(time.Second)))}
+    r.days = int(elapsed / (24 * 3600))
+    r.mf = make([]float64, r.days+1)
+    r.stock = make([]float64, r.days+1)
+    r.mf[0], r.stock[0] = 1, 1
+    for i := 1; i <= r.days; i++ {
+        r.mf[i] = r.mf[i-1] * (1 + c.Market.MF.Drift + c.Market.MF.Volatility*hashUnit(c.Seed, "mf", i))
+        r.stock[i] = r.stock[i-1] * (1 + c.Market.Stock.Drift + c.Market.Stock.Volatility*hashUnit(c.Seed, "stock", i))
+        d := c.start.AddDate(0, 0, i)
+        for _, e := range c.Events {
+            if e.firesOn(d) {
+                r.fired = append(r.fired, simFired{simEvent: e, date: d, day: i})
+            }
+        }
+    }
+    return r
+}
+
+// ————— applying it —————
+
+// simulate returns fixture data with the phone's simulation applied, or data
+// itself when there is none or the fixture can't be parsed.
+func simulate(ctx context.Context, phone, fileName string, data []byte) []byte {
+    var apply func(doc map[string]interface{}, run simRun) bool
+    switch fileName {
+    case "fetch_bank_transactions.json":
+        apply = simBank
+    case "fetch_mf_transactions.json":
+        apply = simMF
+    case "fetch_net_worth.json":
+        apply = simNetWorth
+    default:
+        return data
+    }
+    cfg := simulationFor(ctx, phone)
+    if cfg == nil {
+        return data
+    }
+    var doc map[string]interface{}
+    dec := json.NewDecoder(bytes.NewReader(data))
+    dec.UseNumber() // keep the fixture's numbers exactly as written
+    if dec.Decode(&doc) != nil || doc == nil {
+        return data
+    }
+    if !apply(doc, cfg.run(time.Now())) {
+        return data
+    }
+    out, err := json.Marshal(doc)
+    if err != nil {
+        return data
+    }
+    return out
+}
+
+// simBank adds a row per fired event to its account, newest first like the
+// fixtures, carrying the running balance on from the latest real row.
+func simBank(doc map[string]interface{}, run simRun) bool {
+    accounts, _ := doc["bankTransactions"].([]interface{})
+    added := make([][]interface{}, len(accounts))
+    balances := make([]float64, len(accounts))
+    for i, a := range accounts {
+        txns, _ := dig(a, "txns").([]interface{})
+        balances[i] = latestRowBalance(txns)
+    }
+    for _, f := range run.fired {
+        if f.Account >= len(accounts) {
+            continue
+        }
+        typ := bankTxnDebit
+        if f.credits() {
+            typ = bankTxnCredit
+            balances[f.Account] += f.Amount
+        } else {
+            balances[f.Account] -= f.Amount
+        }
+        narration, mode := f.bankRow()
+        row := []interface{}{fmtAmount(f.Amount), narration, f.date.Format(time.DateOnly), typ, mode, fmtAmount(balances[f.Account])}
+        added[f.Account] = append([]interface{}{row}, added[f.Account]...)
+    }
+    changed := false
+    for i, rows := range added {
+        acc, ok := accounts[i].(map[string]interface{})
+        if !ok || len(rows) == 0 {
+            continue
+        }
+        txns, _ := acc["txns"].([]interface{})
+        acc["txns"] = append(rows, txns...)
+        changed = true
+    }
+    return changed
+}
+
+// latestRowBalance is the balance on the first row of the latest date.
+func latestRowBalance(txns []interface{}) float64 {
+    var date string
+    var balance float64
+    for _, t := range txns {
+        row, _ := t.([]interface{})
+        if len(row) < 6 {
+            continue
+        }
+        if d, _ := row[2].(string); d > date {
+            date, balance = d, jsonFloat(row[5])
+        }
+    }
+    return balance
+}
+
+// simMF records each fired SIP as a BUY at the scheme's last price moved by
+// the mutual fund walk.
+func simMF(doc map[string]interface{}, run simRun) bool {
+    schemes, _ := doc["mfTransactions"].([]interface{})
+    changed := false
+    for _, f := range run.fired {
+        if f.Kind != "sip" {
+            continue
+        }
+        for _, s := range schemes {
+            scheme, ok := s.(map[string]interface{})
+            if !ok || scheme["isin"] != f.ISIN {
+                continue
+            }
+            txns, _ := scheme["txns"].([]interface{})
+            base := lastPurchasePrice(txns)
+            if base <= 0 {
+                break
+            }
+            nav := base * run.mf[f.day]
+            units := math.Round(f.Amount/nav*1000) / 1000
+            scheme["txns"] = append(txns, []interface{}{1, f.date.Format(time.DateOnly), math.Round(nav*10000) / 10000, units, round2(f.Amount)})
+            changed = true
+            break
+        }
+    }
+    return changed
+}
+
+// lastPurchasePrice is the price on the scheme's latest row:
+// [orderType, date, price, units, amount].
+func lastPurchasePrice(txns []interface{}) float64 {
+    var date string
+    var price float64
+    for _, t := range txns {
+        row, _ := t.([]interface{})
+        if len(row) < 3 {
+            continue
+        }
+        if d, _ := row[1].(string); d >= date {
+            date, price = d, jsonFloat(row[2])
+        }
+    }
+    return price
+}
+
+// simNetWorth moves the asset totals: savings by the net of the bank events,
+// mutual funds and stocks by their walks (plus SIPs, grown since purchase).
+// Scheme and holding details are scaled too so they agree with the totals.
+func simNetWorth(doc map[string]interface{}, run simRun) bool {
+    nwr, _ := doc["netWorthResponse"].(map[string]interface{})
+    if nwr == nil {
+        return false
+    }
+    mfF, stockF := run.mf[run.days], run.stock[run.days]
+    var cash, sipValue float64
+    sipByISIN := map[string][2]float64{} // isin → invested, current value
+    for _, f := range run.fired {
+        if f.credits() {
+            cash += f.Amount
+        } else {
+            cash -= f.Amount
+        }
+        if f.Kind == "sip" {
+            grown := f.Amount * mfF / run.mf[f.day]
+            sipValue += grown
+            v := sipByISIN[f.ISIN]
+            sipByISIN[f.ISIN] = [2]float64{v[0] + f.Amount, v[1] + grown}
+        }
+    }
+
+    var delta float64
+    assets, _ := nwr["assetValues"].([]interface{})
+    for _, a := range assets {
+        value, _ := dig(a, "value").(map[string]interface{})
+        old, ok := moneyValue(value)
+        if !ok {
+            continue
+        }
+        var v float64
+        switch dig(a, "netWorthAttribute") {
+        case "ASSET_TYPE_MUTUAL_FUND":
+            v = old*mfF + sipValue
+        case "ASSET_TYPE_INDIAN_SECURITIES", "ASSET_TYPE_US_SECURITIES":
+            v = old * stockF
+        case "ASSET_TYPE_SAVINGS_ACCOUNTS":
+            v = old + cash
+        default:
+            continue
+        }
+        setMoney(value, v)
+        delta += v - old
+    }
+    if total, _ := nwr["totalNetWorthValue"].(map[string]interface{}); total != nil {
+        if t, ok := moneyValue(total); ok {
+            setMoney(total, t+delta)
+        }
+    }
+
+    schemes, _ := dig(doc, "mfSchemeAnalytics", "schemeAnalytics").([]interface{})
+    for _, s := range schemes {
+        isin, _ := dig(s, "schemeDetail", "isinNumber").(string)
+        sip := sipByISIN[isin]
+        scaleMoney(dig(s, "schemeDetail", "nav"), mfF, 0)
+        details := dig(s, "enrichedAnalytics", "analytics", "schemeDetails")
+        scaleMoney(dig(details, "currentValue"), mfF, sip[1])
+        scaleMoney(dig(details, "investedValue"), 1, sip[0])
+    }
+    accounts, _ := dig(doc, "accountDetailsBulkResponse", "accountDetailsMap").(map[string]interface{})
+    for _, acc := range accounts {
+        scaleMoney(dig(acc, "equitySummary", "currentValue"), stockF, 0)
+        holdings, _ := dig(acc, "equitySummary", "holdingsInfo").([]interface{})
+        for _, h := range holdings {
+            scaleMoney(dig(h, "lastTradedPrice"), stockF, 0)
+        }
+    }
+    return true
+}
+
+// ————— JSON helpers —————
+
+// dig walks nested objects, returning nil when a key is missing.
+func dig(v interface{}, keys ...string) interface{} {
+    for _, k := range keys {
+        m, ok := v.(map[string]interface{})
+        if !ok {
+            return nil
+        }
+        v = m[k]
+    }
+    return v
+}
+
+// jsonFloat reads the numbers (json.Number here) and numeric strings the
+// fixtures mix.
+func jsonFloat(v interface{}) float64 {
+    if n, ok := v.(json.Number); ok {
+        f, _ := n.Float64()
+        return f
+    }
+    return toFloat(v)
+}
+
+// moneyValue reads a {currencyCode, units, nanos} object.
+func moneyValue(m map[string]interface{}) (float64, bool) {
+    if m == nil || m["units"] == nil {
+        return 0, false
+    }
+    return jsonFloat(m["units"]) + jsonFloat(m["nanos"])/1e9, true
+}
+
+// setMoney writes v back as string units and integer nanos, as the fixtures
+// do.
+func setMoney(m map[string]interface{}, v float64) {
+    v = round2(v)
+    units := math.Trunc(v)
+    m["units"] = strconv.FormatFloat(units, 'f', 0, 64)
+    if nanos := math.Round((v - units) * 1e9); nanos != 0 {
+        m["nanos"] = int64(nanos)
+    } else {
+        delete(m, "nanos")
+    }
+}
+
+// scaleMoney sets a money object, if v is one, to value*factor + add.
+func scaleMoney(v interface{}, factor, add float64) {
+    m, _ := v.(map[string]interface{})
+    if old, ok := moneyValue(m); ok {
+        setMoney(m, old*factor+add)
+    }
+}
+
+// fmtAmount formats bank amounts as strings, like the fixture rows.
+func fmtAmount(v float64) string {
+    return strconv.FormatFloat(round2(v), 'f', -1, 64)
+}
+
+// ————— endpoints —————
+
+// simulationHandler serves /api/simulation: where the phone's virtual clock
+// is, or 404 when it has no simulation.
+func simulationHandler(w http.ResponseWriter, r *http.Request) {
+    phone := r.Context().Value("phone").(string)
+    cfg := simulationFor(r.Context(), phone)
+    if cfg == nil {
+        http.Error(w, "no simulation for this phone", http.StatusNotFound)
+        return
+    }
+    run := cfg.run(time.Now())
+    writeJSON(w, map[string]interface{}{
+        "start":       cfg.start.Format(time.DateOnly),
+        "virtualNow":  run.now.UTC().Format(time.RFC3339),
+        "speed":       cfg.Speed,
+        "days":        run.days,
+        "eventsFired": len(run.fired),
+        "mfFactor":    math.Round(run.mf[run.days]*10000) / 10000,
+        "stockFactor": math.Round(run.stock[run.days]*10000) / 10000,
+    })
+}
+
+// simulationResetHandler serves POST /admin/simulation/reset: every virtual
+// clock goes back to its start date.
+func simulationResetHandler(w http.ResponseWriter, r *http.Request) {
+    if r.Method != http.MethodPost {
+        http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+        return
+    }
+    simClock.Lock()
+    simClock.epoch = time.Now()
+    simClock.Unlock()
+    for _, phone := range allowedMobileNumbers() {
+        fixturesChanged(phone)
+    }
+    log.Printf("admin: simulation clocks reset")
+    writeJSON(w, map[string]string{"status": "reset"})
+}
@@ -0,0 +1,77 @@
+package main
+
+import (
+    "math"
+    "net/http"
+    "strconv"
+)
+
+// ————— goal-based SIP —————
+// The monthly SIP that, together with today's investable assets growing at
+// ?rate=, reaches ?goal= in ?years=. Assets compound monthly; the SIP is
+// the future value of an ordinary annuity (paid at month end).
+
+// investableAssets are the net worth asset types expected to keep compounding
+// towards a goal; EPF and NPS are locked in and savings are the emergency
+// buffer, so they are left out.
+var investableAssets = []string{
+    "ASSET_TYPE_MUTUAL_FUND", "ASSET_TYPE_INDIAN_SECURITIES", "ASSET_TYPE_US_SECURITIES",
+    "ASSET_TYPE_ETF", "ASSET_TYPE_DEPOSITS", "ASSET_TYPE_SGB",
+}
+
+func sipRecommendationHandler(w http.ResponseWriter, r *http.Request) {
+    phone := r.Context().Value("phone").(string)
+    q := r.URL.Query()
+    goal, err := strconv.ParseFloat(q.Get("goal"), 64)
+    if err != nil || goal <= 0 || math.IsInf(goal, 0) || math.IsNaN(goal) {
+        http.Error(w, "goal must be a positive amount", http.StatusBadRequest)
+        return
+    }
+    years, err := intParam(q.Get("years"), 10, 1, maxForecastYears)
+    if err != nil {
+        http.Error(w, "years must be an integer from 1 to 50", http.StatusBadRequest)
+        return
+    }
+    rate := 12.0
+    if s := q.Get("rate"); s != "" {
+        f, err := strconv.ParseFloat(s, 64)
+        if err != nil || f < 0 || f > 100 || math.IsNaN(f) {
+            http.Error(w, "rate must be a yearly percentage from 0 to 100", http.StatusBadRequest)
+            return
+        }
+        rate = f
+    }
+
+    nw, err := loadNetWorth(r.Context(), phone)
+    if err != nil {
+        http.Error(w, "data not found", http.StatusInternalServerError)
+        return
+    }
+    var assets float64
+    for _, name := range investableAssets {
+        assets += nw.attribute(name)
+    }
+    assets = math.Max(0, assets)
+
+    months := years * 12
+    i := rate / 100 / 12
+    growth := math.Pow(1+i, float64(months))
+    projected := assets * growth
+    sip := 0.0
+    if gap := goal - projected; gap > 0 {
+        if i == 0 {
+            sip = gap / float64(months)
+        } else {
+            sip = gap * i / (growth - 1)
+        }
+    }
+    writeJSON(w, map[string]interface{}{
+        "goal":             goal,
+        "years":            years,
+        "rate":             rate,
+        "investableAssets": round2(assets),
+        "projectedAssets":  round2(projected),
+        "monthlySip":       round2(sip),
+        "goalMet":          sip == 0,
+    })
+}
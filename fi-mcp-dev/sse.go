@@ -0,0 +1,457 @@
+package main
+
+import (
+    "bytes"
+    "context"
+    "crypto/sha256"
+    "fmt"
+    "log"
+    "net/http"
+    "net/url"
+    "strconv"
+    "sync"
+    "time"
+
+    "github.com/epifi/fi-mcp-lite/middlewares"
+    "github.com/epifi/fi-mcp-lite/pkg"
+)
+
+// ————— SSE helper —————
+// sseStream polls the fixture every interval, or ?interval=, and pushes it
+// only when it differs from the last payload sent; the first tick always
+// sends. filter, if set, rewrites each payload for this client; the replay
+// ring keeps the unfiltered events since it is shared by every client of the
+// phone.
+//
+// Every event carries an increasing id:. A client reconnecting with
+// Last-Event-ID (or ?lastEventId=) continues from that id: it skips the
+// replay and gets the current snapshot straight away.
+func sseStream(fileName string, interval time.Duration, filter streamFilter) http.Handler {
+    return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+        phone := r.Context().Value("phone").(string)
+        interval, err := streamInterval(r.URL.Query(), interval)
+        if err != nil {
+            http.Error(w, err.Error(), http.StatusBadRequest)
+            return
+        }
+        rewrite, err := streamRewrite(filter, r.URL.Query())
+        if err != nil {
+            http.Error(w, "invalid stream filter: "+err.Error(), http.StatusBadRequest)
+            return
+        }
+        id, resumed, err := lastEventID(r)
+        if err != nil {
+            http.Error(w, "invalid Last-Event-ID", http.StatusBadRequest)
+            return
+        }
+        rc, ok := startSSE(w, r)
+        if !ok {
+            return
+        }
+        defer metrics.StreamStarted(middlewares.StreamSSE)()
+
+        feed := newFixtureFeed(r.Context(), phone, fileName, rewrite)
+        if !resumed {
+            for _, data := range feed.replay() {
+                id++
+                writeEventID(w, id, data)
+            }
+        }
+        if err := rc.Flush(); err != nil {
+            return
+        }
+
+        next := tickSchedule(interval)
+        first := next()
+        if resumed {
+            first = 0
+        }
+        timer := time.NewTimer(first)
+        defer timer.Stop()
+        ka := newKeepAlive()
+        defer ka.stop()
+
+        for {
+            select {
+            case <-r.Context().Done():
+                return
+            case <-ka.C():
+                if !ka.send(w, rc) {
+                    return
+                }
+            case <-timer.C:
+                timer.Reset(next())
+                data, ok := feed.next(r.Context())
+                if !ok {
+                    continue
+                }
+                id++
+                writeEventID(w, id, data)
+                if err := rc.Flush(); err != nil {
+                    return
+                }
+                ka.reset()
+            }
+        }
+    })
+}
+
+// streamRewrite builds a client's payload rewrite from its params; with no
+// filter, payloads pass through as they are.
+func streamRewrite(filter streamFilter, q url.Values) (func([]byte) ([]byte, error), error) {
+    if filter != nil {
+        f, err := filter(q)
+        if err != nil {
+            return nil, err
+        }
+        if f != nil {
+            return f, nil
+        }
+    }
+    return func(data []byte) ([]byte, error) { return data, nil }, nil
+}
+
+// fixtureFeed is the polling half of a stream, shared by SSE and WebSocket:
+// each call to next reads the fixture and returns it, rewritten, only when
+// it changed since the last call.
+type fixtureFeed struct {
+    phone, fileName string
+    ring            *eventRing
+    rewrite         func([]byte) ([]byte, error)
+    last            [sha256.Size]byte
+    sent            bool
+}
+
+func newFixtureFeed(ctx context.Context, phone, fileName string, rewrite func([]byte) ([]byte, error)) *fixtureFeed {
+    return &fixtureFeed{
+        phone:    phone,
+        fileName: fileName,
+        ring:     replayRing(scenarioFrom(ctx), phone, fileName),
+        rewrite:  rewrite,
+    }
+}
+
+// replay returns the ring's events rewritten for this client.
+func (f *fixtureFeed) replay() [][]byte {
+    var out [][]byte
+    for _, data := range f.ring.snapshot() {
+        if data, err := f.rewrite(data); err == nil {
+            out = append(out, data)
+        }
+    }
+    return out
+}
+
+// next is false when the fixture is unchanged or can't be read or
+// rewritten; errors are logged.
+func (f *fixtureFeed) next(ctx context.Context) ([]byte, bool) {
+    data, err := readDataFile(ctx, f.phone, f.fileName)
+    if err == nil {
+        data, err = servedFixture(data)
+    }
+    if err != nil {
+        log.Printf("read error: phone=%s file=%s: %v", logPhone(f.phone), f.fileName, readErr(err))
+        return nil, false
+    }
+    sum := sha256.Sum256(data)
+    if f.sent && sum == f.last {
+        return nil, false
+    }
+    f.last, f.sent = sum, true
+    f.ring.add(data, sum)
+    if data, err = f.rewrite(data); err != nil {
+        log.Printf("filter error: phone=%s file=%s: %v", logPhone(f.phone), f.fileName, err)
+        return nil, false
+    }
+    return data, true
+}
+
+// sseRamp is read once, so a bad FI_MCP_SSE_RAMP_FACTOR is logged once
+// rather than on every stream.
+var sseRamp = sync.OnceValue(pkg.GetSSERamp)
+
+// tickSchedule yields the delay before each event. Normally that is just
+// interval; in ramp mode (FI_MCP_SSE_RAMP) the first gap is
+// FI_MCP_SSE_RAMP_START and each later one grows by FI_MCP_SSE_RAMP_FACTOR
+// until it reaches interval.
+func tickSchedule(interval time.Duration) func() time.Duration {
+    ramp := sseRamp()
+    if !ramp.Enabled {
+        return func() time.Duration { return interval }
+    }
+    delay := ramp.Start
+    return func() time.Duration {
+        d := min(delay, interval)
+        if delay < interval {
+            delay = time.Duration(float64(delay) * ramp.Factor)
+        }
+        return d
+    }
+}
+
+// maxStartDelay caps ?startDelay=.
+const maxStartDelay = 30 * time.Second
+
+// ?interval= bounds: faster would just burn reads, slower looks like a
+// dead stream.
+const (
+    minStreamInterval = 500 * time.Millisecond
+    maxStreamInterval = 10 * time.Minute
+)
+
+// streamInterval is ?interval= (a Go duration such as 10s) when set, else
+// def. Unlike startDelay it isn't clamped: a value out of bounds is an error.
+func streamInterval(q url.Values, def time.Duration) (time.Duration, error) {
+    s := q.Get("interval")
+    if s == "" {
+        return def, nil
+    }
+    d, err := time.ParseDuration(s)
+    if err != nil || d < minStreamInterval || d > maxStreamInterval {
+        return 0, fmt.Errorf("interval must be a duration from %s to %s", minStreamInterval, maxStreamInterval)
+    }
+    return d, nil
+}
+
+// startSSE sends the event-stream headers and then waits out ?startDelay=
+// (a Go duration, clamped to 0–30s) so clients can be tested in their
+// connecting state. It fails, after replying, on a bad startDelay, when the
+// writer can't flush, or when the client leaves during the delay.
+func startSSE(w http.ResponseWriter, r *http.Request) (*http.ResponseController, bool) {
+    var delay time.Duration
+    if s := r.URL.Query().Get("startDelay"); s != "" {
+        d, err := time.ParseDuration(s)
+        if err != nil {
+            http.Error(w, "invalid startDelay", http.StatusBadRequest)
+            return nil, false
+        }
+        delay = max(0, min(d, maxStartDelay))
+    }
+
+    w.Header().Set("Content-Type", "text/event-stream")
+    w.Header().Set("Cache-Control", "no-cache")
+    w.Header().Set("X-Accel-Buffering", "no")
+    if r.ProtoMajor < 2 {
+        // connection-specific headers are not allowed over HTTP/2
+        w.Header().Set("Connection", "keep-alive")
+    }
+
+    // ResponseController reaches the underlying writer's Flush on both
+    // HTTP/1.1 and HTTP/2, even through middleware that wraps w.
+    rc := http.NewResponseController(w)
+    if err := rc.Flush(); err != nil {
+        http.Error(w, "streaming unsupported", http.StatusInternalServerError)
+        return nil, false
+    }
+    if delay > 0 {
+        t := time.NewTimer(delay)
+        defer t.Stop()
+        select {
+        case <-r.Context().Done():
+            return nil, false
+        case <-t.C:
+        }
+    }
+    return rc, true
+}
+
+// keepAlive sends a ": keep-alive" comment after FI_MCP_SSE_KEEPALIVE without
+// an event, so proxies and clients don't drop a stream whose data isn't
+// changing. Handlers call reset after each event.
+type keepAlive struct {
+    every time.Duration
+    timer *time.Timer
+}
+
+func newKeepAlive() *keepAlive {
+    k := &keepAlive{every: pkg.GetSSEKeepAlive()}
+    if k.every > 0 {
+        k.timer = time.NewTimer(k.every)
+    }
+    return k
+}
+
+// C is nil, and so never ready, when keep-alives are off.
+func (k *keepAlive) C() <-chan time.Time {
+    if k.timer == nil {
+        return nil
+    }
+    return k.timer.C
+}
+
+func (k *keepAlive) reset() {
+    if k.timer != nil {
+        k.timer.Reset(k.every)
+    }
+}
+
+func (k *keepAlive) stop() {
+    if k.timer != nil {
+        k.timer.Stop()
+    }
+}
+
+// send writes the comment and reports whether the client is still there.
+func (k *keepAlive) send(w http.ResponseWriter, rc *http.ResponseController) bool {
+    fmt.Fprint(w, ": keep-alive\n\n")
+    k.reset()
+    return rc.Flush() == nil
+}
+
+// lastEventID reads the id a reconnecting client last processed, from the
+// Last-Event-ID header or the lastEventId param. resumed is false for a
+// fresh connection.
+func lastEventID(r *http.Request) (id uint64, resumed bool, err error) {
+    s := r.Header.Get("Last-Event-ID")
+    if s == "" {
+        s = r.URL.Query().Get("lastEventId")
+    }
+    if s == "" {
+        return 0, false, nil
+    }
+    id, err = strconv.ParseUint(s, 10, 64)
+    return id, err == nil, err
+}
+
+// writeEventID is writeEvent with an id: line first.
+func writeEventID(w http.ResponseWriter, id uint64, data []byte) {
+    fmt.Fprintf(w, "id: %d\n", id)
+    writeEvent(w, data)
+}
+
+// writeEvent frames data as one SSE event, splitting multi-line payloads
+// (pretty-printed fixtures) into several data: lines as the spec requires.
+func writeEvent(w http.ResponseWriter, data []byte) {
+    for _, line := range bytes.Split(bytes.TrimRight(data, "\n"), []byte("\n")) {
+        fmt.Fprintf(w, "data: %s\n", line)
+    }
+    fmt.Fprint(w, "\n")
+}
+
+// ————— shutdown —————
+// http.Server.Shutdown waits for requests to finish but never cancels them,
+// and a stream never finishes on its own. main registers stopStreams with
+// RegisterOnShutdown so every stream's context ends and its handler returns.
+
+var streamsCtx, stopStreams = context.WithCancel(context.Background())
+
+// logDrain logs the number of open streams whenever it changes during
+// shutdown, until they are all gone or ctx ends.
+func logDrain(ctx context.Context) {
+    tick := time.NewTicker(100 * time.Millisecond)
+    defer tick.Stop()
+    last := int64(-1)
+    for {
+        if n := metrics.ActiveStreams(); n != last {
+            if n == 0 {
+                return
+            }
+            log.Printf("draining: %d stream(s) open", n)
+            last = n
+        }
+        select {
+        case <-ctx.Done():
+            return
+        case <-tick.C:
+        }
+    }
+}
+
+func withShutdown(next http.Handler) http.Handler {
+    return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+        ctx, cancel := context.WithCancel(r.Context())
+        defer cancel()
+        defer context.AfterFunc(streamsCtx, cancel)()
+        next.ServeHTTP(w, r.WithContext(ctx))
+    })
+}
+
+// ————— per-session stream limit —————
+
+var openStreams = struct {
+    sync.Mutex
+    bySession map[string]int
+}{bySession: map[string]int{}}
+
+// withStreamLimit rejects a stream with 409 when its session already holds
+// FI_MCP_MAX_STREAMS_PER_SESSION open ones.
+func withStreamLimit(next http.Handler) http.Handler {
+    return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+        limit := pkg.GetMaxStreamsPerSession()
+        if limit == 0 {
+            next.ServeHTTP(w, r)
+            return
+        }
+        key := sessionKey(r)
+        openStreams.Lock()
+        if openStreams.bySession[key] >= limit {
+            openStreams.Unlock()
+            http.Error(w, fmt.Sprintf("session already has %d open stream(s)", limit), http.StatusConflict)
+            return
+        }
+        openStreams.bySession[key]++
+        openStreams.Unlock()
+        defer func() {
+            openStreams.Lock()
+            if openStreams.bySession[key]--; openStreams.bySession[key] <= 0 {
+                delete(openStreams.bySession, key)
+            }
+            openStreams.Unlock()
+        }()
+        next.ServeHTTP(w, r)
+    })
+}
+
+// ————— replay buffer —————
+// Every stream keeps its last FI_MCP_SSE_REPLAY events per
+// scenario+phone+file, and a newly connected client receives them before
+// live ticks. 0 disables replay.
+
+type eventRing struct {
+    mu     sync.Mutex
+    size   int
+    events [][]byte
+    newest [sha256.Size]byte // hash of the last entry in events
+}
+
+// add stores data, whose hash is sum, unless it is the newest entry
+// already. Every client of a phone's stream feeds the same ring, so each one
+// offers the snapshots it sees; only the first offer of each change is kept.
+func (r *eventRing) add(data []byte, sum [sha256.Size]byte) {
+    if r.size <= 0 {
+        return
+    }
+    r.mu.Lock()
+    defer r.mu.Unlock()
+    if len(r.events) > 0 && sum == r.newest {
+        return
+    }
+    r.newest = sum
+    r.events = append(r.events, data)
+    if len(r.events) > r.size {
+        r.events = r.events[len(r.events)-r.size:]
+    }
+}
+
+func (r *eventRing) snapshot() [][]byte {
+    r.mu.Lock()
+    defer r.mu.Unlock()
+    return append([][]byte(nil), r.events...)
+}
+
+var replay = struct {
+    sync.Mutex
+    rings map[string]*eventRing
+}{rings: map[string]*eventRing{}}
+
+func replayRing(scenario, phone, fileName string) *eventRing {
+    key := scenario + "/" + phone + "/" + fileName
+    replay.Lock()
+    defer replay.Unlock()
+    ring, ok := replay.rings[key]
+    if !ok {
+        ring = &eventRing{size: pkg.GetSSEReplaySize()}
+        replay.rings[key] = ring
+    }
+    return ring
+}
@@ -0,0 +1,174 @@
+package main
+
+import (
+    "errors"
+    "fmt"
+    "net/http"
+    "strings"
+    "time"
+
+    "github.com/epifi/fi-mcp-lite/middlewares"
+)
+
+// ————— multiplexed SSE —————
+// /stream/all?datasets=net_worth,bank_transactions carries several
+// /stream/<name> feeds over one connection. Each event is named after its
+// dataset (event: net_worth), so an EventSource client listens with
+// addEventListener("net_worth", ...) rather than onmessage. Every dataset
+// ticks at its own Interval unless ?interval= sets one for all of them, and
+// stream params such as minAmount apply to the datasets that take them. ids
+// count up across the whole connection; resuming with Last-Event-ID skips
+// the replay and sends every dataset's current snapshot straight away.
+
+// muxFeed is one dataset's part of a multiplexed stream.
+type muxFeed struct {
+    name string
+    feed *fixtureFeed
+    next func() time.Duration
+    due  time.Time
+}
+
+// streamDatasets parses ?datasets= against the registered endpoints, in the
+// order given and without repeats.
+func streamDatasets(s string, registered []dataEndpoint) ([]dataEndpoint, error) {
+    var out []dataEndpoint
+    seen := map[string]bool{}
+    for _, name := range strings.Split(s, ",") {
+        name = strings.TrimSpace(name)
+        if name == "" || seen[name] {
+            continue
+        }
+        e, ok := endpointIn(registered, name)
+        if !ok {
+            return nil, fmt.Errorf("unknown data type: %s", name)
+        }
+        seen[name] = true
+        out = append(out, e)
+    }
+    if len(out) == 0 {
+        return nil, errors.New("datasets is required: data type names, comma-separated")
+    }
+    return out, nil
+}
+
+func endpointIn(endpoints []dataEndpoint, name string) (dataEndpoint, bool) {
+    for _, e := range endpoints {
+        if e.Name == name {
+            return e, true
+        }
+    }
+    return dataEndpoint{}, false
+}
+
+// multiStream serves /stream/all over the endpoints main registered.
+func multiStream(registered []dataEndpoint) http.Handler {
+    return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+        phone := r.Context().Value("phone").(string)
+        q := r.URL.Query()
+        endpoints, err := streamDatasets(q.Get("datasets"), registered)
+        if err != nil {
+            http.Error(w, err.Error(), http.StatusBadRequest)
+            return
+        }
+        feeds := make([]*muxFeed, 0, len(endpoints))
+        for _, e := range endpoints {
+            interval, err := streamInterval(q, e.Interval)
+            if err != nil {
+                http.Error(w, err.Error(), http.StatusBadRequest)
+                return
+            }
+            rewrite, err := streamRewrite(e.StreamFilter, q)
+            if err != nil {
+                http.Error(w, fmt.Sprintf("invalid stream filter for %s: %v", e.Name, err), http.StatusBadRequest)
+                return
+            }
+            feeds = append(feeds, &muxFeed{
+                name: e.Name,
+                feed: newFixtureFeed(r.Context(), phone, e.File, rewrite),
+                next: tickSchedule(interval),
+            })
+        }
+        id, resumed, err := lastEventID(r)
+        if err != nil {
+            http.Error(w, "invalid Last-Event-ID", http.StatusBadRequest)
+            return
+        }
+        rc, ok := startSSE(w, r)
+        if !ok {
+            return
+        }
+        defer metrics.StreamStarted(middlewares.StreamSSE)()
+
+        if !resumed {
+            for _, f := range feeds {
+                for _, data := range f.feed.replay() {
+                    id++
+                    writeNamedEvent(w, f.name, id, data)
+                }
+            }
+        }
+        if err := rc.Flush(); err != nil {
+            return
+        }
+
+        now := time.Now()
+        for _, f := range feeds {
+            f.due = now
+            if first := f.next(); !resumed {
+                f.due = now.Add(first)
+            }
+        }
+        timer := time.NewTimer(time.Until(earliestDue(feeds)))
+        defer timer.Stop()
+        ka := newKeepAlive()
+        defer ka.stop()
+
+        for {
+            select {
+            case <-r.Context().Done():
+                return
+            case <-ka.C():
+                if !ka.send(w, rc) {
+                    return
+                }
+            case now := <-timer.C:
+                sent := false
+                for _, f := range feeds {
+                    if f.due.After(now) {
+                        continue
+                    }
+                    f.due = now.Add(f.next())
+                    if data, ok := f.feed.next(r.Context()); ok {
+                        id++
+                        writeNamedEvent(w, f.name, id, data)
+                        sent = true
+                    }
+                }
+                timer.Reset(time.Until(earliestDue(feeds)))
+                if !sent {
+                    continue
+                }
+                if err := rc.Flush(); err != nil {
+                    return
+                }
+                ka.reset()
+            }
+        }
+    })
+}
+
+func earliestDue(feeds []*muxFeed) time.Time {
+    due := feeds[0].due
+    for _, f := range feeds[1:] {
+        if f.due.Before(due) {
+            due = f.due
+        }
+    }
+    return due
+}
+
+// writeNamedEvent is writeEventID with an event: line naming the dataset.
+func writeNamedEvent(w http.ResponseWriter, name string, id uint64, data []byte) {
+    fmt.Fprintf(w, "event: %s\n", name)
+    writeEventID(w, id, data)
+}
@@ -0,0 +1,48 @@
+package main
+
+import (
+    "net/http"
+    "strconv"
+    "sync"
+
+    "github.com/epifi/fi-mcp-lite/pkg"
+)
+
+// ————— simulated upstream throttling —————
+// An endpoint listed in FI_MCP_THROTTLE answers 429 with Retry-After to the
+// first N requests of each session, then recovers, like a provider that
+// throttles a new client.
+
+type throttleCounter struct {
+    mu   sync.Mutex
+    seen map[string]int
+}
+
+var throttled = &throttleCounter{seen: map[string]int{}}
+
+// hit counts one request and reports whether it is still within the first n.
+func (c *throttleCounter) hit(key string, n int) bool {
+    c.mu.Lock()
+    defer c.mu.Unlock()
+    if c.seen[key] >= n {
+        return false
+    }
+    c.seen[key]++
+    return true
+}
+
+// withThrottle must run inside withAuth; requests are counted per session
+// cookie. n of 0 disables it.
+func withThrottle(name string, n int, next http.Handler) http.Handler {
+    if n == 0 {
+        return next
+    }
+    return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+        if throttled.hit(sessionKey(r)+"/"+name, n) {
+            w.Header().Set("Retry-After", strconv.Itoa(pkg.GetThrottleRetryAfter()))
+            http.Error(w, "rate limited by upstream provider (simulated)", http.StatusTooManyRequests)
+            return
+        }
+        next.ServeHTTP(w, r)
+    })
+}
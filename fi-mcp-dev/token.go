@@ -0,0 +1,92 @@
+package main
+
+import (
+    "context"
+    "crypto/hmac"
+    "crypto/sha256"
+    "encoding/hex"
+    "net/http"
+    "strconv"
+    "strings"
+    "time"
+
+    "github.com/epifi/fi-mcp-lite/middlewares"
+    "github.com/epifi/fi-mcp-lite/pkg"
+)
+
+// ————— stream share tokens —————
+// A token grants read-only SSE access to one phone until it expires, without
+// the session cookie. Format: "<phone>.<unix expiry>.<hex HMAC-SHA256>",
+// signed with FI_MCP_STREAM_TOKEN_SECRET. No secret, no tokens.
+
+const maxStreamTokenTTL = 24 * time.Hour
+
+func signStreamToken(secret, phone string, exp int64) string {
+    payload := phone + "." + strconv.FormatInt(exp, 10)
+    mac := hmac.New(sha256.New, []byte(secret))
+    mac.Write([]byte(payload))
+    return payload + "." + hex.EncodeToString(mac.Sum(nil))
+}
+
+// verifyStreamToken returns the phone a valid, unexpired token was issued for.
+func verifyStreamToken(secret, token string, now time.Time) (string, bool) {
+    parts := strings.Split(token, ".")
+    if secret == "" || len(parts) != 3 {
+        return "", false
+    }
+    exp, err := strconv.ParseInt(parts[1], 10, 64)
+    if err != nil || now.Unix() >= exp {
+        return "", false
+    }
+    if !hmac.Equal([]byte(signStreamToken(secret, parts[0], exp)), []byte(token)) {
+        return "", false
+    }
+    return parts[0], true
+}
+
+// streamTokenHandler issues a token for the logged-in phone; ?ttl= is a Go
+// duration (default 15m, at most 24h).
+func streamTokenHandler(w http.ResponseWriter, r *http.Request) {
+    secret := pkg.GetStreamTokenSecret()
+    if secret == "" {
+        http.Error(w, "stream tokens disabled", http.StatusNotFound)
+        return
+    }
+    ttl := 15 * time.Minute
+    if v := r.URL.Query().Get("ttl"); v != "" {
+        d, err := time.ParseDuration(v)
+        if err != nil || d <= 0 {
+            http.Error(w, "invalid ttl", http.StatusBadRequest)
+            return
+        }
+        ttl = min(d, maxStreamTokenTTL)
+    }
+    phone := r.Context().Value("phone").(string)
+    exp := time.Now().Add(ttl)
+    writeJSON(w, map[string]interface{}{
+        "token":     signStreamToken(secret, phone, exp.Unix()),
+        "expiresAt": exp.UTC().Format(time.RFC3339),
+    })
+}
+
+// withStreamAuth accepts ?token= in place of the session cookie. It also
+// applies the per-session stream limit, which needs the caller identified.
+func withStreamAuth(next http.Handler) http.Handler {
+    next = withShutdown(withStreamLimit(next))
+    cookieAuth := withAuth(next)
+    return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+        token := r.URL.Query().Get("token")
+        if token == "" {
+            cookieAuth.ServeHTTP(w, r)
+            return
+        }
+        phone, ok := verifyStreamToken(pkg.GetStreamTokenSecret(), token, time.Now())
+        if !ok {
+            http.Error(w, "invalid or expired token", http.StatusUnauthorized)
+            return
+        }
+        middlewares.SetRequestPhone(r.Context(), phone)
+        ctx := context.WithValue(r.Context(), "phone", phone)
+        next.ServeHTTP(w, r.WithContext(ctx))
+    })
+}
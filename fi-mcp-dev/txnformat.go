@@ -0,0 +1,263 @@
+package main
+
+import (
+    "bytes"
+    "encoding/csv"
+    "encoding/json"
+    "fmt"
+    "mime"
+    "net/http"
+    "sort"
+    "strconv"
+    "strings"
+)
+
+// ————— CSV / NDJSON —————
+// /api/<txn type> also answers as CSV or NDJSON, picked by ?format=csv|ndjson
+// or else by the Accept header (text/csv, application/x-ndjson). Each
+// transaction becomes one row: its group's GroupCols, the row's Cols and a
+// typeName, always in that order. Missing values are empty cells in CSV and
+// null in NDJSON. Paging and filters apply first; the page's total and
+// next_offset move to X-Total-Count and X-Next-Offset. With ?sort=, rows
+// come out in that order across groups, not group by group as in JSON.
+
+const (
+    formatJSON   = "json"
+    formatCSV    = "csv"
+    formatNDJSON = "ndjson"
+)
+
+var formatContentTypes = map[string]string{
+    formatCSV:    "text/csv; charset=utf-8",
+    formatNDJSON: "application/x-ndjson",
+}
+
+// txnFormat is the response format r asks for. From Accept it takes the
+// recognised media type with the highest q, the first one listed on a tie.
+// An Accept header naming none of the formats gets JSON, as before; only a
+// bad ?format= is an error.
+func txnFormat(r *http.Request) (string, error) {
+    if f := r.URL.Query().Get("format"); f != "" {
+        switch f {
+        case formatJSON, formatCSV, formatNDJSON:
+            return f, nil
+        }
+        return "", fmt.Errorf("invalid format %q: use json, csv or ndjson", f)
+    }
+    format, best := formatJSON, 0.0
+    for _, part := range strings.Split(r.Header.Get("Accept"), ",") {
+        mt, params, err := mime.ParseMediaType(strings.TrimSpace(part))
+        if err != nil {
+            continue
+        }
+        q := 1.0
+        if s, ok := params["q"]; ok {
+            if q, err = strconv.ParseFloat(s, 64); err != nil || q < 0 || q > 1 {
+                continue
+            }
+        }
+        var f string
+        switch mt {
+        case "text/csv":
+            f = formatCSV
+        case "application/x-ndjson", "application/ndjson":
+            f = formatNDJSON
+        case "application/json", "*/*", "application/*":
+            f = formatJSON
+        default:
+            continue
+        }
+        if q > best {
+            format, best = f, q
+        }
+    }
+    return format, nil
+}
+
+// withTxnFormat converts what next writes when r asks for CSV or NDJSON.
+// next's whole response is buffered in a recorder first, since the JSON has
+// to be complete before it can be flattened. Errors from next pass through
+// as they are.
+func withTxnFormat(rows txnRows, next http.Handler) http.Handler {
+    return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+        w.Header().Add("Vary", "Accept")
+        format, err := txnFormat(r)
+        if err != nil {
+            http.Error(w, err.Error(), http.StatusBadRequest)
+            return
+        }
+        if format == formatJSON {
+            next.ServeHTTP(w, r)
+            return
+        }
+        rec := &recorder{header: w.Header().Clone(), status: http.StatusOK}
+        next.ServeHTTP(rec, r)
+        for k, v := range rec.header {
+            w.Header()[k] = v
+        }
+        if rec.status != http.StatusOK {
+            w.WriteHeader(rec.status)
+            w.Write(rec.body.Bytes())
+            return
+        }
+
+        var doc map[string]json.RawMessage
+        if err := json.Unmarshal(rec.body.Bytes(), &doc); err != nil {
+            http.Error(w, "transactions unavailable", http.StatusInternalServerError)
+            return
+        }
+        var groups []map[string]json.RawMessage
+        if raw, ok := doc[rows.ListKey]; ok { // fixtures like {} have no rows at all
+            if err := json.Unmarshal(raw, &groups); err != nil {
+                http.Error(w, "transactions unavailable", http.StatusInternalServerError)
+                return
+            }
+        }
+        w.Header().Del("Content-Length")
+        w.Header().Set("Content-Type", formatContentTypes[format])
+        if raw, ok := doc["total"]; ok {
+            w.Header().Set("X-Total-Count", string(raw))
+        }
+        if raw, ok := doc["next_offset"]; ok && string(raw) != "null" {
+            w.Header().Set("X-Next-Offset", string(raw))
+        }
+        w.WriteHeader(http.StatusOK)
+        records := txnRecords(rows, groups)
+        // the page was already checked by withTxnPage, which answered 400
+        if p, _ := parseTxnPage(r.URL.Query(), rows); p != nil && p.sortBy != "" {
+            sortTxnRecords(records, rows, p)
+        }
+        if format == formatCSV {
+            writeTxnCSV(w, rows, records)
+        } else {
+            writeTxnNDJSON(w, rows, records)
+        }
+    })
+}
+
+// txnRecords flattens every row to txnColumns(rows) values, skipping rows
+// that aren't arrays. A nil value is a missing one.
+func txnRecords(rows txnRows, groups []map[string]json.RawMessage) [][]json.RawMessage {
+    var out [][]json.RawMessage
+    for _, g := range groups {
+        var txns []json.RawMessage
+        if err := json.Unmarshal(g["txns"], &txns); err != nil {
+            continue // no txns in this group
+        }
+        for _, raw := range txns {
+            var row []json.RawMessage
+            if json.Unmarshal(raw, &row) != nil {
+                continue
+            }
+            vals := make([]json.RawMessage, 0, len(rows.GroupCols)+len(rows.Cols)+1)
+            for _, c := range rows.GroupCols {
+                vals = append(vals, g[c])
+            }
+            for i := range rows.Cols {
+                var v json.RawMessage
+                if i < len(row) {
+                    v = row[i]
+                }
+                vals = append(vals, v)
+            }
+            var typeName json.RawMessage
+            if rows.TypeIdx < len(row) {
+                var code interface{}
+                json.Unmarshal(row[rows.TypeIdx], &code)
+                if name, ok := rows.TypeNames[int(toFloat(code))]; ok {
+                    typeName, _ = json.Marshal(name)
+                }
+            }
+            out = append(out, append(vals, typeName))
+        }
+    }
+    return out
+}
+
+// sortTxnRecords puts the flattened rows back in ?sort= order. The page's
+// JSON lists rows under their groups, which breaks a sort across groups.
+func sortTxnRecords(records [][]json.RawMessage, rows txnRows, p *txnPage) {
+    col := len(rows.GroupCols) + rows.DateIdx
+    if p.sortBy == "amount" {
+        col = len(rows.GroupCols) + rows.AmountIdx
+    }
+    key := func(rec []json.RawMessage) (string, float64) {
+        var v interface{}
+        json.Unmarshal(rec[col], &v)
+        s, _ := v.(string)
+        return s, toFloat(v)
+    }
+    sort.SliceStable(records, func(i, j int) bool {
+        a, b := records[i], records[j]
+        if p.desc {
+            a, b = b, a
+        }
+        as, af := key(a)
+        bs, bf := key(b)
+        if p.sortBy == "amount" {
+            return af < bf
+        }
+        return as < bs
+    })
+}
+
+func txnColumns(rows txnRows) []string {
+    cols := append(append([]string(nil), rows.GroupCols...), rows.Cols...)
+    return append(cols, "typeName")
+}
+
+// writeTxnCSV writes a header line and one record per row. Strings are
+// unquoted; numbers keep the fixture's notation.
+func writeTxnCSV(w http.ResponseWriter, rows txnRows, records [][]json.RawMessage) {
+    cw := csv.NewWriter(w)
+    cw.Write(txnColumns(rows))
+    record := make([]string, len(txnColumns(rows)))
+    for _, vals := range records {
+        for i, v := range vals {
+            record[i] = csvCell(v)
+        }
+        if cw.Write(record) != nil {
+            return
+        }
+    }
+    cw.Flush()
+}
+
+func csvCell(v json.RawMessage) string {
+    v = bytes.TrimSpace(v)
+    switch {
+    case len(v) == 0 || string(v) == "null":
+        return ""
+    case v[0] == '"':
+        var s string
+        if json.Unmarshal(v, &s) == nil {
+            return s
+        }
+    }
+    return string(v)
+}
+
+// writeTxnNDJSON writes one JSON object per row, keys in column order.
+func writeTxnNDJSON(w http.ResponseWriter, rows txnRows, records [][]json.RawMessage) {
+    cols := txnColumns(rows)
+    var line bytes.Buffer
+    for _, vals := range records {
+        line.Reset()
+        line.WriteByte('{')
+        for i, v := range vals {
+            if i > 0 {
+                line.WriteByte(',')
+            }
+            line.WriteString(strconv.Quote(cols[i]))
+            line.WriteByte(':')
+            // compacted, so a pretty-printed fixture can't break the line
+            if len(v) == 0 || json.Compact(&line, v) != nil {
+                line.WriteString("null")
+            }
+        }
+        line.WriteString("}\n")
+        if _, err := w.Write(line.Bytes()); err != nil {
+            return
+        }
+    }
+}
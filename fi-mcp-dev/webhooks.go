@@ -0,0 +1,471 @@
+package main
+
+import (
+    "bytes"
+    "context"
+    "crypto/hmac"
+    "crypto/sha256"
+    "encoding/hex"
+    "encoding/json"
+    "errors"
+    "fmt"
+    "io"
+    "io/fs"
+    "log"
+    "net/http"
+    "net/url"
+    "sort"
+    "strconv"
+    "sync"
+    "time"
+
+    "github.com/epifi/fi-mcp-lite/pkg"
+)
+
+// ————— webhooks —————
+// A logged-in phone can register callback URLs per dataset instead of
+// holding a stream open. Every FI_MCP_WEBHOOK_POLL, and straight away when
+// the admin API or a simulation reset changes the phone's fixtures, each
+// hook's dataset is read the way the hook's owner would see it (simulation
+// and X-Scenario included). If it differs from what the hook last saw, the
+// new payload is POSTed, signed with the hook's secret. Failed deliveries are
+// retried with doubling backoff, up to FI_MCP_WEBHOOK_RETRIES times; a newer
+// change replaces a payload that is still being retried. Hooks live in
+// memory and are gone after a restart.
+
+const (
+    maxWebhooksPerPhone = 20
+    webhookTimeout      = 10 * time.Second
+    maxWebhookBackoff   = time.Minute
+)
+
+// webhookInfo is the part of a hook its owner sees.
+type webhookInfo struct {
+    ID        string    `json:"id"`
+    Dataset   string    `json:"dataset"`
+    URL       string    `json:"url"`
+    Scenario  string    `json:"scenario,omitempty"`
+    CreatedAt time.Time `json:"createdAt"`
+}
+
+type webhook struct {
+    phone string
+    wake  chan struct{} // a new pending payload
+    stop  context.CancelFunc
+
+    mu        sync.Mutex
+    info      webhookInfo // Dataset and URL change with PUT
+    secret    string
+    last      [32]byte // hash of the last payload checked
+    seq       uint64
+    pending   *webhookEvent
+    lastState *webhookDelivery
+}
+
+// webhookEvent is the body POSTed to a hook.
+type webhookEvent struct {
+    ID        string          `json:"id"`
+    HookID    string          `json:"hookId"`
+    Dataset   string          `json:"dataset"`
+    ChangedAt time.Time       `json:"changedAt"`
+    Data      json.RawMessage `json:"data"` // null once the fixture is gone
+}
+
+// webhookDelivery is how the last delivery went, shown by GET.
+type webhookDelivery struct {
+    EventID    string    `json:"eventId"`
+    At         time.Time `json:"at"`
+    OK         bool      `json:"ok"`
+    Attempts   int       `json:"attempts"`
+    HTTPStatus int       `json:"httpStatus,omitempty"`
+    Error      string    `json:"error,omitempty"`
+}
+
+type webhookView struct {
+    webhookInfo
+    LastDelivery *webhookDelivery `json:"lastDelivery"`
+}
+
+func (h *webhook) view() webhookView {
+    h.mu.Lock()
+    defer h.mu.Unlock()
+    return webhookView{h.info, h.lastState}
+}
+
+var webhooks = struct {
+    sync.Mutex
+    byPhone map[string]map[string]*webhook
+}{byPhone: map[string]map[string]*webhook{}}
+
+// webhooksCtx ends every delivery worker; main stops it on shutdown.
+var webhooksCtx, stopWebhooks = context.WithCancel(context.Background())
+
+var webhookClient = &http.Client{
+    Timeout: webhookTimeout,
+    // a redirect would resend the signed body somewhere else
+    CheckRedirect: func(*http.Request, []*http.Request) error { return http.ErrUseLastResponse },
+}
+
+// startWebhooks runs the change check every FI_MCP_WEBHOOK_POLL until shutdown.
+func startWebhooks() {
+    every := pkg.GetWebhookPoll()
+    go func() {
+        t := time.NewTicker(every)
+        defer t.Stop()
+        for {
+            select {
+            case <-webhooksCtx.Done():
+                return
+            case <-t.C:
+                webhooks.Lock()
+                phones := make([]string, 0, len(webhooks.byPhone))
+                for phone := range webhooks.byPhone {
+                    phones = append(phones, phone)
+                }
+                webhooks.Unlock()
+                for _, phone := range phones {
+                    checkWebhooks(phone)
+                }
+            }
+        }
+    }()
+}
+
+func phoneWebhooks(phone string) []*webhook {
+    webhooks.Lock()
+    defer webhooks.Unlock()
+    out := make([]*webhook, 0, len(webhooks.byPhone[phone]))
+    for _, h := range webhooks.byPhone[phone] {
+        out = append(out, h)
+    }
+    return out
+}
+
+// checkWebhooks queues a delivery for each of the phone's hooks whose data
+// changed. Hooks of a phone that is no longer allowed are dropped.
+func checkWebhooks(phone string) {
+    hooks := phoneWebhooks(phone)
+    if len(hooks) == 0 {
+        return
+    }
+    if !allowedPhone(phone) {
+        for _, h := range hooks {
+            removeWebhook(phone, h.info.ID)
+        }
+        return
+    }
+    for _, h := range hooks {
+        dataset, data, ok := h.read()
+        if !ok {
+            continue
+        }
+        sum := sha256.Sum256(data)
+        h.mu.Lock()
+        // skip a read that raced with a PUT to another dataset
+        if sum != h.last && dataset == h.info.Dataset {
+            h.last = sum
+            h.seq++
+            h.pending = &webhookEvent{
+                ID:        h.info.ID + "-" + strconv.FormatUint(h.seq, 10),
+                HookID:    h.info.ID,
+                Dataset:   dataset,
+                ChangedAt: time.Now().UTC(),
+                Data:      data,
+            }
+            select {
+            case h.wake <- struct{}{}:
+            default:
+            }
+        }
+        h.mu.Unlock()
+    }
+}
+
+// read returns the hook's dataset and its data as served to its owner, or
+// null when the fixture doesn't exist. It is false when the fixture can't be
+// read right now.
+func (h *webhook) read() (string, []byte, bool) {
+    h.mu.Lock()
+    info := h.info
+    h.mu.Unlock()
+    e, _ := dataEndpointByName(info.Dataset)
+    ctx := webhooksCtx
+    if info.Scenario != "" {
+        ctx = context.WithValue(ctx, "scenario", info.Scenario)
+    }
+    data, err := readDataFile(ctx, h.phone, e.File)
+    if errors.Is(err, fs.ErrNotExist) {
+        return info.Dataset, []byte("null"), true
+    }
+    if err == nil {
+        data, err = servedFixture(data)
+    }
+    if err == nil && !json.Valid(data) {
+        err = errors.New("not JSON")
+    }
+    if err != nil {
+        log.Printf("webhook %s: phone=%s file=%s: %v", info.ID, logPhone(h.phone), e.File, readErr(err))
+        return "", nil, false
+    }
+    return info.Dataset, data, true
+}
+
+// deliver sends pending events one at a time until ctx ends.
+func (h *webhook) deliver(ctx context.Context) {
+    for {
+        select {
+        case <-ctx.Done():
+            return
+        case <-h.wake:
+        }
+        h.mu.Lock()
+        ev := h.pending
+        h.pending = nil
+        h.mu.Unlock()
+        for ev != nil {
+            ev = h.send(ctx, ev)
+        }
+    }
+}
+
+// send POSTs ev with retries. It returns a newer event that arrived while ev
+// was being retried, which replaces it, or nil.
+func (h *webhook) send(ctx context.Context, ev *webhookEvent) *webhookEvent {
+    body, _ := json.Marshal(ev)
+    retries := pkg.GetWebhookRetries()
+    backoff := time.Second
+    state := &webhookDelivery{EventID: ev.ID}
+    for attempt := 1; ; attempt++ {
+        state.Attempts = attempt
+        state.HTTPStatus, state.Error = h.post(ctx, ev, body)
+        state.At = time.Now().UTC()
+        state.OK = state.Error == ""
+        h.mu.Lock()
+        h.lastState = state
+        h.mu.Unlock()
+        if state.OK || attempt > retries {
+            if !state.OK {
+                log.Printf("webhook %s: giving up on %s after %d attempt(s): %s", ev.HookID, ev.ID, attempt, state.Error)
+            }
+            return nil
+        }
+        select {
+        case <-ctx.Done():
+            return nil
+        case <-h.wake:
+            h.mu.Lock()
+            next := h.pending
+            h.pending = nil
+            h.mu.Unlock()
+            if next != nil {
+                return next
+            }
+        case <-time.After(backoff):
+        }
+        backoff = min(2*backoff, maxWebhookBackoff)
+        state = &webhookDelivery{EventID: ev.ID, Attempts: attempt}
+    }
+}
+
+// post makes one delivery attempt. The signature is
+// hex(HMAC-SHA256(secret, timestamp + "." + body)).
+func (h *webhook) post(ctx context.Context, ev *webhookEvent, body []byte) (status int, errMsg string) {
+    h.mu.Lock()
+    target, secret := h.info.URL, h.secret
+    h.mu.Unlock()
+    req, err := http.NewRequestWithContext(ctx, http.MethodPost, target, bytes.NewReader(body))
+    if err != nil {
+        return 0, err.Error()
+    }
+    ts := strconv.FormatInt(time.Now().Unix(), 10)
+    mac := hmac.New(sha256.New, []byte(secret))
+    mac.Write([]byte(ts + "."))
+    mac.Write(body)
+    req.Header.Set("Content-Type", "application/json")
+    req.Header.Set("User-Agent", "fi-mcp-lite-webhooks")
+    req.Header.Set("X-Webhook-Id", ev.HookID)
+    req.Header.Set("X-Webhook-Event-Id", ev.ID)
+    req.Header.Set("X-Webhook-Timestamp", ts)
+    req.Header.Set("X-Webhook-Signature", "sha256="+hex.EncodeToString(mac.Sum(nil)))
+    resp, err := webhookClient.Do(req)
+    if err != nil {
+        return 0, err.Error()
+    }
+    io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+    resp.Body.Close()
+    if resp.StatusCode < 200 || resp.StatusCode > 299 {
+        return resp.StatusCode, resp.Status
+    }
+    return resp.StatusCode, ""
+}
+
+func removeWebhook(phone, id string) bool {
+    webhooks.Lock()
+    defer webhooks.Unlock()
+    h, ok := webhooks.byPhone[phone][id]
+    if !ok {
+        return false
+    }
+    h.stop()
+    delete(webhooks.byPhone[phone], id)
+    if len(webhooks.byPhone[phone]) == 0 {
+        delete(webhooks.byPhone, phone)
+    }
+    return true
+}
+
+// ————— endpoints —————
+
+type webhookRequest struct {
+    Dataset string `json:"dataset"`
+    URL     string `json:"url"`
+    Secret  string `json:"secret"` // generated when empty
+}
+
+func (req webhookRequest) check() error {
+    if _, ok := dataEndpointByName(req.Dataset); !ok {
+        return fmt.Errorf("unknown dataset %q", req.Dataset)
+    }
+    u, err := url.Parse(req.URL)
+    if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+        return errors.New("url must be an absolute http(s) URL")
+    }
+    return nil
+}
+
+func decodeWebhookRequest(w http.ResponseWriter, r *http.Request) (webhookRequest, bool) {
+    var req webhookRequest
+    if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, 64<<10)).Decode(&req); err != nil {
+        http.Error(w, "invalid JSON body", http.StatusBadRequest)
+        return req, false
+    }
+    if err := req.check(); err != nil {
+        http.Error(w, err.Error(), http.StatusBadRequest)
+        return req, false
+    }
+    return req, true
+}
+
+// listWebhooksHandler serves GET /api/webhooks.
+func listWebhooksHandler(w http.ResponseWriter, r *http.Request) {
+    phone := r.Context().Value("phone").(string)
+    out := []webhookView{}
+    for _, h := range phoneWebhooks(phone) {
+        out = append(out, h.view())
+    }
+    sort.Slice(out, func(i, j int) bool { return out[i].CreatedAt.Before(out[j].CreatedAt) })
+    writeJSON(w, out)
+}
+
+// createWebhookHandler serves POST /api/webhooks with
+// {"dataset": "<type>", "url": "...", "secret": "..."}. The reply is the only
+// time the secret is shown. Only changes after this point are delivered.
+func createWebhookHandler(w http.ResponseWriter, r *http.Request) {
+    phone := r.Context().Value("phone").(string)
+    req, ok := decodeWebhookRequest(w, r)
+    if !ok {
+        return
+    }
+    if req.Secret == "" {
+        req.Secret = randomToken()
+    }
+    ctx, cancel := context.WithCancel(webhooksCtx)
+    h := &webhook{
+        phone: phone,
+        wake:  make(chan struct{}, 1),
+        stop:  cancel,
+        info: webhookInfo{
+            ID:        randomToken()[:16],
+            Dataset:   req.Dataset,
+            URL:       req.URL,
+            Scenario:  scenarioFrom(r.Context()),
+            CreatedAt: time.Now().UTC(),
+        },
+        secret: req.Secret,
+    }
+    if _, data, ok := h.read(); ok {
+        h.last = sha256.Sum256(data)
+    }
+
+    webhooks.Lock()
+    if len(webhooks.byPhone[phone]) >= maxWebhooksPerPhone {
+        webhooks.Unlock()
+        cancel()
+        http.Error(w, fmt.Sprintf("at most %d webhooks per phone", maxWebhooksPerPhone), http.StatusConflict)
+        return
+    }
+    if webhooks.byPhone[phone] == nil {
+        webhooks.byPhone[phone] = map[string]*webhook{}
+    }
+    webhooks.byPhone[phone][h.info.ID] = h
+    webhooks.Unlock()
+    go h.deliver(ctx)
+
+    log.Printf("webhook %s: phone=%s dataset=%s registered", h.info.ID, logPhone(phone), req.Dataset)
+    setDefaultContentType(w, defaultContentType)
+    w.WriteHeader(http.StatusCreated)
+    writeJSON(w, struct {
+        webhookView
+        Secret string `json:"secret"`
+    }{h.view(), req.Secret})
+}
+
+// phoneWebhook resolves {id} among the caller's hooks, replying 404 if absent.
+func phoneWebhook(w http.ResponseWriter, r *http.Request) (*webhook, bool) {
+    phone := r.Context().Value("phone").(string)
+    webhooks.Lock()
+    h, ok := webhooks.byPhone[phone][r.PathValue("id")]
+    webhooks.Unlock()
+    if !ok {
+        http.Error(w, "unknown webhook", http.StatusNotFound)
+    }
+    return h, ok
+}
+
+// getWebhookHandler serves GET /api/webhooks/{id}.
+func getWebhookHandler(w http.ResponseWriter, r *http.Request) {
+    if h, ok := phoneWebhook(w, r); ok {
+        writeJSON(w, h.view())
+    }
+}
+
+// updateWebhookHandler serves PUT /api/webhooks/{id}: a new dataset and url,
+// and a new secret if one is given. A new dataset starts from its current
+// data, like a new hook.
+func updateWebhookHandler(w http.ResponseWriter, r *http.Request) {
+    h, ok := phoneWebhook(w, r)
+    if !ok {
+        return
+    }
+    req, ok := decodeWebhookRequest(w, r)
+    if !ok {
+        return
+    }
+    h.mu.Lock()
+    changed := h.info.Dataset != req.Dataset
+    h.info.Dataset, h.info.URL = req.Dataset, req.URL
+    if req.Secret != "" {
+        h.secret = req.Secret
+    }
+    h.mu.Unlock()
+    if changed {
+        if dataset, data, ok := h.read(); ok {
+            h.mu.Lock()
+            if dataset == h.info.Dataset {
+                h.last = sha256.Sum256(data)
+            }
+            h.mu.Unlock()
+        }
+    }
+    writeJSON(w, h.view())
+}
+
+// deleteWebhookHandler serves DELETE /api/webhooks/{id}.
+func deleteWebhookHandler(w http.ResponseWriter, r *http.Request) {
+    phone := r.Context().Value("phone").(string)
+    if !removeWebhook(phone, r.PathValue("id")) {
+        http.Error(w, "unknown webhook", http.StatusNotFound)
+        return
+    }
+    w.WriteHeader(http.StatusNoContent)
+}
@@ -0,0 +1,329 @@
+package main
+
+import (
+    "context"
+    "encoding/json"
+    "errors"
+    "fmt"
+    "net/http"
+    "net/url"
+    "strconv"
+    "strings"
+    "sync"
+    "time"
+
+    "github.com/epifi/fi-mcp-lite/middlewares"
+    "github.com/epifi/fi-mcp-lite/pkg"
+    "github.com/gorilla/websocket"
+)
+
+// ————— WebSocket streams —————
+// /ws/<name> carries the same payloads as /stream/<name>, for clients whose
+// SSE breaks behind proxies. One socket can carry several datasets: the
+// client sends
+//
+//    {"type": "subscribe", "dataset": "<name>", "params": {"minAmount": "500"}}
+//    {"type": "unsubscribe", "dataset": "<name>"}
+//
+// and /ws/<name> starts out subscribed to <name> with the URL's params
+// (/ws starts with nothing). Each payload arrives as
+// {"type": "event", "dataset": "<name>", "id": n, "data": <fixture>}, with
+// ids counted per subscription like SSE ids; a lastEventId param resumes
+// from one, skipping the replay, and an interval param sets the tick as
+// ?interval= does on /stream. The server pings every FI_MCP_WS_PING and
+// hangs up on a socket that doesn't pong within twice that.
+
+const (
+    wsWriteWait        = 10 * time.Second
+    maxWSClientMessage = 64 << 10
+)
+
+var wsUpgrader = websocket.Upgrader{CheckOrigin: wsOriginAllowed}
+
+// openSockets counts upgraded connections. http.Server.Shutdown neither
+// closes nor waits for hijacked ones, so main waits here after it, while the
+// sockets send their close frames.
+var openSockets sync.WaitGroup
+
+func waitForSockets(ctx context.Context) {
+    done := make(chan struct{})
+    go func() {
+        openSockets.Wait()
+        close(done)
+    }()
+    select {
+    case <-done:
+    case <-ctx.Done():
+    }
+}
+
+// wsOriginAllowed lets in native clients (no Origin), same-host pages and
+// FI_MCP_CORS_ORIGINS. Anything else could ride a visitor's session cookie.
+func wsOriginAllowed(r *http.Request) bool {
+    origin := r.Header.Get("Origin")
+    if origin == "" {
+        return true
+    }
+    if u, err := url.Parse(origin); err == nil && strings.EqualFold(u.Host, r.Host) {
+        return true
+    }
+    return contains(pkg.GetCORSOrigins(), origin)
+}
+
+type wsClientMessage struct {
+    Type    string            `json:"type"`
+    Dataset string            `json:"dataset"`
+    Params  map[string]string `json:"params"`
+}
+
+type wsMessage struct {
+    Type    string          `json:"type"` // subscribed, unsubscribed, event or error
+    Dataset string          `json:"dataset,omitempty"`
+    ID      uint64          `json:"id,omitempty"`
+    Data    json.RawMessage `json:"data,omitempty"`
+    Error   string          `json:"error,omitempty"`
+}
+
+// wsSubscription is a checked subscribe request.
+type wsSubscription struct {
+    endpoint dataEndpoint
+    rewrite  func([]byte) ([]byte, error)
+    interval time.Duration
+    lastID   uint64
+    resumed  bool
+}
+
+func newWSSubscription(e dataEndpoint, q url.Values) (*wsSubscription, error) {
+    rewrite, err := streamRewrite(e.StreamFilter, q)
+    if err != nil {
+        return nil, fmt.Errorf("invalid stream filter: %v", err)
+    }
+    interval, err := streamInterval(q, e.Interval)
+    if err != nil {
+        return nil, err
+    }
+    sub := &wsSubscription{endpoint: e, rewrite: rewrite, interval: interval}
+    if s := q.Get("lastEventId"); s != "" {
+        if sub.lastID, err = strconv.ParseUint(s, 10, 64); err != nil {
+            return nil, errors.New("invalid lastEventId")
+        }
+        sub.resumed = true
+    }
+    return sub, nil
+}
+
+type wsConn struct {
+    conn  *websocket.Conn
+    ctx   context.Context
+    phone string
+    mu    sync.Mutex // one writer at a time
+    wg    sync.WaitGroup
+    // subs is only touched by the handler goroutine
+    subs map[string]*wsFeed
+}
+
+// wsFeed is a running subscription; done closes once it has stopped sending.
+type wsFeed struct {
+    stop context.CancelFunc
+    done chan struct{}
+}
+
+func (f *wsFeed) halt() {
+    f.stop()
+    <-f.done
+}
+
+func (c *wsConn) send(m wsMessage) error {
+    c.mu.Lock()
+    defer c.mu.Unlock()
+    data, err := json.Marshal(m)
+    if err != nil {
+        return err
+    }
+    c.conn.SetWriteDeadline(time.Now().Add(wsWriteWait))
+    return c.conn.WriteMessage(websocket.TextMessage, data)
+}
+
+func (c *wsConn) sendError(dataset, msg string) {
+    c.send(wsMessage{Type: "error", Dataset: dataset, Error: msg})
+}
+
+// wsHandler serves /ws/<dataset>, or /ws when dataset is "".
+func wsHandler(dataset string) http.Handler {
+    return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+        var first *wsSubscription
+        if dataset != "" {
+            e, _ := dataEndpointByName(dataset)
+            sub, err := newWSSubscription(e, r.URL.Query())
+            if err != nil {
+                http.Error(w, err.Error(), http.StatusBadRequest)
+                return
+            }
+            first = sub
+        }
+        conn, err := wsUpgrader.Upgrade(w, r, nil)
+        if err != nil {
+            return // the upgrader has replied
+        }
+        openSockets.Add(1)
+        defer openSockets.Done()
+        defer metrics.StreamStarted(middlewares.StreamWebSocket)()
+
+        ctx, cancel := context.WithCancel(r.Context())
+        c := &wsConn{conn: conn, ctx: ctx, phone: r.Context().Value("phone").(string), subs: map[string]*wsFeed{}}
+        defer func() {
+            cancel()
+            c.wg.Wait()
+            conn.Close()
+        }()
+        // on shutdown, say goodbye and unblock the read below
+        defer context.AfterFunc(ctx, func() {
+            c.mu.Lock()
+            conn.WriteControl(websocket.CloseMessage, websocket.FormatCloseMessage(websocket.CloseGoingAway, ""), time.Now().Add(time.Second))
+            c.mu.Unlock()
+            conn.Close()
+        })()
+
+        conn.SetReadLimit(maxWSClientMessage)
+        if ping := pkg.GetWSPing(); ping > 0 {
+            conn.SetReadDeadline(time.Now().Add(2 * ping))
+            conn.SetPongHandler(func(string) error {
+                return conn.SetReadDeadline(time.Now().Add(2 * ping))
+            })
+            c.wg.Add(1)
+            go c.pinger(ping)
+        }
+        if first != nil {
+            c.subscribe(first)
+        }
+        c.readLoop()
+    })
+}
+
+func (c *wsConn) pinger(every time.Duration) {
+    defer c.wg.Done()
+    t := time.NewTicker(every)
+    defer t.Stop()
+    for {
+        select {
+        case <-c.ctx.Done():
+            return
+        case <-t.C:
+            if err := c.conn.WriteControl(websocket.PingMessage, nil, time.Now().Add(wsWriteWait)); err != nil {
+                return
+            }
+        }
+    }
+}
+
+// readLoop handles client messages until the socket closes or times out.
+func (c *wsConn) readLoop() {
+    for {
+        _, data, err := c.conn.ReadMessage()
+        if err != nil {
+            return // closed, gone, or missed its pongs
+        }
+        var m wsClientMessage
+        if err := json.Unmarshal(data, &m); err != nil {
+            c.sendError("", "invalid JSON message")
+            continue
+        }
+        switch m.Type {
+        case "subscribe":
+            e, ok := dataEndpointByName(m.Dataset)
+            if !ok {
+                c.sendError(m.Dataset, "unknown data type")
+                continue
+            }
+            q := url.Values{}
+            for k, v := range m.Params {
+                q.Set(k, v)
+            }
+            if pkg.GetStrictParams() {
+                if unknown := unknownParams(q, append([]string{"lastEventId", "interval"}, e.StreamParams...)); len(unknown) > 0 {
+                    c.sendError(m.Dataset, "unsupported params: "+strings.Join(unknown, ", "))
+                    continue
+                }
+            }
+            sub, err := newWSSubscription(e, q)
+            if err != nil {
+                c.sendError(m.Dataset, err.Error())
+                continue
+            }
+            c.subscribe(sub)
+        case "unsubscribe":
+            f, ok := c.subs[m.Dataset]
+            if !ok {
+                c.sendError(m.Dataset, "not subscribed")
+                continue
+            }
+            f.halt()
+            delete(c.subs, m.Dataset)
+            c.send(wsMessage{Type: "unsubscribed", Dataset: m.Dataset})
+        default:
+            c.sendError(m.Dataset, "unknown message type")
+        }
+    }
+}
+
+// subscribe starts (or, with new params, restarts) one dataset's feed.
+func (c *wsConn) subscribe(sub *wsSubscription) {
+    name := sub.endpoint.Name
+    if f, ok := c.subs[name]; ok {
+        f.halt()
+    }
+    ctx, cancel := context.WithCancel(c.ctx)
+    f := &wsFeed{stop: cancel, done: make(chan struct{})}
+    c.subs[name] = f
+    if c.send(wsMessage{Type: "subscribed", Dataset: name}) != nil {
+        close(f.done)
+        return
+    }
+    c.wg.Add(1)
+    go func() {
+        defer c.wg.Done()
+        defer close(f.done)
+        c.feed(ctx, sub)
+    }()
+}
+
+// feed is sseStream's loop with WebSocket framing.
+func (c *wsConn) feed(ctx context.Context, sub *wsSubscription) {
+    name := sub.endpoint.Name
+    feed := newFixtureFeed(c.ctx, c.phone, sub.endpoint.File, sub.rewrite)
+    id := sub.lastID
+    event := func(data []byte) bool {
+        if !json.Valid(data) {
+            // FI_MCP_EMPTY_FIXTURE can be any text
+            data, _ = json.Marshal(string(data))
+        }
+        id++
+        return c.send(wsMessage{Type: "event", Dataset: name, ID: id, Data: data}) == nil
+    }
+    if !sub.resumed {
+        for _, data := range feed.replay() {
+            if !event(data) {
+                return
+            }
+        }
+    }
+
+    next := tickSchedule(sub.interval)
+    first := next()
+    if sub.resumed {
+        first = 0
+    }
+    timer := time.NewTimer(first)
+    defer timer.Stop()
+    for {
+        select {
+        case <-ctx.Done():
+            return
+        case <-timer.C:
+            timer.Reset(next())
+            if data, ok := feed.next(ctx); ok && !event(data) {
+                return
+            }
+        }
+    }
+}